@@ -0,0 +1,285 @@
+package gopt
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// InvalidBindTarget is the error returned by Bind when the destination is not a non-nil pointer to a struct
+var InvalidBindTarget = errors.New("bind target must be a non-nil pointer to a struct")
+
+// BindError is the (aggregated) error returned by Bind when one or more fields could not be bound
+type BindError struct {
+	// Errors is the errors for each field that could not be bound
+	Errors []*BindFieldError
+}
+
+// Error implements error
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("bind failed (%d field errors): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// BindFieldError is the error for a single field that could not be bound (see BindError)
+type BindFieldError struct {
+	// Field is the name of the field (nested struct fields are dot separated, e.g. "Outer.Inner")
+	Field string
+	// Path is the path of the value in the map
+	Path string
+	// Message describes the error
+	Message string
+}
+
+// Error implements error
+func (e *BindFieldError) Error() string {
+	return fmt.Sprintf("field %q (path %q): %s", e.Field, e.Path, e.Message)
+}
+
+// BindOption is an option that can be passed to Bind
+type BindOption func(bc *bindContext)
+
+// BindFieldConverters is a BindOption that supplies converters for a specific field (nested struct fields are
+// dot separated, e.g. "Outer.Inner")
+//
+// The type T must be the type of the field (or, for Optional[T] and *Optional[T] fields, the optional's value type)
+func BindFieldConverters[T any](field string, converters ...Converter[T]) BindOption {
+	return func(bc *bindContext) {
+		bc.fieldConverters[field] = append(bc.fieldConverters[field], bindConverters[T](converters)...)
+	}
+}
+
+// BindTypeConverters is a BindOption that supplies converters for all fields of type T (or, for Optional[T] and
+// *Optional[T] fields, where the optional's value type is T)
+func BindTypeConverters[T any](converters ...Converter[T]) BindOption {
+	return func(bc *bindContext) {
+		rt := reflect.TypeOf((*T)(nil)).Elem()
+		bc.typeConverters[rt] = append(bc.typeConverters[rt], bindConverters[T](converters)...)
+	}
+}
+
+// Bind binds values from a map[string]any (e.g. as unmarshalled from JSON) into the fields of the struct pointed to by dst
+//
+// The path of each field's value is determined by the field's `gopt` tag (see ExtractJsonPath for the path grammar) - for example:
+//   type Order struct {
+//     ID       string                `gopt:"order.id"`
+//     Customer gopt.Optional[string] `gopt:"order.customer.name"`
+//     FirstQty *gopt.Optional[int]   `gopt:"order.lines[0].qty"`
+//   }
+// If a field has no `gopt` tag path, the name from the `json` tag (or the field name) is used as the property name.
+// Fields tagged with `gopt:"-"` (or `json:"-"`) are not bound.  The `gopt` tag may also contain validation rules (see
+// Validate) - e.g. `gopt:"order.id,required"`.  The parameterless rule names (required, notnull and notblank) are
+// reserved words in the tag - a path of one of these names must be quoted in brackets, e.g. `gopt:"['required']"`
+//
+// Optional[T] (and *Optional[T]) fields are set (see Optional.WasSet) if the path existed - even if the value was null - and
+// the source is recorded as SourceBind.  Other fields are only set if the path existed and the value was non-null.
+// Nested structs (and pointers to structs) are bound from the object at their path, and embedded structs are bound
+// from the same object (as with encoding/json)
+//
+// Values that are not of the field type are converted using the converters supplied with the BindFieldConverters and
+// BindTypeConverters options - if a value cannot be converted, the field is left unchanged and a *BindError
+// listing each field that could not be bound is returned
+func Bind(m map[string]any, dst any, options ...BindOption) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return InvalidBindTarget
+	}
+	bc := &bindContext{
+		fieldConverters: map[string][]bindConverter{},
+		typeConverters:  map[reflect.Type][]bindConverter{},
+	}
+	for _, option := range options {
+		option(bc)
+	}
+	bc.bindStruct(m, rv.Elem(), "", nil)
+	if len(bc.errors) > 0 {
+		return &BindError{Errors: bc.errors}
+	}
+	return nil
+}
+
+type bindConverter func(value any) (any, bool)
+
+func bindConverters[T any](converters []Converter[T]) []bindConverter {
+	result := make([]bindConverter, len(converters))
+	for i, c := range converters {
+		c := c
+		result[i] = func(value any) (any, bool) {
+			return c(value)
+		}
+	}
+	return result
+}
+
+type bindable interface {
+	bindValue(v any, converters []bindConverter) bool
+	bindType() reflect.Type
+}
+
+var bindableType = reflect.TypeOf((*bindable)(nil)).Elem()
+
+func (o *Optional[T]) bindValue(v any, converters []bindConverter) bool {
+	if !isPresent(v) {
+		o.clear(SourceBind)
+		return true
+	} else if tv, ok := v.(T); ok {
+		o.setValue(tv, SourceBind)
+		return true
+	}
+	for _, c := range converters {
+		if cv, ok := c(v); ok {
+			if tv, ok := cv.(T); ok {
+				o.setValue(tv, SourceBind)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *Optional[T]) bindType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+type bindContext struct {
+	fieldConverters map[string][]bindConverter
+	typeConverters  map[reflect.Type][]bindConverter
+	errors          []*BindFieldError
+}
+
+func (bc *bindContext) bindStruct(node any, rv reflect.Value, prefix string, pathPrefix []pathStep) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := prefix + sf.Name
+		steps, explicit, err := bindFieldPath(sf)
+		if err != nil {
+			bc.fail(name, sf.Tag.Get("gopt"), err.Error())
+			continue
+		} else if steps == nil {
+			continue
+		}
+		fv := rv.Field(i)
+		if sf.Anonymous && !explicit && bindStructType(sf.Type) {
+			if sf.Type.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			bc.bindStruct(node, fv, prefix, pathPrefix)
+			continue
+		}
+		v, found := resolvePathSteps(node, steps)
+		if !found {
+			continue
+		}
+		fullPath := append(append(make([]pathStep, 0, len(pathPrefix)+len(steps)), pathPrefix...), steps...)
+		bc.bindField(v, fv, sf.Type, name, fullPath)
+	}
+}
+
+func (bc *bindContext) bindField(v any, fv reflect.Value, ft reflect.Type, name string, path []pathStep) {
+	if reflect.PtrTo(ft).Implements(bindableType) {
+		bc.bindOptional(v, fv.Addr().Interface().(bindable), name, path)
+	} else if ft.Kind() == reflect.Ptr && ft.Implements(bindableType) {
+		if fv.IsNil() {
+			fv.Set(reflect.New(ft.Elem()))
+		}
+		bc.bindOptional(v, fv.Interface().(bindable), name, path)
+	} else if v == nil {
+		// null leaves non-optional fields unchanged
+	} else if cv, ok := bc.convert(v, ft, name); ok {
+		fv.Set(cv)
+	} else if mv, ok := v.(map[string]any); ok && bindStructType(ft) {
+		if ft.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				fv.Set(reflect.New(ft.Elem()))
+			}
+			fv = fv.Elem()
+		}
+		bc.bindStruct(mv, fv, name+".", path)
+	} else {
+		bc.fail(name, formatPath(path), fmt.Sprintf("cannot convert %s to %s", valueKind(v), ft))
+	}
+}
+
+func (bc *bindContext) bindOptional(v any, o bindable, name string, path []pathStep) {
+	if !o.bindValue(v, bc.converters(o.bindType(), name)) {
+		bc.fail(name, formatPath(path), fmt.Sprintf("cannot convert %s to %s", valueKind(v), o.bindType()))
+	}
+}
+
+func (bc *bindContext) convert(v any, ft reflect.Type, name string) (reflect.Value, bool) {
+	if vt := reflect.TypeOf(v); vt.AssignableTo(ft) {
+		return reflect.ValueOf(v), true
+	}
+	for _, c := range bc.converters(ft, name) {
+		if cv, ok := c(v); ok && cv != nil && reflect.TypeOf(cv).AssignableTo(ft) {
+			return reflect.ValueOf(cv), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func (bc *bindContext) converters(rt reflect.Type, name string) []bindConverter {
+	return append(append([]bindConverter{}, bc.fieldConverters[name]...), bc.typeConverters[rt]...)
+}
+
+func (bc *bindContext) fail(name string, path string, msg string) {
+	bc.errors = append(bc.errors, &BindFieldError{
+		Field:   name,
+		Path:    path,
+		Message: msg,
+	})
+}
+
+// bindFieldPath determines the path for a struct field - returns nil steps if the field is not to be bound
+func bindFieldPath(sf reflect.StructField) (steps []pathStep, explicit bool, err error) {
+	if tag, ok := sf.Tag.Lookup("gopt"); ok && tag != "" {
+		path, _, err := parseGoptTag(tag)
+		if err != nil {
+			return nil, true, err
+		} else if path == "-" {
+			return nil, true, nil
+		} else if path != "" {
+			steps, err = parsePath(path)
+			return steps, true, err
+		}
+	}
+	name := sf.Name
+	if tag, ok := sf.Tag.Lookup("json"); ok {
+		if tag == "-" {
+			return nil, true, nil
+		} else if jn := strings.Split(tag, ",")[0]; jn != "" {
+			name = jn
+			explicit = true
+		}
+	}
+	return []pathStep{{key: name}}, explicit, nil
+}
+
+func bindStructType(rt reflect.Type) bool {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt.Kind() == reflect.Struct && !reflect.PtrTo(rt).Implements(bindableType)
+}
+
+func resolvePathSteps(node any, steps []pathStep) (any, bool) {
+	for _, step := range steps {
+		v, ok := step.child(node)
+		if !ok {
+			return nil, false
+		}
+		node = v
+	}
+	return node, true
+}
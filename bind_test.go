@@ -0,0 +1,219 @@
+package gopt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	type address struct {
+		Street   string           `json:"street"`
+		Postcode Optional[string] `json:"postcode"`
+	}
+	type Embedded struct {
+		Note Optional[string] `gopt:"meta.note"`
+	}
+	type order struct {
+		Embedded
+		ID          string             `gopt:"order.id"`
+		Customer    Optional[string]   `gopt:"order.customer.name"`
+		Email       *Optional[string]  `gopt:"order.customer.email"`
+		FirstQty    Optional[float64]  `gopt:"order.lines[0].qty"`
+		LastQty     *Optional[float64] `gopt:"order.lines[-1].qty"`
+		Label       Optional[string]   `gopt:"order.labels[\"app.kubernetes.io/name\"]"`
+		Missing     Optional[string]   `gopt:"order.missing"`
+		MissingPtr  *Optional[string]  `gopt:"order.missing"`
+		Null        Optional[string]   `gopt:"order.null"`
+		Status      string             `json:"status,omitempty"`
+		Total       float64            // no tags - uses field name
+		Address     address            `gopt:"order.address"`
+		AddressPtr  *address           `gopt:"order.address"`
+		Raw         map[string]any     `gopt:"order.customer"`
+		Any         any                `gopt:"order.lines"`
+		Ignored     string             `gopt:"-"`
+		JsonIgnored string             `json:"-"`
+		unexported  string
+		Lines       []any                    `gopt:"order.lines"`
+		Counts      Optional[[]any]          `gopt:"order.lines"`
+		Meta        Optional[map[string]any] `json:"meta"`
+	}
+	m := map[string]any{
+		"order": map[string]any{
+			"id": "o-1",
+			"customer": map[string]any{
+				"name":  "Bilbo",
+				"email": "bilbo@example.com",
+			},
+			"lines": []any{
+				map[string]any{"qty": float64(1)},
+				map[string]any{"qty": float64(2)},
+			},
+			"labels": map[string]any{
+				"app.kubernetes.io/name": "shop",
+			},
+			"null": nil,
+			"address": map[string]any{
+				"street":   "Bagshot Row",
+				"postcode": "SH1 1RE",
+			},
+		},
+		"meta": map[string]any{
+			"note": "hello",
+		},
+		"status":      "open",
+		"Total":       float64(10.5),
+		"Ignored":     "x",
+		"JsonIgnored": "x",
+		"unexported":  "x",
+	}
+	o := &order{}
+	err := Bind(m, o)
+	require.NoError(t, err)
+	require.Equal(t, "o-1", o.ID)
+	require.Equal(t, "Bilbo", o.Customer.OrElse(""))
+	require.Equal(t, SourceBind, o.Customer.Source())
+	require.NotNil(t, o.Email)
+	require.Equal(t, "bilbo@example.com", o.Email.OrElse(""))
+	require.Equal(t, float64(1), o.FirstQty.OrElse(0))
+	require.Equal(t, float64(2), o.LastQty.OrElse(0))
+	require.Equal(t, "shop", o.Label.OrElse(""))
+	require.False(t, o.Missing.WasSet())
+	require.Nil(t, o.MissingPtr)
+	require.True(t, o.Null.WasSet())
+	require.False(t, o.Null.IsPresent())
+	require.Equal(t, SourceBind, o.Null.Source())
+	require.Equal(t, "open", o.Status)
+	require.Equal(t, 10.5, o.Total)
+	require.Equal(t, "Bagshot Row", o.Address.Street)
+	require.Equal(t, "SH1 1RE", o.Address.Postcode.OrElse(""))
+	require.NotNil(t, o.AddressPtr)
+	require.Equal(t, "Bagshot Row", o.AddressPtr.Street)
+	require.Equal(t, "Bilbo", o.Raw["name"])
+	require.Equal(t, 2, len(o.Any.([]any)))
+	require.Equal(t, "", o.Ignored)
+	require.Equal(t, "", o.JsonIgnored)
+	require.Equal(t, "", o.unexported)
+	require.Equal(t, 2, len(o.Lines))
+	require.Equal(t, 2, len(o.Counts.OrElse(nil)))
+	require.True(t, o.Meta.IsPresent())
+	require.Equal(t, "hello", o.Note.OrElse(""))
+}
+
+func TestBind_Errors(t *testing.T) {
+	type nested struct {
+		Value int `json:"value"`
+	}
+	type target struct {
+		Age     Optional[int]  `json:"age"`
+		Count   int            `json:"count"`
+		Name    string         `json:"name"`
+		Nested  nested         `json:"nested"`
+		BadPath Optional[bool] `gopt:"foo[x]"`
+		Ok      string         `json:"ok"`
+	}
+	m := map[string]any{
+		"age":    "42",
+		"count":  float64(1),
+		"name":   1,
+		"nested": map[string]any{"value": "x"},
+		"ok":     "yes",
+	}
+	dst := &target{}
+	err := Bind(m, dst)
+	require.Error(t, err)
+	var bErr *BindError
+	require.True(t, errors.As(err, &bErr))
+	require.Equal(t, 5, len(bErr.Errors))
+	require.Equal(t, "Age", bErr.Errors[0].Field)
+	require.Equal(t, "age", bErr.Errors[0].Path)
+	require.Equal(t, "cannot convert string to int", bErr.Errors[0].Message)
+	require.Equal(t, "Count", bErr.Errors[1].Field)
+	require.Equal(t, "cannot convert number to int", bErr.Errors[1].Message)
+	require.Equal(t, "Name", bErr.Errors[2].Field)
+	require.Equal(t, "Nested.Value", bErr.Errors[3].Field)
+	require.Equal(t, "nested.value", bErr.Errors[3].Path)
+	require.Equal(t, "BadPath", bErr.Errors[4].Field)
+	require.Equal(t, "foo[x]", bErr.Errors[4].Path)
+	require.Equal(t, "yes", dst.Ok)
+	require.False(t, dst.Age.WasSet())
+	require.Equal(t, `field "Age" (path "age"): cannot convert string to int`, bErr.Errors[0].Error())
+	require.Contains(t, err.Error(), "bind failed (5 field errors): ")
+}
+
+func TestBind_Converters(t *testing.T) {
+	type target struct {
+		Age   Optional[int]  `json:"age"`
+		Count int            `json:"count"`
+		Size  *Optional[int] `json:"size"`
+		Name  string         `json:"name"`
+	}
+	m := map[string]any{
+		"age":   "42",
+		"count": float64(1),
+		"size":  float64(3),
+		"name":  1,
+	}
+	floatToInt := func(v any) (int, bool) {
+		if f, ok := v.(float64); ok {
+			return int(f), true
+		}
+		return 0, false
+	}
+	strToInt := func(v any) (int, bool) {
+		if s, ok := v.(string); ok {
+			i, err := strconv.Atoi(s)
+			return i, err == nil
+		}
+		return 0, false
+	}
+	intToStr := func(v any) (string, bool) {
+		if i, ok := v.(int); ok {
+			return fmt.Sprintf("%d", i), true
+		}
+		return "", false
+	}
+	dst := &target{}
+	err := Bind(m, dst,
+		BindTypeConverters[int](floatToInt),
+		BindFieldConverters[int]("Age", strToInt),
+		BindFieldConverters[string]("Name", intToStr))
+	require.NoError(t, err)
+	require.Equal(t, 42, dst.Age.OrElse(0))
+	require.Equal(t, 1, dst.Count)
+	require.Equal(t, 3, dst.Size.OrElse(0))
+	require.Equal(t, "1", dst.Name)
+}
+
+func TestBind_FromJson(t *testing.T) {
+	type target struct {
+		Name  Optional[string] `json:"name"`
+		Email Optional[string] `json:"email"`
+		Phone Optional[string] `json:"phone"`
+	}
+	var m map[string]any
+	err := json.Unmarshal([]byte(`{"name": "Frodo", "email": null}`), &m)
+	require.NoError(t, err)
+	dst := &target{}
+	err = Bind(m, dst)
+	require.NoError(t, err)
+	require.True(t, dst.Name.WasSet())
+	require.True(t, dst.Name.IsPresent())
+	require.True(t, dst.Email.WasSet())
+	require.False(t, dst.Email.IsPresent())
+	require.False(t, dst.Phone.WasSet())
+}
+
+func TestBind_InvalidTarget(t *testing.T) {
+	type target struct{}
+	err := Bind(map[string]any{}, target{})
+	require.Equal(t, InvalidBindTarget, err)
+	err = Bind(map[string]any{}, (*target)(nil))
+	require.Equal(t, InvalidBindTarget, err)
+	s := ""
+	err = Bind(map[string]any{}, &s)
+	require.Equal(t, InvalidBindTarget, err)
+}
@@ -0,0 +1,405 @@
+package gopt
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CacheLoaderPanicked is the error returned (by Cache.GetOrLoad) to callers waiting on a loader call that panicked
+var CacheLoaderPanicked = errors.New("cache loader panicked")
+
+// CacheLoader is the function passed to Cache.GetOrLoad to load the value for a key that is not cached
+//
+// If the returned optional is not present (e.g. Empty), the key is cached as known absent (see CacheNegativeTTL) - if
+// an error is returned, nothing is cached
+type CacheLoader[K comparable, V any] func(ctx context.Context, key K) (*Optional[V], error)
+
+// CacheOption is an option that can be passed to NewCache
+type CacheOption func(cfg *cacheConfig)
+
+type cacheConfig struct {
+	ttl            time.Duration
+	negativeTtl    time.Duration
+	negativeTtlSet bool
+	noNegative     bool
+	maxSize        int
+	clock          func() time.Time
+}
+
+// CacheTTL is a CacheOption that sets the default time-to-live for cached values (zero or negative means no expiry)
+func CacheTTL(ttl time.Duration) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// CacheNegativeTTL is a CacheOption that sets the time-to-live for keys cached as known absent (zero or negative means
+// no expiry)
+//
+// If not specified, the negative time-to-live is the same as the default time-to-live (see CacheTTL)
+func CacheNegativeTTL(ttl time.Duration) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.negativeTtl = ttl
+		cfg.negativeTtlSet = true
+	}
+}
+
+// CacheNoNegative is a CacheOption that disables negative caching (i.e. loaders returning a not present value are not cached)
+func CacheNoNegative() CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.noNegative = true
+	}
+}
+
+// CacheMaxSize is a CacheOption that sets the maximum number of cached entries (including known absent entries) - when
+// the cache is full, the least recently used entry is evicted (zero or negative means unbounded)
+func CacheMaxSize(n int) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.maxSize = n
+	}
+}
+
+// CacheClock is a CacheOption that sets the clock used to determine expiry (by default, time.Now is used)
+func CacheClock(clock func() time.Time) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.clock = clock
+	}
+}
+
+// CacheStats is the statistics returned by Cache.Stats
+type CacheStats struct {
+	// Hits is the number of lookups that found a (non-expired) cached entry - including known absent entries
+	Hits uint64
+	// NegativeHits is the number of lookups that found a (non-expired) known absent entry
+	NegativeHits uint64
+	// Misses is the number of lookups that did not find a (non-expired) cached entry
+	Misses uint64
+	// Loads is the number of loader calls (concurrent misses for the same key share a single loader call)
+	Loads uint64
+	// LoadErrors is the number of loader calls that returned an error
+	LoadErrors uint64
+	// Evictions is the number of entries evicted because the cache was full
+	Evictions uint64
+	// Expirations is the number of entries removed because they had expired
+	Expirations uint64
+}
+
+// HitRatio returns the ratio of hits to lookups (0 if there have been no lookups)
+func (s CacheStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// Cache is a concurrency safe, expiring, size bounded cache of optionals
+//
+// Use NewCache to create a cache - for example:
+//   users := gopt.NewCache[string, *User](gopt.CacheTTL(time.Minute), gopt.CacheMaxSize(1000))
+//   ...
+//   user, err := users.GetOrLoad(ctx, id, func(ctx context.Context, id string) (*gopt.Optional[*User], error) {
+//     return findUser(ctx, id)
+//   })
+//
+// As with OptMap, values that are not present (e.g. nil) are not stored as values - instead, the key is cached as
+// known absent (negative caching)
+type Cache[K comparable, V any] struct {
+	mutex   sync.Mutex
+	cfg     cacheConfig
+	entries map[K]*list.Element
+	lru     *list.List
+	calls   map[K]*cacheCall[V]
+	stats   CacheStats
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	present bool
+	expires time.Time
+}
+
+type cacheCall[V any] struct {
+	done   chan struct{}
+	result *Optional[V]
+	err    error
+	// stale is set when the key is put, deleted or cleared during the load (the loaded result is then not cached)
+	stale bool
+}
+
+// detachedContext is the context passed to loaders - it carries the values of the first caller's context but is
+// never cancelled and has no deadline (so that the first caller giving up does not fail the load for other callers)
+type detachedContext struct {
+	parent context.Context
+}
+
+func (ctx detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (ctx detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (ctx detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key any) any {
+	return ctx.parent.Value(key)
+}
+
+// NewCache creates a new Cache with the supplied options
+func NewCache[K comparable, V any](options ...CacheOption) *Cache[K, V] {
+	cfg := cacheConfig{
+		clock: time.Now,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if !cfg.negativeTtlSet {
+		cfg.negativeTtl = cfg.ttl
+	}
+	if cfg.clock == nil {
+		cfg.clock = time.Now
+	}
+	return &Cache[K, V]{
+		cfg:     cfg,
+		entries: map[K]*list.Element{},
+		lru:     list.New(),
+		calls:   map[K]*cacheCall[V]{},
+	}
+}
+
+// Get returns an optional of the cached value for the key
+//
+// If the key is not cached (or has expired), an empty (unset) optional is returned.  If the key is cached as known
+// absent, an optional that is set (with source SourceCache) but not present is returned.  Otherwise, the returned
+// optional is set (with source SourceCache) and present
+func (c *Cache[K, V]) Get(key K) *Optional[V] {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if o, ok := c.lookup(key); ok {
+		return o
+	}
+	return Empty[V]()
+}
+
+// GetOrLoad returns an optional of the cached value for the key (see Get) or, if the key is not cached (or has expired),
+// calls the supplied loader and caches the result
+//
+// Concurrent calls for the same key share a single loader call - callers waiting on another caller's load return early
+// (with the context error) if their own context is done.  The loader is called with a context that carries the values
+// of the first caller's context, but is not cancelled (and has no deadline) when the first caller's context is
+//
+// If the key is put, deleted or the cache cleared while the loader is running, the loaded result is returned to the
+// callers of that load but is not cached
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, loader CacheLoader[K, V]) (*Optional[V], error) {
+	c.mutex.Lock()
+	if o, ok := c.lookup(key); ok {
+		c.mutex.Unlock()
+		return o, nil
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mutex.Unlock()
+		return call.wait(ctx)
+	}
+	call := &cacheCall[V]{done: make(chan struct{})}
+	c.calls[key] = call
+	c.stats.Loads++
+	c.mutex.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.load(detachedContext{parent: ctx}, key, loader, call)
+	return call.result, call.err
+}
+
+func (c *Cache[K, V]) load(ctx context.Context, key K, loader CacheLoader[K, V], call *cacheCall[V]) {
+	completed := false
+	defer func() {
+		if !completed {
+			call.err = CacheLoaderPanicked
+		}
+		c.mutex.Lock()
+		if c.calls[key] == call {
+			delete(c.calls, key)
+		}
+		if call.err != nil {
+			c.stats.LoadErrors++
+		} else if call.stale {
+			call.result = cacheOptional(call.result.OrElse(*new(V)), call.result.IsPresent())
+		} else if call.result.IsPresent() {
+			call.result = c.put(key, call.result.value, true, c.cfg.ttl)
+		} else if !c.cfg.noNegative {
+			call.result = c.put(key, *new(V), false, c.cfg.negativeTtl)
+		} else {
+			call.result = cacheOptional(*new(V), false)
+		}
+		c.mutex.Unlock()
+		close(call.done)
+	}()
+	call.result, call.err = loader(ctx, key)
+	if call.err != nil {
+		call.result = Empty[V]()
+	}
+	completed = true
+}
+
+func (call *cacheCall[V]) wait(ctx context.Context) (*Optional[V], error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return Empty[V](), call.err
+		}
+		return cacheOptional(call.result.value, call.result.present), nil
+	case <-ctx.Done():
+		return Empty[V](), ctx.Err()
+	}
+}
+
+// Put caches the value for the key (using the default time-to-live - see CacheTTL)
+//
+// If the value is not present (e.g. nil), the key is cached as known absent
+func (c *Cache[K, V]) Put(key K, v V) {
+	c.PutWithTTL(key, v, c.cfg.ttl)
+}
+
+// PutWithTTL caches the value for the key with the specified time-to-live (zero or negative means no expiry)
+//
+// If the value is not present (e.g. nil), the key is cached as known absent (unless negative caching is disabled - see CacheNoNegative)
+func (c *Cache[K, V]) PutWithTTL(key K, v V, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidate(key)
+	if present := isPresent(v); present || !c.cfg.noNegative {
+		c.put(key, v, present, ttl)
+	}
+}
+
+// PutAbsent caches the key as known absent (using the negative time-to-live - see CacheNegativeTTL)
+//
+// If negative caching is disabled (see CacheNoNegative), does nothing
+func (c *Cache[K, V]) PutAbsent(key K) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidate(key)
+	if !c.cfg.noNegative {
+		c.put(key, *new(V), false, c.cfg.negativeTtl)
+	}
+}
+
+// Delete removes the key from the cache
+//
+// returns true if the key was cached (even if it had expired)
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidate(key)
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+		return true
+	}
+	return false
+}
+
+// Clear removes all entries from the cache (statistics are not reset)
+func (c *Cache[K, V]) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, call := range c.calls {
+		call.stale = true
+	}
+	c.calls = map[K]*cacheCall[V]{}
+	c.entries = map[K]*list.Element{}
+	c.lru.Init()
+}
+
+// Len returns the number of cached entries (including known absent and expired entries not yet removed)
+func (c *Cache[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the cache statistics
+func (c *Cache[K, V]) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
+
+func (c *Cache[K, V]) lookup(key K) (*Optional[V], bool) {
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry[K, V])
+		if e.expires.IsZero() || c.cfg.clock().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			if !e.present {
+				c.stats.NegativeHits++
+			}
+			return e.optional(), true
+		}
+		c.remove(el)
+		c.stats.Expirations++
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *Cache[K, V]) put(key K, v V, present bool, ttl time.Duration) *Optional[V] {
+	e := &cacheEntry[K, V]{
+		key:     key,
+		value:   v,
+		present: present,
+	}
+	if ttl > 0 {
+		e.expires = c.cfg.clock().Add(ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+	} else {
+		c.entries[key] = c.lru.PushFront(e)
+		for c.cfg.maxSize > 0 && c.lru.Len() > c.cfg.maxSize {
+			c.remove(c.lru.Back())
+			c.stats.Evictions++
+		}
+	}
+	return e.optional()
+}
+
+// invalidate marks any load in progress for the key as stale - so that its result is not cached (and subsequent
+// GetOrLoad calls start a new load)
+func (c *Cache[K, V]) invalidate(key K) {
+	if call, ok := c.calls[key]; ok {
+		call.stale = true
+		delete(c.calls, key)
+	}
+}
+
+func (c *Cache[K, V]) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry[K, V]).key)
+}
+
+func (e *cacheEntry[K, V]) optional() *Optional[V] {
+	return cacheOptional(e.value, e.present)
+}
+
+func cacheOptional[V any](v V, present bool) *Optional[V] {
+	result := Empty[V]()
+	if present {
+		result.setValue(v, SourceCache)
+	} else {
+		result.clear(SourceCache)
+	}
+	return result
+}
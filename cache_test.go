@@ -0,0 +1,443 @@
+package gopt
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c := NewCache[string, int]()
+	o := c.Get("a")
+	require.False(t, o.WasSet())
+	require.False(t, o.IsPresent())
+
+	c.Put("a", 1)
+	o = c.Get("a")
+	require.True(t, o.WasSet())
+	require.True(t, o.IsPresent())
+	require.Equal(t, 1, o.OrElse(0))
+	require.Equal(t, SourceCache, o.Source())
+	require.Equal(t, 1, c.Len())
+
+	c.PutAbsent("b")
+	o = c.Get("b")
+	require.True(t, o.WasSet())
+	require.False(t, o.IsPresent())
+	require.Equal(t, SourceCache, o.Source())
+	require.Equal(t, 2, c.Len())
+
+	require.True(t, c.Delete("a"))
+	require.False(t, c.Delete("a"))
+	require.False(t, c.Get("a").WasSet())
+	c.Clear()
+	require.Equal(t, 0, c.Len())
+
+	stats := c.Stats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(1), stats.NegativeHits)
+	require.Equal(t, uint64(2), stats.Misses)
+	require.Equal(t, 0.5, stats.HitRatio())
+	require.Equal(t, 0.0, CacheStats{}.HitRatio())
+}
+
+func TestCache_PutNotPresent(t *testing.T) {
+	c := NewCache[string, *int]()
+	c.Put("a", nil)
+	o := c.Get("a")
+	require.True(t, o.WasSet())
+	require.False(t, o.IsPresent())
+
+	c = NewCache[string, *int](CacheNoNegative())
+	c.Put("a", nil)
+	c.PutAbsent("b")
+	require.Equal(t, 0, c.Len())
+	require.False(t, c.Get("a").WasSet())
+}
+
+func TestCache_TTL(t *testing.T) {
+	clock := newTestClock()
+	c := NewCache[string, int](CacheTTL(time.Minute), CacheNegativeTTL(10*time.Second), CacheClock(clock.Now))
+	c.Put("a", 1)
+	c.PutWithTTL("b", 2, 0)
+	c.PutWithTTL("c", 3, time.Hour)
+	c.PutAbsent("d")
+
+	clock.Advance(9 * time.Second)
+	require.True(t, c.Get("a").IsPresent())
+	require.True(t, c.Get("d").WasSet())
+	clock.Advance(time.Second)
+	require.False(t, c.Get("d").WasSet())
+	clock.Advance(50 * time.Second)
+	require.False(t, c.Get("a").WasSet())
+	require.True(t, c.Get("c").IsPresent())
+	clock.Advance(24 * time.Hour)
+	require.False(t, c.Get("c").WasSet())
+	require.True(t, c.Get("b").IsPresent())
+	require.Equal(t, 1, c.Len())
+	require.Equal(t, uint64(3), c.Stats().Expirations)
+}
+
+func TestCache_NegativeTTLDefaultsToTTL(t *testing.T) {
+	clock := newTestClock()
+	c := NewCache[string, int](CacheTTL(time.Minute), CacheClock(clock.Now))
+	c.PutAbsent("a")
+	clock.Advance(59 * time.Second)
+	require.True(t, c.Get("a").WasSet())
+	clock.Advance(time.Second)
+	require.False(t, c.Get("a").WasSet())
+}
+
+func TestCache_LRU(t *testing.T) {
+	c := NewCache[string, int](CacheMaxSize(2))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	require.True(t, c.Get("a").IsPresent())
+	c.Put("c", 3)
+	require.Equal(t, 2, c.Len())
+	require.False(t, c.Get("b").WasSet())
+	require.True(t, c.Get("a").IsPresent())
+	require.True(t, c.Get("c").IsPresent())
+	// replacing does not evict...
+	c.Put("a", 10)
+	require.Equal(t, 2, c.Len())
+	require.Equal(t, 10, c.Get("a").OrElse(0))
+	c.Put("d", 4)
+	require.False(t, c.Get("c").WasSet())
+	require.Equal(t, uint64(2), c.Stats().Evictions)
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	c := NewCache[string, int]()
+	calls := 0
+	loader := func(ctx context.Context, key string) (*Optional[int], error) {
+		calls++
+		switch key {
+		case "absent":
+			return Empty[int](), nil
+		case "error":
+			return nil, errors.New("failed")
+		}
+		return Of(len(key)), nil
+	}
+	o, err := c.GetOrLoad(context.Background(), "abc", loader)
+	require.NoError(t, err)
+	require.Equal(t, 3, o.OrElse(0))
+	require.Equal(t, SourceCache, o.Source())
+	o, err = c.GetOrLoad(context.Background(), "abc", loader)
+	require.NoError(t, err)
+	require.Equal(t, 3, o.OrElse(0))
+	require.Equal(t, 1, calls)
+
+	o, err = c.GetOrLoad(context.Background(), "absent", loader)
+	require.NoError(t, err)
+	require.True(t, o.WasSet())
+	require.False(t, o.IsPresent())
+	o, err = c.GetOrLoad(context.Background(), "absent", loader)
+	require.NoError(t, err)
+	require.True(t, o.WasSet())
+	require.Equal(t, 2, calls)
+
+	o, err = c.GetOrLoad(context.Background(), "error", loader)
+	require.Error(t, err)
+	require.False(t, o.WasSet())
+	_, err = c.GetOrLoad(context.Background(), "error", loader)
+	require.Error(t, err)
+	require.Equal(t, 4, calls)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(4), stats.Loads)
+	require.Equal(t, uint64(2), stats.LoadErrors)
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(1), stats.NegativeHits)
+	require.Equal(t, uint64(4), stats.Misses)
+}
+
+func TestCache_GetOrLoad_NoNegative(t *testing.T) {
+	c := NewCache[string, int](CacheNoNegative())
+	calls := 0
+	loader := func(ctx context.Context, key string) (*Optional[int], error) {
+		calls++
+		return nil, nil
+	}
+	for i := 0; i < 2; i++ {
+		o, err := c.GetOrLoad(context.Background(), "a", loader)
+		require.NoError(t, err)
+		require.True(t, o.WasSet())
+		require.False(t, o.IsPresent())
+	}
+	require.Equal(t, 2, calls)
+	require.Equal(t, 0, c.Len())
+}
+
+func TestCache_GetOrLoad_SingleFlight(t *testing.T) {
+	c := NewCache[string, string]()
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (*Optional[string], error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return Of("value-" + key), nil
+	}
+	const n = 20
+	var started, wg sync.WaitGroup
+	results := make([]*Optional[string], n)
+	for i := 0; i < n; i++ {
+		started.Add(1)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			o, err := c.GetOrLoad(context.Background(), "k", loader)
+			require.NoError(t, err)
+			results[i] = o
+		}(i)
+	}
+	started.Wait()
+	// wait until all callers are either loading or waiting...
+	for {
+		c.mutex.Lock()
+		misses := c.stats.Misses
+		c.mutex.Unlock()
+		if misses == n {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i, o := range results {
+		require.Equal(t, "value-k", o.OrElse(""))
+		for j := i + 1; j < n; j++ {
+			require.False(t, o == results[j])
+		}
+	}
+	require.Equal(t, uint64(1), c.Stats().Loads)
+}
+
+func TestCache_GetOrLoad_WaiterContextDone(t *testing.T) {
+	c := NewCache[string, int]()
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	go func() {
+		_, _ = c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (*Optional[int], error) {
+			close(loading)
+			<-release
+			return Of(1), nil
+		})
+	}()
+	<-loading
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	o, err := c.GetOrLoad(ctx, "k", func(ctx context.Context, key string) (*Optional[int], error) {
+		t.Fatal("loader should not be called")
+		return nil, nil
+	})
+	require.Equal(t, context.Canceled, err)
+	require.False(t, o.WasSet())
+	close(release)
+}
+
+func TestCache_GetOrLoad_DetachedContext(t *testing.T) {
+	type ctxKey struct{}
+	c := NewCache[string, int]()
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "v"))
+	var loaderErr error
+	var loaderValue any
+	first := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "k", func(ctx context.Context, key string) (*Optional[int], error) {
+			close(loading)
+			<-release
+			loaderErr = ctx.Err()
+			loaderValue = ctx.Value(ctxKey{})
+			return Of(1), nil
+		})
+		first <- err
+	}()
+	<-loading
+	waited := make(chan *Optional[int])
+	go func() {
+		o, _ := c.GetOrLoad(context.Background(), "k", nil)
+		waited <- o
+	}()
+	for {
+		c.mutex.Lock()
+		misses := c.stats.Misses
+		c.mutex.Unlock()
+		if misses == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// cancelling the first caller's context does not cancel the load for other callers...
+	cancel()
+	close(release)
+	require.NoError(t, <-first)
+	require.Equal(t, 1, (<-waited).OrElse(0))
+	require.NoError(t, loaderErr)
+	require.Equal(t, "v", loaderValue)
+	require.Equal(t, 1, c.Get("k").OrElse(0))
+}
+
+func TestCache_GetOrLoad_InvalidatedDuringLoad(t *testing.T) {
+	testCases := map[string]func(c *Cache[string, int]){
+		"Put": func(c *Cache[string, int]) {
+			c.Put("k", 2)
+		},
+		"PutAbsent": func(c *Cache[string, int]) {
+			c.PutAbsent("k")
+		},
+		"Delete": func(c *Cache[string, int]) {
+			c.Delete("k")
+		},
+		"Clear": func(c *Cache[string, int]) {
+			c.Clear()
+		},
+	}
+	for name, invalidate := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := NewCache[string, int]()
+			release := make(chan struct{})
+			loading := make(chan struct{})
+			loaded := make(chan *Optional[int])
+			go func() {
+				o, _ := c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (*Optional[int], error) {
+					close(loading)
+					<-release
+					return Of(1), nil
+				})
+				loaded <- o
+			}()
+			<-loading
+			invalidate(c)
+			before := c.Get("k")
+			close(release)
+			// the caller of the load gets the loaded value, but it is not cached...
+			o := <-loaded
+			require.Equal(t, 1, o.OrElse(0))
+			require.Equal(t, SourceCache, o.Source())
+			after := c.Get("k")
+			require.Equal(t, before.WasSet(), after.WasSet())
+			require.Equal(t, before.OrElse(-1), after.OrElse(-1))
+		})
+	}
+
+	// a GetOrLoad after the invalidation starts a new load...
+	c := NewCache[string, int]()
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		_, _ = c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (*Optional[int], error) {
+			close(loading)
+			<-release
+			return Of(1), nil
+		})
+		close(done)
+	}()
+	<-loading
+	c.Delete("k")
+	o, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (*Optional[int], error) {
+		return Of(2), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, o.OrElse(0))
+	close(release)
+	<-done
+	require.Equal(t, 2, c.Get("k").OrElse(0))
+	require.Equal(t, uint64(2), c.Stats().Loads)
+}
+
+func TestCache_GetOrLoad_LoaderPanics(t *testing.T) {
+	c := NewCache[string, int]()
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	panicked := make(chan any)
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		_, _ = c.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (*Optional[int], error) {
+			close(loading)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-loading
+	waited := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), "k", nil)
+		waited <- err
+	}()
+	for {
+		c.mutex.Lock()
+		misses := c.stats.Misses
+		c.mutex.Unlock()
+		if misses == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	require.Equal(t, "boom", <-panicked)
+	require.Equal(t, CacheLoaderPanicked, <-waited)
+	require.Equal(t, 0, c.Len())
+	require.Equal(t, uint64(1), c.Stats().LoadErrors)
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	c := NewCache[int, int](CacheMaxSize(50))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := (i * (g + 1)) % 100
+				switch i % 4 {
+				case 0:
+					c.Put(k, i)
+				case 1:
+					c.Get(k)
+				case 2:
+					_, _ = c.GetOrLoad(context.Background(), k, func(ctx context.Context, key int) (*Optional[int], error) {
+						return Of(key), nil
+					})
+				default:
+					c.Delete(k)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	require.LessOrEqual(t, c.Len(), 50)
+}
@@ -0,0 +1,123 @@
+package gopt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ContextValueNotPresent is the error panicked by ContextKey.MustFrom when the context value is not present
+var ContextValueNotPresent = errors.New("context value not present")
+
+// ContextKey is a typed key for context.Context values
+//
+// Use NewContextKey to create keys - each key created is distinct (even if created with the same name), for example:
+//   var RequestId = gopt.NewContextKey[string]("request-id")
+//   ...
+//   ctx = RequestId.WithValue(ctx, "abc123")
+//   ...
+//   id := RequestId.From(ctx).OrElse("unknown")
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey creates a new typed context key with the supplied name (the name is used only for descriptive purposes)
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{
+		name: name,
+	}
+}
+
+// Name returns the name of the key
+func (k *ContextKey[T]) Name() string {
+	return k.name
+}
+
+// String implements fmt.Stringer
+func (k *ContextKey[T]) String() string {
+	return fmt.Sprintf("gopt.ContextKey[%T](%s)", *new(T), k.name)
+}
+
+// WithValue returns a copy of the supplied context with the key set to the supplied value
+//
+// If the supplied context is nil, context.Background is used
+func (k *ContextKey[T]) WithValue(ctx context.Context, v T) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, k, v)
+}
+
+// From returns an optional of the key's value in the supplied context
+//
+// If the context is nil, the key has no value in the context or the value is not of the key's type, an empty
+// optional is returned (consistent with MapTo)
+func (k *ContextKey[T]) From(ctx context.Context) *Optional[T] {
+	if ctx != nil {
+		if v, ok := ctx.Value(k).(T); ok {
+			return Of[T](v)
+		}
+	}
+	return Empty[T]()
+}
+
+// MustFrom returns the key's value in the supplied context
+//
+// If the value is not present (see From), it panics with an error wrapping ContextValueNotPresent
+func (k *ContextKey[T]) MustFrom(ctx context.Context) T {
+	if o := k.From(ctx); o.present {
+		return o.value
+	}
+	panic(fmt.Errorf("%w: %s", ContextValueNotPresent, k.name))
+}
+
+// FromRequest returns an optional of the key's value in the supplied request's context (see From)
+func (k *ContextKey[T]) FromRequest(r *http.Request) *Optional[T] {
+	if r == nil {
+		return Empty[T]()
+	}
+	return k.From(r.Context())
+}
+
+// WithRequestValue returns a shallow copy of the supplied request with the key set to the supplied value in the request's context
+func (k *ContextKey[T]) WithRequestValue(r *http.Request, v T) *http.Request {
+	return r.WithContext(k.WithValue(r.Context(), v))
+}
+
+// Middleware returns http middleware that, for each request, populates the request's context with the value returned by
+// the supplied extract func (if the returned optional is present) - for example:
+//   var UserAgent = gopt.NewContextKey[string]("user-agent")
+//   ...
+//   handler = UserAgent.Middleware(gopt.HeaderValue[string]("User-Agent"))(handler)
+func (k *ContextKey[T]) Middleware(extract func(r *http.Request) *Optional[T]) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o := extract(r); o.IsPresent() {
+				r = k.WithRequestValue(r, o.value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HeaderValue returns an extract func (for use with ContextKey.Middleware) that extracts a request header value (see ExtractHeader)
+func HeaderValue[T any](key string, converters ...Converter[T]) func(r *http.Request) *Optional[T] {
+	return func(r *http.Request) *Optional[T] {
+		return ExtractHeader[T](r.Header, key, converters...)
+	}
+}
+
+// QueryValue returns an extract func (for use with ContextKey.Middleware) that extracts a request URL query value (see ExtractQuery)
+func QueryValue[T any](key string, converters ...Converter[T]) func(r *http.Request) *Optional[T] {
+	return func(r *http.Request) *Optional[T] {
+		return ExtractQuery[T](r.URL.Query(), key, converters...)
+	}
+}
+
+// FormValue returns an extract func (for use with ContextKey.Middleware) that extracts a request form value (see ExtractForm)
+func FormValue[T any](key string, converters ...Converter[T]) func(r *http.Request) *Optional[T] {
+	return func(r *http.Request) *Optional[T] {
+		return ExtractForm[T](r, key, converters...)
+	}
+}
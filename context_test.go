@@ -0,0 +1,106 @@
+package gopt
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextKey(t *testing.T) {
+	key := NewContextKey[string]("request-id")
+	require.Equal(t, "request-id", key.Name())
+	require.Equal(t, "gopt.ContextKey[string](request-id)", key.String())
+
+	ctx := context.Background()
+	o := key.From(ctx)
+	require.False(t, o.IsPresent())
+	require.False(t, o.WasSet())
+
+	ctx = key.WithValue(ctx, "abc")
+	o = key.From(ctx)
+	require.True(t, o.IsPresent())
+	require.Equal(t, "abc", o.OrElse(""))
+	require.Equal(t, "abc", key.MustFrom(ctx))
+
+	// keys with the same name and type are distinct...
+	other := NewContextKey[string]("request-id")
+	require.False(t, other.From(ctx).IsPresent())
+
+	// nil context...
+	require.False(t, key.From(nil).IsPresent())
+	ctx = key.WithValue(nil, "x")
+	require.Equal(t, "x", key.MustFrom(ctx))
+}
+
+func TestContextKey_TypeMismatch(t *testing.T) {
+	key := NewContextKey[int]("n")
+	ctx := context.WithValue(context.Background(), key, "not an int")
+	require.False(t, key.From(ctx).IsPresent())
+}
+
+func TestContextKey_NilValue(t *testing.T) {
+	key := NewContextKey[*int]("p")
+	ctx := key.WithValue(context.Background(), nil)
+	require.False(t, key.From(ctx).IsPresent())
+}
+
+func TestContextKey_MustFrom_Panics(t *testing.T) {
+	key := NewContextKey[int]("n")
+	defer func() {
+		r := recover()
+		require.NotNil(t, r)
+		err, ok := r.(error)
+		require.True(t, ok)
+		require.True(t, errors.Is(err, ContextValueNotPresent))
+		require.Equal(t, "context value not present: n", err.Error())
+	}()
+	key.MustFrom(context.Background())
+}
+
+func TestContextKey_Request(t *testing.T) {
+	key := NewContextKey[int]("n")
+	require.False(t, key.FromRequest(nil).IsPresent())
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	require.False(t, key.FromRequest(r).IsPresent())
+	r2 := key.WithRequestValue(r, 42)
+	require.Equal(t, 42, key.FromRequest(r2).OrElse(0))
+	require.False(t, key.FromRequest(r).IsPresent())
+}
+
+func TestContextKey_Middleware(t *testing.T) {
+	requestId := NewContextKey[string]("request-id")
+	page := NewContextKey[int]("page")
+	var gotId *Optional[string]
+	var gotPage *Optional[int]
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotId = requestId.FromRequest(r)
+		gotPage = page.FromRequest(r)
+	}))
+	handler = requestId.Middleware(HeaderValue[string]("x-request-id"))(handler)
+	handler = page.Middleware(QueryValue[int]("page"))(handler)
+
+	r := httptest.NewRequest(http.MethodGet, "/?page=3", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	require.Equal(t, "abc", gotId.OrElse(""))
+	require.Equal(t, 3, gotPage.OrElse(0))
+
+	r = httptest.NewRequest(http.MethodGet, "/?page=x", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	require.False(t, gotId.IsPresent())
+	require.False(t, gotPage.IsPresent())
+}
+
+func TestContextKey_Middleware_FormValue(t *testing.T) {
+	name := NewContextKey[string]("name")
+	var got *Optional[string]
+	handler := name.Middleware(FormValue[string]("name"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = name.FromRequest(r)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/?name=foo", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	require.Equal(t, "foo", got.OrElse(""))
+}
@@ -0,0 +1,346 @@
+// Package convert - ready-made gopt.Converter implementations for use with gopt.Extract, gopt.ExtractJson, gopt.ExtractJsonPath etc.
+/*
+Converters are provided for the common cases when extracting values from maps (e.g. as unmarshalled from JSON) - such as
+float64 to int (JSON numbers are unmarshalled as float64), numeric strings to numbers, RFC 3339 strings to time.Time etc.
+
+All numeric converters check for overflow and precision-loss (and reject the value rather than silently truncating it)
+
+Default returns the appropriate chain of converters for a type - e.g.
+	o := gopt.Extract[string, int](m, "count", convert.Default[int]()...)
+*/
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-andiamo/gopt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Signed is a constraint for signed integer types
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is a constraint for unsigned integer types
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Integer is a constraint for integer types
+type Integer interface {
+	Signed | Unsigned
+}
+
+// Float is a constraint for floating-point types
+type Float interface {
+	~float32 | ~float64
+}
+
+// Number is a constraint for integer and floating-point types
+type Number interface {
+	Integer | Float
+}
+
+// ToInteger returns a converter that converts any numeric value (including json.Number) to the integer type T
+//
+// The value is rejected if it would overflow T or, for floating-point values, if it has a fractional part (or is NaN or infinite)
+func ToInteger[T Integer]() gopt.Converter[T] {
+	return func(value any) (T, bool) {
+		switch vt := value.(type) {
+		case json.Number:
+			return parseInteger[T](string(vt))
+		}
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return fromInt64[T](rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return fromUint64[T](rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			return fromFloat64[T](rv.Float())
+		}
+		return 0, false
+	}
+}
+
+// ToFloat returns a converter that converts any numeric value (including json.Number) to the floating-point type T
+//
+// Integer values are rejected if they cannot be represented exactly by T (precision-loss), and floating-point values
+// are rejected if they would overflow T (converting float64 to float32 may round the value)
+func ToFloat[T Float]() gopt.Converter[T] {
+	return func(value any) (T, bool) {
+		switch vt := value.(type) {
+		case json.Number:
+			return parseFloat[T](string(vt))
+		}
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := rv.Int()
+			t := T(n)
+			if f := float64(t); f < math.MaxInt64 && int64(f) == n {
+				return t, true
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u := rv.Uint()
+			t := T(u)
+			if f := float64(t); f < math.MaxUint64 && uint64(f) == u {
+				return t, true
+			}
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			t := T(f)
+			if !math.IsInf(float64(t), 0) || math.IsInf(f, 0) {
+				return t, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// ParseNumber returns a converter that parses numeric strings (and json.Number) to the numeric type T
+//
+// The value is rejected if it is not a valid number for T (e.g. "1.5" for an integer type) or would overflow T
+func ParseNumber[T Number]() gopt.Converter[T] {
+	return func(value any) (T, bool) {
+		var s string
+		switch vt := value.(type) {
+		case string:
+			s = vt
+		case json.Number:
+			s = string(vt)
+		default:
+			return 0, false
+		}
+		switch reflect.TypeOf(T(0)).Kind() {
+		case reflect.Float32, reflect.Float64:
+			return parseFloat[T](s)
+		}
+		return parseInteger[T](s)
+	}
+}
+
+// ToBool is a converter that converts strings (as accepted by strconv.ParseBool - e.g. "true", "1", "false", "0") and
+// the numbers 0 and 1 to bool
+var ToBool gopt.Converter[bool] = func(value any) (bool, bool) {
+	switch vt := value.(type) {
+	case string:
+		b, err := strconv.ParseBool(vt)
+		return b, err == nil
+	}
+	if n, ok := ToInteger[uint8]()(value); ok && n <= 1 {
+		return n == 1, true
+	}
+	return false, false
+}
+
+// ToTime is a converter that converts RFC 3339 strings (with optional fractional seconds) to time.Time
+var ToTime = ToTimeLayout(time.RFC3339)
+
+// ToTimeLayout returns a converter that converts strings to time.Time using the supplied layouts (each layout is tried in turn)
+func ToTimeLayout(layouts ...string) gopt.Converter[time.Time] {
+	return func(value any) (time.Time, bool) {
+		if s, ok := value.(string); ok {
+			for _, layout := range layouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t, true
+				}
+			}
+		}
+		return time.Time{}, false
+	}
+}
+
+// ToDuration is a converter that converts duration strings (as accepted by time.ParseDuration - e.g. "1h30m") to time.Duration
+var ToDuration gopt.Converter[time.Duration] = func(value any) (time.Duration, bool) {
+	if s, ok := value.(string); ok {
+		d, err := time.ParseDuration(s)
+		return d, err == nil
+	}
+	return 0, false
+}
+
+// ToBytes is a converter that converts base64 strings to []byte
+//
+// The standard, raw standard, URL and raw URL encodings are tried in turn
+var ToBytes = ToBytesEncoding(base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding)
+
+// ToBytesEncoding returns a converter that converts base64 strings to []byte using the supplied encodings (each encoding is tried in turn)
+func ToBytesEncoding(encodings ...*base64.Encoding) gopt.Converter[[]byte] {
+	return func(value any) ([]byte, bool) {
+		if s, ok := value.(string); ok {
+			for _, enc := range encodings {
+				if b, err := enc.DecodeString(s); err == nil {
+					return b, true
+				}
+			}
+		}
+		return nil, false
+	}
+}
+
+// ToBigInt is a converter that converts integer strings, json.Number and numeric values (floating-point values must not
+// have a fractional part) to *big.Int
+var ToBigInt gopt.Converter[*big.Int] = func(value any) (*big.Int, bool) {
+	switch vt := value.(type) {
+	case string:
+		return new(big.Int).SetString(vt, 10)
+	case json.Number:
+		return new(big.Int).SetString(string(vt), 10)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); !math.IsInf(f, 0) && !math.IsNaN(f) && f == math.Trunc(f) {
+			bi, _ := big.NewFloat(f).Int(nil)
+			return bi, true
+		}
+	}
+	return nil, false
+}
+
+// ToSlice returns a converter that converts a []any to []T - each element must either be of type T or be converted
+// by one of the supplied converters (otherwise the whole value is rejected)
+func ToSlice[T any](converters ...gopt.Converter[T]) gopt.Converter[[]T] {
+	return func(value any) ([]T, bool) {
+		sv, ok := value.([]any)
+		if !ok {
+			return nil, false
+		}
+		result := make([]T, len(sv))
+		for i, v := range sv {
+			if result[i], ok = convertElement[T](v, converters...); !ok {
+				return nil, false
+			}
+		}
+		return result, true
+	}
+}
+
+// Default returns the default chain of converters for the type T
+//
+// Defaults are provided for the built-in numeric types, bool, time.Time, time.Duration, []byte, *big.Int and slices
+// of string, int, int64, float64 and bool - for any other type, nil is returned
+func Default[T any]() []gopt.Converter[T] {
+	var chain any
+	switch any(*new(T)).(type) {
+	case int:
+		chain = numberChain[int]()
+	case int8:
+		chain = numberChain[int8]()
+	case int16:
+		chain = numberChain[int16]()
+	case int32:
+		chain = numberChain[int32]()
+	case int64:
+		chain = numberChain[int64]()
+	case uint:
+		chain = numberChain[uint]()
+	case uint8:
+		chain = numberChain[uint8]()
+	case uint16:
+		chain = numberChain[uint16]()
+	case uint32:
+		chain = numberChain[uint32]()
+	case uint64:
+		chain = numberChain[uint64]()
+	case float32:
+		chain = floatChain[float32]()
+	case float64:
+		chain = floatChain[float64]()
+	case bool:
+		chain = []gopt.Converter[bool]{ToBool}
+	case time.Time:
+		chain = []gopt.Converter[time.Time]{ToTime}
+	case time.Duration:
+		chain = []gopt.Converter[time.Duration]{ToDuration}
+	case []byte:
+		chain = []gopt.Converter[[]byte]{ToBytes}
+	case *big.Int:
+		chain = []gopt.Converter[*big.Int]{ToBigInt}
+	case []string:
+		chain = []gopt.Converter[[]string]{ToSlice[string]()}
+	case []int:
+		chain = []gopt.Converter[[]int]{ToSlice[int](numberChain[int]()...)}
+	case []int64:
+		chain = []gopt.Converter[[]int64]{ToSlice[int64](numberChain[int64]()...)}
+	case []float64:
+		chain = []gopt.Converter[[]float64]{ToSlice[float64](floatChain[float64]()...)}
+	case []bool:
+		chain = []gopt.Converter[[]bool]{ToSlice[bool](ToBool)}
+	default:
+		return nil
+	}
+	return chain.([]gopt.Converter[T])
+}
+
+func numberChain[T Integer]() []gopt.Converter[T] {
+	return []gopt.Converter[T]{ToInteger[T](), ParseNumber[T]()}
+}
+
+func floatChain[T Float]() []gopt.Converter[T] {
+	return []gopt.Converter[T]{ToFloat[T](), ParseNumber[T]()}
+}
+
+func convertElement[T any](value any, converters ...gopt.Converter[T]) (T, bool) {
+	if v, ok := value.(T); ok && value != nil {
+		return v, true
+	}
+	for _, c := range converters {
+		if v, ok := c(value); ok {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func fromInt64[T Number](n int64) (T, bool) {
+	if t := T(n); int64(t) == n && (t < 0) == (n < 0) {
+		return t, true
+	}
+	return 0, false
+}
+
+func fromUint64[T Number](u uint64) (T, bool) {
+	if t := T(u); t >= 0 && uint64(t) == u {
+		return t, true
+	}
+	return 0, false
+}
+
+func fromFloat64[T Integer](f float64) (T, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, false
+	} else if f >= math.MinInt64 && f < math.MaxInt64 {
+		return fromInt64[T](int64(f))
+	} else if f > 0 && f < math.MaxUint64 {
+		return fromUint64[T](uint64(f))
+	}
+	return 0, false
+}
+
+func parseInteger[T Number](s string) (T, bool) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return fromInt64[T](n)
+	} else if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return fromUint64[T](u)
+	}
+	return 0, false
+}
+
+func parseFloat[T Number](s string) (T, bool) {
+	if f, err := strconv.ParseFloat(s, reflect.TypeOf(T(0)).Bits()); err == nil {
+		return T(f), true
+	}
+	return 0, false
+}
@@ -0,0 +1,287 @@
+package convert
+
+import (
+	"encoding/json"
+	"github.com/go-andiamo/gopt"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestToInteger(t *testing.T) {
+	toInt := ToInteger[int]()
+	testCases := []struct {
+		value  any
+		expect int
+		ok     bool
+	}{
+		{float64(1), 1, true},
+		{float64(-1), -1, true},
+		{float64(1.5), 0, false},
+		{math.NaN(), 0, false},
+		{math.Inf(1), 0, false},
+		{float32(2), 2, true},
+		{int8(3), 3, true},
+		{uint64(4), 4, true},
+		{uint64(math.MaxUint64), 0, false},
+		{json.Number("5"), 5, true},
+		{json.Number("5.5"), 0, false},
+		{json.Number("99999999999999999999"), 0, false},
+		{"6", 0, false},
+		{true, 0, false},
+		{nil, 0, false},
+	}
+	for _, tc := range testCases {
+		v, ok := toInt(tc.value)
+		require.Equal(t, tc.ok, ok, "%#v", tc.value)
+		require.Equal(t, tc.expect, v, "%#v", tc.value)
+	}
+}
+
+func TestToInteger_Overflow(t *testing.T) {
+	_, ok := ToInteger[int8]()(float64(127))
+	require.True(t, ok)
+	_, ok = ToInteger[int8]()(float64(128))
+	require.False(t, ok)
+	_, ok = ToInteger[int8]()(float64(-129))
+	require.False(t, ok)
+	_, ok = ToInteger[uint8]()(float64(-1))
+	require.False(t, ok)
+	_, ok = ToInteger[uint8]()(-1)
+	require.False(t, ok)
+	_, ok = ToInteger[uint8]()(256)
+	require.False(t, ok)
+	_, ok = ToInteger[int64]()(uint64(math.MaxUint64))
+	require.False(t, ok)
+	u, ok := ToInteger[uint64]()(float64(1 << 63))
+	require.True(t, ok)
+	require.Equal(t, uint64(1<<63), u)
+	_, ok = ToInteger[uint64]()(float64(1 << 64))
+	require.False(t, ok)
+	u, ok = ToInteger[uint64]()(json.Number("18446744073709551615"))
+	require.True(t, ok)
+	require.Equal(t, uint64(math.MaxUint64), u)
+	_, ok = ToInteger[int64]()(json.Number("18446744073709551615"))
+	require.False(t, ok)
+}
+
+func TestToFloat(t *testing.T) {
+	f, ok := ToFloat[float64]()(1)
+	require.True(t, ok)
+	require.Equal(t, float64(1), f)
+	f, ok = ToFloat[float64]()(int64(1 << 53))
+	require.True(t, ok)
+	require.Equal(t, float64(1<<53), f)
+	_, ok = ToFloat[float64]()(int64(1<<53 + 1))
+	require.False(t, ok)
+	_, ok = ToFloat[float64]()(int64(math.MaxInt64))
+	require.False(t, ok)
+	_, ok = ToFloat[float64]()(uint64(math.MaxUint64))
+	require.False(t, ok)
+	f, ok = ToFloat[float64]()(json.Number("1.5"))
+	require.True(t, ok)
+	require.Equal(t, 1.5, f)
+	_, ok = ToFloat[float64]()(json.Number("x"))
+	require.False(t, ok)
+	_, ok = ToFloat[float64]()("1.5")
+	require.False(t, ok)
+
+	f32, ok := ToFloat[float32]()(float64(1.5))
+	require.True(t, ok)
+	require.Equal(t, float32(1.5), f32)
+	_, ok = ToFloat[float32]()(math.MaxFloat64)
+	require.False(t, ok)
+	f32, ok = ToFloat[float32]()(math.Inf(-1))
+	require.True(t, ok)
+	require.True(t, math.IsInf(float64(f32), -1))
+	_, ok = ToFloat[float32]()(1<<24 + 1)
+	require.False(t, ok)
+	_, ok = ToFloat[float32]()(json.Number("1e39"))
+	require.False(t, ok)
+}
+
+func TestParseNumber(t *testing.T) {
+	i, ok := ParseNumber[int]()("123")
+	require.True(t, ok)
+	require.Equal(t, 123, i)
+	_, ok = ParseNumber[int]()("1.5")
+	require.False(t, ok)
+	_, ok = ParseNumber[int8]()("128")
+	require.False(t, ok)
+	_, ok = ParseNumber[uint]()("-1")
+	require.False(t, ok)
+	_, ok = ParseNumber[int]()(123)
+	require.False(t, ok)
+	i, ok = ParseNumber[int]()(json.Number("42"))
+	require.True(t, ok)
+	require.Equal(t, 42, i)
+	f, ok := ParseNumber[float64]()("1.5")
+	require.True(t, ok)
+	require.Equal(t, 1.5, f)
+	_, ok = ParseNumber[float32]()("1e39")
+	require.False(t, ok)
+	_, ok = ParseNumber[float64]()("abc")
+	require.False(t, ok)
+}
+
+func TestToBool(t *testing.T) {
+	testCases := []struct {
+		value  any
+		expect bool
+		ok     bool
+	}{
+		{"true", true, true},
+		{"1", true, true},
+		{"false", false, true},
+		{"0", false, true},
+		{"yes", false, false},
+		{float64(1), true, true},
+		{float64(0), false, true},
+		{float64(2), false, false},
+		{-1, false, false},
+		{json.Number("1"), true, true},
+		{nil, false, false},
+	}
+	for _, tc := range testCases {
+		v, ok := ToBool(tc.value)
+		require.Equal(t, tc.ok, ok, "%#v", tc.value)
+		require.Equal(t, tc.expect, v, "%#v", tc.value)
+	}
+}
+
+func TestToTime(t *testing.T) {
+	v, ok := ToTime("2023-01-02T03:04:05Z")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), v)
+	v, ok = ToTime("2023-01-02T03:04:05.123+01:00")
+	require.True(t, ok)
+	require.Equal(t, 123000000, v.Nanosecond())
+	_, ok = ToTime("2023-01-02")
+	require.False(t, ok)
+	_, ok = ToTime(1)
+	require.False(t, ok)
+
+	toDate := ToTimeLayout(time.RFC3339, "2006-01-02")
+	v, ok = toDate("2023-01-02")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), v)
+}
+
+func TestToDuration(t *testing.T) {
+	d, ok := ToDuration("1h30m")
+	require.True(t, ok)
+	require.Equal(t, 90*time.Minute, d)
+	_, ok = ToDuration("1 hour")
+	require.False(t, ok)
+	_, ok = ToDuration(float64(1))
+	require.False(t, ok)
+}
+
+func TestToBytes(t *testing.T) {
+	b, ok := ToBytes("aGVsbG8/")
+	require.True(t, ok)
+	require.Equal(t, []byte("hello?"), b)
+	b, ok = ToBytes("aGVsbG8_")
+	require.True(t, ok)
+	require.Equal(t, []byte("hello?"), b)
+	b, ok = ToBytes("aGk")
+	require.True(t, ok)
+	require.Equal(t, []byte("hi"), b)
+	_, ok = ToBytes("!!!")
+	require.False(t, ok)
+	_, ok = ToBytes(1)
+	require.False(t, ok)
+}
+
+func TestToBigInt(t *testing.T) {
+	bi, ok := ToBigInt(json.Number("123456789012345678901234567890"))
+	require.True(t, ok)
+	require.Equal(t, "123456789012345678901234567890", bi.String())
+	bi, ok = ToBigInt("-42")
+	require.True(t, ok)
+	require.Equal(t, big.NewInt(-42), bi)
+	bi, ok = ToBigInt(float64(1e20))
+	require.True(t, ok)
+	require.Equal(t, "100000000000000000000", bi.String())
+	bi, ok = ToBigInt(uint64(math.MaxUint64))
+	require.True(t, ok)
+	require.Equal(t, "18446744073709551615", bi.String())
+	bi, ok = ToBigInt(int8(-1))
+	require.True(t, ok)
+	require.Equal(t, big.NewInt(-1), bi)
+	_, ok = ToBigInt(1.5)
+	require.False(t, ok)
+	_, ok = ToBigInt("1.5")
+	require.False(t, ok)
+	_, ok = ToBigInt(true)
+	require.False(t, ok)
+}
+
+func TestToSlice(t *testing.T) {
+	s, ok := ToSlice[string]()([]any{"a", "b"})
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b"}, s)
+	_, ok = ToSlice[string]()([]any{"a", 1})
+	require.False(t, ok)
+	_, ok = ToSlice[string]()([]any{"a", nil})
+	require.False(t, ok)
+	_, ok = ToSlice[string]()("a")
+	require.False(t, ok)
+	is, ok := ToSlice[int](ToInteger[int]())([]any{float64(1), 2})
+	require.True(t, ok)
+	require.Equal(t, []int{1, 2}, is)
+	_, ok = ToSlice[int](ToInteger[int]())([]any{float64(1.5)})
+	require.False(t, ok)
+	as, ok := ToSlice[any]()([]any{1, "a"})
+	require.True(t, ok)
+	require.Equal(t, []any{1, "a"}, as)
+}
+
+func TestDefault(t *testing.T) {
+	var m map[string]any
+	err := json.Unmarshal([]byte(`{
+		"int": 1,
+		"intStr": "2",
+		"float": 1.5,
+		"bool": "true",
+		"time": "2023-01-02T03:04:05Z",
+		"duration": "1m",
+		"bytes": "aGk=",
+		"big": "123456789012345678901234567890",
+		"ints": [1, "2", 3],
+		"strs": ["a", "b"]
+	}`), &m)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, gopt.ExtractJson[int](m, "int", Default[int]()...).OrElse(-1))
+	require.Equal(t, 2, gopt.ExtractJson[int](m, "intStr", Default[int]()...).OrElse(-1))
+	require.False(t, gopt.ExtractJson[int](m, "float", Default[int]()...).IsPresent())
+	require.Equal(t, uint8(2), gopt.ExtractJson[uint8](m, "intStr", Default[uint8]()...).OrElse(0))
+	require.Equal(t, float32(1.5), gopt.ExtractJson[float32](m, "float", Default[float32]()...).OrElse(0))
+	require.Equal(t, float64(2), gopt.ExtractJson[float64](m, "intStr", Default[float64]()...).OrElse(0))
+	require.True(t, gopt.ExtractJson[bool](m, "bool", Default[bool]()...).OrElse(false))
+	require.Equal(t, 2023, gopt.ExtractJson[time.Time](m, "time", Default[time.Time]()...).OrElse(time.Time{}).Year())
+	require.Equal(t, time.Minute, gopt.ExtractJson[time.Duration](m, "duration", Default[time.Duration]()...).OrElse(0))
+	require.Equal(t, []byte("hi"), gopt.ExtractJson[[]byte](m, "bytes", Default[[]byte]()...).OrElse(nil))
+	require.Equal(t, "123456789012345678901234567890", gopt.ExtractJson[*big.Int](m, "big", Default[*big.Int]()...).OrElse(nil).String())
+	require.Equal(t, []int{1, 2, 3}, gopt.ExtractJson[[]int](m, "ints", Default[[]int]()...).OrElse(nil))
+	require.Equal(t, []int64{1, 2, 3}, gopt.ExtractJson[[]int64](m, "ints", Default[[]int64]()...).OrElse(nil))
+	require.Equal(t, []float64{1, 2, 3}, gopt.ExtractJson[[]float64](m, "ints", Default[[]float64]()...).OrElse(nil))
+	require.Equal(t, []string{"a", "b"}, gopt.ExtractJson[[]string](m, "strs", Default[[]string]()...).OrElse(nil))
+	o, _ := gopt.ExtractJsonPath[int](m, "ints[1]", Default[int]()...)
+	require.Equal(t, 2, o.OrElse(-1))
+
+	require.Equal(t, 2, len(Default[int8]()))
+	require.Equal(t, 2, len(Default[int16]()))
+	require.Equal(t, 2, len(Default[int32]()))
+	require.Equal(t, 2, len(Default[int64]()))
+	require.Equal(t, 2, len(Default[uint]()))
+	require.Equal(t, 2, len(Default[uint16]()))
+	require.Equal(t, 2, len(Default[uint32]()))
+	require.Equal(t, 2, len(Default[uint64]()))
+	require.Equal(t, 1, len(Default[[]bool]()))
+	require.Nil(t, Default[string]())
+	require.Nil(t, Default[struct{}]())
+}
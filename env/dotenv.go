@@ -0,0 +1,112 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DotEnvError is the error returned by ReadDotEnv and ReadDotEnvFile when a line is invalid
+type DotEnvError struct {
+	// Line is the (1 based) line number
+	Line int
+	// Message describes the error
+	Message string
+}
+
+// Error implements error
+func (e *DotEnvError) Error() string {
+	return fmt.Sprintf("invalid .env line %d: %s", e.Line, e.Message)
+}
+
+// ReadDotEnvFile reads environment variables from a .env file (see ReadDotEnv)
+func ReadDotEnvFile(path string) (Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return ReadDotEnv(f)
+}
+
+// ReadDotEnv reads environment variables in .env format
+//
+// Each line is of the form NAME=VALUE (optionally preceded by "export") - blank lines and lines starting with "#" are ignored.
+// Values may be double-quoted (where \n, \r, \t, \" and \\ escapes are unescaped), single-quoted (literal) or unquoted
+// (where any trailing " #" comment is removed and the value is trimmed)
+func ReadDotEnv(r io.Reader) (Map, error) {
+	result := Map{}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		eq := strings.IndexByte(line, '=')
+		if eq == -1 {
+			return nil, &DotEnvError{Line: lineNo, Message: "expected NAME=VALUE"}
+		}
+		name := strings.TrimSpace(line[:eq])
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return nil, &DotEnvError{Line: lineNo, Message: fmt.Sprintf("invalid name %q", name)}
+		}
+		value, err := parseDotEnvValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, &DotEnvError{Line: lineNo, Message: err.Error()}
+		}
+		result[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseDotEnvValue(s string) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+	switch s[0] {
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(s); i++ {
+			c := s[i]
+			if c == '"' {
+				return sb.String(), checkDotEnvTrailing(s[i+1:])
+			} else if c == '\\' && i+1 < len(s) {
+				i++
+				switch c = s[i]; c {
+				case 'n':
+					c = '\n'
+				case 'r':
+					c = '\r'
+				case 't':
+					c = '\t'
+				}
+			}
+			sb.WriteByte(c)
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	case '\'':
+		if end := strings.IndexByte(s[1:], '\''); end != -1 {
+			return s[1 : end+1], checkDotEnvTrailing(s[end+2:])
+		}
+		return "", fmt.Errorf("unterminated single-quoted value")
+	}
+	if ci := strings.Index(s, " #"); ci != -1 {
+		s = strings.TrimSpace(s[:ci])
+	}
+	return s, nil
+}
+
+func checkDotEnvTrailing(s string) error {
+	if s = strings.TrimSpace(s); s != "" && s[0] != '#' {
+		return fmt.Errorf("unexpected characters after quoted value")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package env
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadDotEnv(t *testing.T) {
+	m, err := ReadDotEnv(strings.NewReader(`
+# comment
+FOO=bar
+export EXPORTED=yes
+SPACED = value with spaces  
+COMMENTED=value # comment
+HASH=a#b
+EMPTY=
+DQ="line1\nline2 \"quoted\" \\ # not a comment" # comment
+SQ='literal \n # not a comment'
+`))
+	require.NoError(t, err)
+	require.Equal(t, Map{
+		"FOO":       "bar",
+		"EXPORTED":  "yes",
+		"SPACED":    "value with spaces",
+		"COMMENTED": "value",
+		"HASH":      "a#b",
+		"EMPTY":     "",
+		"DQ":        "line1\nline2 \"quoted\" \\ # not a comment",
+		"SQ":        `literal \n # not a comment`,
+	}, m)
+	empty := LookupIn[string](m, "EMPTY")
+	require.True(t, empty.WasSet())
+	require.False(t, empty.IsPresent())
+}
+
+func TestReadDotEnv_Errors(t *testing.T) {
+	testCases := []struct {
+		content string
+		line    int
+	}{
+		{"FOO", 1},
+		{"\n=bar", 2},
+		{"A B=c", 1},
+		{`FOO="bar`, 1},
+		{`FOO='bar`, 1},
+		{`FOO="bar" baz`, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.content, func(t *testing.T) {
+			_, err := ReadDotEnv(strings.NewReader(tc.content))
+			require.Error(t, err)
+			var dErr *DotEnvError
+			require.True(t, errors.As(err, &dErr))
+			require.Equal(t, tc.line, dErr.Line)
+		})
+	}
+}
+
+func TestReadDotEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(path, []byte("FOO=bar\n"), 0o600)
+	require.NoError(t, err)
+	m, err := ReadDotEnvFile(path)
+	require.NoError(t, err)
+	require.Equal(t, Map{"FOO": "bar"}, m)
+
+	_, err = ReadDotEnvFile(filepath.Join(t.TempDir(), "missing.env"))
+	require.Error(t, err)
+	require.True(t, os.IsNotExist(err))
+}
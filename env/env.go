@@ -0,0 +1,368 @@
+// Package env - environment variable lookup and struct loading using gopt.Optional
+/*
+Unlike os.Getenv, the functions in this package distinguish between an environment variable that is not defined
+(the optional is unset) and one that is defined but empty (the optional is set but not present) - e.g.
+	port := env.Lookup[int]("PORT")
+	if !port.WasSet() {
+		// PORT not defined
+	} else if !port.IsPresent() {
+		// PORT defined but empty (or not a valid int)
+	}
+*/
+package env
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/go-andiamo/gopt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceEnv is the source (see gopt.Source) recorded for optionals set by Lookup, LookupIn and Load
+var SourceEnv = gopt.NewSource("env")
+
+// InvalidTarget is the error returned by Load when the destination is not a non-nil pointer to a struct
+var InvalidTarget = errors.New("load target must be a non-nil pointer to a struct")
+
+// Environment is the interface for a source of environment variables
+type Environment interface {
+	// LookupEnv returns the value of the named environment variable and whether it is defined
+	LookupEnv(name string) (string, bool)
+}
+
+// EnvironmentFunc is an adapter to allow the use of a func as an Environment
+type EnvironmentFunc func(name string) (string, bool)
+
+// LookupEnv implements Environment
+func (f EnvironmentFunc) LookupEnv(name string) (string, bool) {
+	return f(name)
+}
+
+// OS is the Environment of the process's environment variables (see os.LookupEnv)
+var OS Environment = EnvironmentFunc(os.LookupEnv)
+
+// Map is an Environment of environment variables held in a map (e.g. for tests, or as read from a .env file by ReadDotEnv)
+type Map map[string]string
+
+// LookupEnv implements Environment
+func (m Map) LookupEnv(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// Chain returns an Environment that looks up each environment variable in the supplied environments in turn - the
+// first environment in which the variable is defined is used
+//
+// For example, to use the process environment variables with defaults from a .env file:
+//   dotEnv, err := env.ReadDotEnvFile(".env")
+//   ...
+//   err = env.Load(&cfg, env.WithEnvironment(env.Chain(env.OS, dotEnv)))
+func Chain(environments ...Environment) Environment {
+	return EnvironmentFunc(func(name string) (string, bool) {
+		for _, e := range environments {
+			if v, ok := e.LookupEnv(name); ok {
+				return v, true
+			}
+		}
+		return "", false
+	})
+}
+
+// Lookup looks up an environment variable (in the process environment) and returns an optional of the specified type
+//
+// If the environment variable is not defined, an empty (unset) optional is returned.  If the environment variable is
+// defined but empty, an optional that is set but not present is returned.  Otherwise, the value is converted to the
+// specified type and the optional is set (with source SourceEnv) and present - if the value cannot be converted, an
+// optional that is set but not present is returned
+//
+// Converters are passed the value (as a string).  If no converter converts the value, the value is parsed by the
+// built-in parsing...
+//   string - the value
+//   bool - as accepted by strconv.ParseBool (e.g. "true", "1", "false", "0")
+//   integers and floats - as accepted by strconv
+//   time.Duration - as accepted by time.ParseDuration (e.g. "1h30m")
+//   encoding.TextUnmarshaler implementations (e.g. time.Time, as RFC 3339)
+//   slices (other than []byte) - comma-separated values, each parsed as above
+func Lookup[T any](name string, converters ...gopt.Converter[T]) *gopt.Optional[T] {
+	return LookupIn[T](OS, name, converters...)
+}
+
+// LookupIn is the same as Lookup, except that the environment variable is looked up in the supplied Environment
+func LookupIn[T any](environment Environment, name string, converters ...gopt.Converter[T]) *gopt.Optional[T] {
+	result := gopt.Empty[T]()
+	s, ok := environment.LookupEnv(name)
+	if !ok {
+		return result
+	} else if s == "" {
+		return result.ClearFrom(SourceEnv)
+	}
+	for _, c := range converters {
+		if v, ok := c(s); ok {
+			return result.SetFrom(SourceEnv, v)
+		}
+	}
+	var v T
+	if err := parseValue(s, reflect.ValueOf(&v).Elem()); err == nil {
+		return result.SetFrom(SourceEnv, v)
+	}
+	return result.ClearFrom(SourceEnv)
+}
+
+// Option is an option that can be passed to Load
+type Option func(opts *options)
+
+type options struct {
+	environment Environment
+	prefix      string
+}
+
+// WithEnvironment is an Option that sets the Environment used by Load (the default is OS)
+func WithEnvironment(environment Environment) Option {
+	return func(opts *options) {
+		opts.environment = environment
+	}
+}
+
+// WithPrefix is an Option that sets the prefix used by Load for all environment variable names
+func WithPrefix(prefix string) Option {
+	return func(opts *options) {
+		opts.prefix = prefix
+	}
+}
+
+// LoadError is the (aggregated) error returned by Load when one or more fields could not be loaded
+type LoadError struct {
+	// Errors is the errors for each field that could not be loaded
+	Errors []*FieldError
+}
+
+// Error implements error
+func (e *LoadError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("load failed (%d field errors): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// FieldError is the error for a single field that could not be loaded (see LoadError)
+type FieldError struct {
+	// Field is the name of the field (nested struct fields are dot separated, e.g. "Outer.Inner")
+	Field string
+	// Name is the name of the environment variable
+	Name string
+	// Message describes the error
+	Message string
+}
+
+// Error implements error
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %q (env %q): %s", e.Field, e.Name, e.Message)
+}
+
+// Load loads environment variables into the fields of the struct pointed to by dst
+//
+// Fields are loaded from the environment variable named by the field's `env` tag (fields without an `env` tag are
+// not loaded) - for example:
+//   type Config struct {
+//     Port     gopt.Optional[int]     `env:"PORT"`
+//     Debug    bool                   `env:"DEBUG"`
+//     Timeout  *gopt.Optional[time.Duration] `env:"TIMEOUT"`
+//     Database struct {
+//       Host gopt.Optional[string] `env:"HOST"`
+//     } `envPrefix:"DB_"`
+//   }
+// Nested structs (and pointers to structs) are loaded with the prefix from their `envPrefix` tag (added to any prefix
+// supplied using the WithPrefix option)
+//
+// Nil pointer fields (to structs or optionals) are only allocated if a value is loaded into them
+//
+// Optional[T] (and *Optional[T]) fields are set if the environment variable is defined (they are not present if it is
+// empty) and the source is recorded as SourceEnv.  Other fields are only set if the environment variable is defined
+// and non-empty.  Values are parsed as described by Lookup - if a value cannot be parsed, the field is left unchanged
+// and a *LoadError listing each field that could not be loaded is returned
+func Load(dst any, opts ...Option) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return InvalidTarget
+	}
+	o := &options{environment: OS}
+	for _, opt := range opts {
+		opt(o)
+	}
+	l := &loader{environment: o.environment}
+	l.loadStruct(rv.Elem(), "", o.prefix)
+	if len(l.errors) > 0 {
+		return &LoadError{Errors: l.errors}
+	}
+	return nil
+}
+
+type loader struct {
+	environment Environment
+	errors      []*FieldError
+}
+
+// loadStruct loads the fields of the struct - returns true if any field was loaded
+func (l *loader) loadStruct(rv reflect.Value, fieldPrefix string, prefix string) bool {
+	loaded := false
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		field := fieldPrefix + sf.Name
+		if name, ok := sf.Tag.Lookup("env"); ok && name != "" && name != "-" {
+			loaded = l.loadField(fv, field, prefix+name) || loaded
+		} else if !ok && isStructType(sf.Type) {
+			nestedPrefix := prefix + sf.Tag.Get("envPrefix")
+			if sf.Type.Kind() != reflect.Ptr {
+				loaded = l.loadStruct(fv, field+".", nestedPrefix) || loaded
+			} else if !fv.IsNil() {
+				loaded = l.loadStruct(fv.Elem(), field+".", nestedPrefix) || loaded
+			} else if nv := reflect.New(sf.Type.Elem()); l.loadStruct(nv.Elem(), field+".", nestedPrefix) {
+				// nil struct pointers are only allocated if a nested field was loaded...
+				fv.Set(nv)
+				loaded = true
+			}
+		}
+	}
+	return loaded
+}
+
+// loadField loads the field from the environment variable - returns true if the field was loaded
+func (l *loader) loadField(fv reflect.Value, field string, name string) bool {
+	s, ok := l.environment.LookupEnv(name)
+	if !ok {
+		return false
+	}
+	ft := fv.Type()
+	if isOptionalType(ft) {
+		return l.loadOptional(fv.Addr().Interface().(settable), s, field, name)
+	} else if ft.Kind() == reflect.Ptr && isOptionalType(ft.Elem()) {
+		if !fv.IsNil() {
+			return l.loadOptional(fv.Interface().(settable), s, field, name)
+		} else if nv := reflect.New(ft.Elem()); l.loadOptional(nv.Interface().(settable), s, field, name) {
+			fv.Set(nv)
+			return true
+		}
+	} else if s != "" {
+		v := reflect.New(ft).Elem()
+		if err := parseValue(s, v); err != nil {
+			l.fail(field, name, err.Error())
+		} else {
+			fv.Set(v)
+			return true
+		}
+	}
+	return false
+}
+
+func (l *loader) loadOptional(o settable, s string, field string, name string) bool {
+	if s == "" {
+		_ = o.SetAnyFrom(SourceEnv, nil)
+		return true
+	}
+	v := reflect.New(o.ValueType()).Elem()
+	if err := parseValue(s, v); err != nil {
+		l.fail(field, name, err.Error())
+		return false
+	}
+	_ = o.SetAnyFrom(SourceEnv, v.Interface())
+	return true
+}
+
+func (l *loader) fail(field string, name string, msg string) {
+	l.errors = append(l.errors, &FieldError{
+		Field:   field,
+		Name:    name,
+		Message: msg,
+	})
+}
+
+// settable is implemented by *gopt.Optional[T] - allowing optional fields of any type to be loaded
+type settable interface {
+	ValueType() reflect.Type
+	SetAnyFrom(source gopt.Source, v any) error
+}
+
+var (
+	settableType        = reflect.TypeOf((*settable)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isOptionalType determines whether the type is a gopt.Optional[T] (i.e. a struct whose pointer is settable)
+func isOptionalType(rt reflect.Type) bool {
+	return rt.Kind() == reflect.Struct && reflect.PtrTo(rt).Implements(settableType)
+}
+
+func isStructType(rt reflect.Type) bool {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt.Kind() == reflect.Struct && !isOptionalType(rt)
+}
+
+func parseValue(s string, v reflect.Value) error {
+	if reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	} else if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			v.SetInt(int64(d))
+		}
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s))
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		sv := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := parseValue(strings.TrimSpace(part), sv.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
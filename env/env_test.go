@@ -0,0 +1,240 @@
+package env
+
+import (
+	"errors"
+	"github.com/go-andiamo/gopt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	t.Setenv("GOPT_TEST_PORT", "8080")
+	t.Setenv("GOPT_TEST_EMPTY", "")
+	_ = os.Unsetenv("GOPT_TEST_MISSING")
+
+	port := Lookup[int]("GOPT_TEST_PORT")
+	require.True(t, port.WasSet())
+	require.True(t, port.IsPresent())
+	require.Equal(t, 8080, port.OrElse(0))
+	require.Equal(t, SourceEnv, port.Source())
+	require.Equal(t, "env", port.Source().String())
+
+	empty := Lookup[string]("GOPT_TEST_EMPTY")
+	require.True(t, empty.WasSet())
+	require.False(t, empty.IsPresent())
+	require.Equal(t, SourceEnv, empty.Source())
+
+	missing := Lookup[string]("GOPT_TEST_MISSING")
+	require.False(t, missing.WasSet())
+	require.False(t, missing.IsPresent())
+}
+
+func TestLookupIn(t *testing.T) {
+	e := Map{
+		"STR":      "abc",
+		"BOOL":     "true",
+		"INT8":     "127",
+		"BIG":      "128",
+		"UINT":     "42",
+		"FLOAT":    "1.5",
+		"DURATION": "1m30s",
+		"TIME":     "2023-01-02T03:04:05Z",
+		"LIST":     "a, b,c",
+		"INTS":     "1,2,3",
+		"BAD_INTS": "1,x",
+		"BYTES":    "abc",
+	}
+	require.Equal(t, "abc", LookupIn[string](e, "STR").OrElse(""))
+	require.True(t, LookupIn[bool](e, "BOOL").OrElse(false))
+	require.Equal(t, int8(127), LookupIn[int8](e, "INT8").OrElse(0))
+	big := LookupIn[int8](e, "BIG")
+	require.True(t, big.WasSet())
+	require.False(t, big.IsPresent())
+	require.Equal(t, uint(42), LookupIn[uint](e, "UINT").OrElse(0))
+	require.Equal(t, 1.5, LookupIn[float64](e, "FLOAT").OrElse(0))
+	require.Equal(t, 90*time.Second, LookupIn[time.Duration](e, "DURATION").OrElse(0))
+	require.Equal(t, time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), LookupIn[time.Time](e, "TIME").OrElse(time.Time{}))
+	require.Equal(t, []string{"a", "b", "c"}, LookupIn[[]string](e, "LIST").OrElse(nil))
+	require.Equal(t, []int{1, 2, 3}, LookupIn[[]int](e, "INTS").OrElse(nil))
+	require.False(t, LookupIn[[]int](e, "BAD_INTS").IsPresent())
+	require.Equal(t, []byte("abc"), LookupIn[[]byte](e, "BYTES").OrElse(nil))
+	require.False(t, LookupIn[map[string]any](e, "STR").IsPresent())
+
+	type custom struct{ v int }
+	c := LookupIn[custom](e, "INTS", func(value any) (custom, bool) {
+		return custom{v: len(value.(string))}, true
+	})
+	require.Equal(t, 5, c.OrElse(custom{}).v)
+}
+
+func TestChain(t *testing.T) {
+	e := Chain(Map{"A": "1"}, Map{"A": "2", "B": "3"})
+	require.Equal(t, 1, LookupIn[int](e, "A").OrElse(0))
+	require.Equal(t, 3, LookupIn[int](e, "B").OrElse(0))
+	require.False(t, LookupIn[int](e, "C").WasSet())
+}
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("invalid level")
+	}
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	type database struct {
+		Host gopt.Optional[string] `env:"HOST"`
+		Port gopt.Optional[int]    `env:"PORT"`
+	}
+	type config struct {
+		Port     gopt.Optional[int]            `env:"PORT"`
+		Debug    bool                          `env:"DEBUG"`
+		Name     string                        `env:"NAME"`
+		Timeout  *gopt.Optional[time.Duration] `env:"TIMEOUT"`
+		Missing  *gopt.Optional[string]        `env:"MISSING"`
+		Empty    gopt.Optional[string]         `env:"EMPTY"`
+		Level    gopt.Optional[level]          `env:"LEVEL"`
+		Tags     []string                      `env:"TAGS"`
+		Untagged string
+		Ignored  string    `env:"-"`
+		Database database  `envPrefix:"DB_"`
+		Replica  *database `envPrefix:"REPLICA_DB_"`
+		Nested   struct {
+			Value gopt.Optional[string] `env:"VALUE"`
+		}
+	}
+	e := Map{
+		"APP_PORT":            "8080",
+		"APP_DEBUG":           "true",
+		"APP_NAME":            "",
+		"APP_TIMEOUT":         "5s",
+		"APP_EMPTY":           "",
+		"APP_LEVEL":           "high",
+		"APP_TAGS":            "a,b",
+		"APP_Untagged":        "x",
+		"APP_DB_HOST":         "localhost",
+		"APP_DB_PORT":         "5432",
+		"APP_REPLICA_DB_HOST": "replica",
+		"APP_VALUE":           "v",
+	}
+	cfg := &config{Name: "default"}
+	err := Load(cfg, WithEnvironment(e), WithPrefix("APP_"))
+	require.NoError(t, err)
+	require.Equal(t, 8080, cfg.Port.OrElse(0))
+	require.Equal(t, SourceEnv, cfg.Port.Source())
+	require.True(t, cfg.Debug)
+	require.Equal(t, "default", cfg.Name)
+	require.Equal(t, 5*time.Second, cfg.Timeout.OrElse(0))
+	require.Nil(t, cfg.Missing)
+	require.True(t, cfg.Empty.WasSet())
+	require.False(t, cfg.Empty.IsPresent())
+	require.Equal(t, level(2), cfg.Level.OrElse(0))
+	require.Equal(t, []string{"a", "b"}, cfg.Tags)
+	require.Equal(t, "", cfg.Untagged)
+	require.Equal(t, "localhost", cfg.Database.Host.OrElse(""))
+	require.Equal(t, 5432, cfg.Database.Port.OrElse(0))
+	require.NotNil(t, cfg.Replica)
+	require.Equal(t, "replica", cfg.Replica.Host.OrElse(""))
+	require.False(t, cfg.Replica.Port.WasSet())
+	require.Equal(t, "v", cfg.Nested.Value.OrElse(""))
+}
+
+func TestLoad_Errors(t *testing.T) {
+	type config struct {
+		Port   gopt.Optional[int]   `env:"PORT"`
+		Debug  bool                 `env:"DEBUG"`
+		Level  gopt.Optional[level] `env:"LEVEL"`
+		Map    map[string]string    `env:"MAP"`
+		Ok     gopt.Optional[int]   `env:"OK"`
+		Nested struct {
+			Port int `env:"PORT"`
+		} `envPrefix:"NESTED_"`
+	}
+	e := Map{
+		"PORT":        "x",
+		"DEBUG":       "maybe",
+		"LEVEL":       "medium",
+		"MAP":         "a=b",
+		"OK":          "1",
+		"NESTED_PORT": "99999999999999999999",
+	}
+	cfg := &config{}
+	err := Load(cfg, WithEnvironment(e))
+	require.Error(t, err)
+	var lErr *LoadError
+	require.True(t, errors.As(err, &lErr))
+	require.Equal(t, 5, len(lErr.Errors))
+	require.Equal(t, "Port", lErr.Errors[0].Field)
+	require.Equal(t, "PORT", lErr.Errors[0].Name)
+	require.Equal(t, "Debug", lErr.Errors[1].Field)
+	require.Equal(t, "Level", lErr.Errors[2].Field)
+	require.Equal(t, "invalid level", lErr.Errors[2].Message)
+	require.Equal(t, "Map", lErr.Errors[3].Field)
+	require.Equal(t, "unsupported type map[string]string", lErr.Errors[3].Message)
+	require.Equal(t, "Nested.Port", lErr.Errors[4].Field)
+	require.Equal(t, "NESTED_PORT", lErr.Errors[4].Name)
+	require.False(t, cfg.Port.WasSet())
+	require.Equal(t, 1, cfg.Ok.OrElse(0))
+	require.Equal(t, `field "Level" (env "LEVEL"): invalid level`, lErr.Errors[2].Error())
+	require.Contains(t, err.Error(), "load failed (5 field errors): ")
+}
+
+func TestLoad_NilPointers(t *testing.T) {
+	type database struct {
+		Host gopt.Optional[string] `env:"HOST"`
+	}
+	type config struct {
+		Primary *database              `envPrefix:"PRIMARY_"`
+		Replica *database              `envPrefix:"REPLICA_"`
+		Backup  *database              `envPrefix:"BACKUP_"`
+		Port    *gopt.Optional[int]    `env:"PORT"`
+		Name    *gopt.Optional[string] `env:"NAME"`
+	}
+	e := Map{
+		"PRIMARY_HOST": "primary",
+		"BACKUP_HOST":  "",
+		"PORT":         "x",
+	}
+	cfg := &config{}
+	err := Load(cfg, WithEnvironment(e))
+	require.Error(t, err)
+	require.NotNil(t, cfg.Primary)
+	require.Equal(t, "primary", cfg.Primary.Host.OrElse(""))
+	// nil pointers are not allocated when nothing is loaded into them...
+	require.Nil(t, cfg.Replica)
+	require.Nil(t, cfg.Port)
+	require.Nil(t, cfg.Name)
+	// but an empty (defined) variable is loaded...
+	require.NotNil(t, cfg.Backup)
+	require.True(t, cfg.Backup.Host.WasSet())
+	require.False(t, cfg.Backup.Host.IsPresent())
+}
+
+func TestLoad_DefaultEnvironment(t *testing.T) {
+	t.Setenv("GOPT_TEST_LOAD", strconv.Itoa(42))
+	type config struct {
+		Value gopt.Optional[int] `env:"GOPT_TEST_LOAD"`
+	}
+	cfg := &config{}
+	err := Load(cfg)
+	require.NoError(t, err)
+	require.Equal(t, 42, cfg.Value.OrElse(0))
+}
+
+func TestLoad_InvalidTarget(t *testing.T) {
+	type config struct{}
+	require.Equal(t, InvalidTarget, Load(config{}))
+	require.Equal(t, InvalidTarget, Load((*config)(nil)))
+	s := ""
+	require.Equal(t, InvalidTarget, Load(&s))
+}
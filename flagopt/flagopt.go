@@ -0,0 +1,170 @@
+// Package flagopt - command-line flags (using the standard flag package) as gopt.Optional
+/*
+Unlike the standard flag functions, an optional flag distinguishes between a flag that was not given (the optional
+is unset) and one that was given (the optional is set) - even if it was given the zero value, e.g.
+	retries := flagopt.Var[int](nil, "retries", "number of retries")
+	flag.Parse()
+	if retries.WasSet() {
+		// -retries was given (even if -retries=0)
+	}
+*/
+package flagopt
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-andiamo/gopt"
+	"github.com/go-andiamo/gopt/convert"
+	"reflect"
+)
+
+// SourceFlag is the source (see gopt.Source) recorded for optionals set by flags
+var SourceFlag = gopt.NewSource("flag")
+
+// Var defines an optional flag with the specified name and usage string, returning the optional that is set when the flag is given
+//
+// If the supplied flag set is nil, flag.CommandLine is used
+//
+// When the flag is given, the optional is set (with source SourceFlag) - if the flag is given an empty value (e.g. -name=),
+// the optional is set but not present.  Flag values are converted using the supplied converters (which are passed the
+// value as a string) or, if none converts the value, by the converters from convert.Default (string values are used as-is)
+//
+// If the type T is bool, the flag is a boolean flag (i.e. -name is equivalent to -name=true)
+func Var[T any](fs *flag.FlagSet, name string, usage string, converters ...gopt.Converter[T]) *gopt.Optional[T] {
+	o := gopt.Empty[T]()
+	Bind[T](fs, o, name, usage, converters...)
+	return o
+}
+
+// Bind is the same as Var, except that the flag sets the supplied optional (e.g. a field of a config struct)
+func Bind[T any](fs *flag.FlagSet, o *gopt.Optional[T], name string, usage string, converters ...gopt.Converter[T]) {
+	flagSet(fs).Var(&Value[T]{
+		Optional:   o,
+		Converters: converters,
+	}, name, usage)
+}
+
+// SliceVar defines a repeatable optional flag with the specified name and usage string, returning the optional that is
+// set when the flag is given
+//
+// Each time the flag is given, the converted value is appended to the optional's slice value - values are converted as
+// described by Var.  An existing value not set by a flag (e.g. a default) is replaced (as with the standard flag idiom)
+// rather than appended to
+func SliceVar[T any](fs *flag.FlagSet, name string, usage string, converters ...gopt.Converter[T]) *gopt.Optional[[]T] {
+	o := gopt.Empty[[]T]()
+	BindSlice[T](fs, o, name, usage, converters...)
+	return o
+}
+
+// BindSlice is the same as SliceVar, except that the flag sets the supplied optional (e.g. a field of a config struct)
+func BindSlice[T any](fs *flag.FlagSet, o *gopt.Optional[[]T], name string, usage string, converters ...gopt.Converter[T]) {
+	flagSet(fs).Var(&SliceValue[T]{
+		Optional:   o,
+		Converters: converters,
+	}, name, usage)
+}
+
+// Value is a flag.Value (and flag.Getter) that sets an optional
+type Value[T any] struct {
+	// Optional is the optional that is set when the flag is given
+	Optional *gopt.Optional[T]
+	// Converters is the converters used to convert the flag value (see Var)
+	Converters []gopt.Converter[T]
+}
+
+// String implements flag.Value
+func (v *Value[T]) String() string {
+	if v == nil || !v.Optional.IsPresent() {
+		return ""
+	}
+	return fmt.Sprint(v.Optional.OrElse(*new(T)))
+}
+
+// Set implements flag.Value
+func (v *Value[T]) Set(s string) error {
+	if s == "" {
+		v.Optional.ClearFrom(SourceFlag)
+		return nil
+	}
+	cv, err := parseValue[T](s, v.Converters)
+	if err == nil {
+		v.Optional.SetFrom(SourceFlag, cv)
+	}
+	return err
+}
+
+// Get implements flag.Getter
+func (v *Value[T]) Get() any {
+	return v.Optional.OrElse(*new(T))
+}
+
+// IsBoolFlag indicates to the flag package whether the flag is a boolean flag (i.e. whether T is bool)
+func (v *Value[T]) IsBoolFlag() bool {
+	return reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Bool
+}
+
+// SliceValue is a flag.Value (and flag.Getter) that appends to the slice value of an optional each time the flag is given
+type SliceValue[T any] struct {
+	// Optional is the optional that is set when the flag is given
+	Optional *gopt.Optional[[]T]
+	// Converters is the converters used to convert each flag value (see Var)
+	Converters []gopt.Converter[T]
+}
+
+// String implements flag.Value
+func (v *SliceValue[T]) String() string {
+	if v == nil || !v.Optional.IsPresent() {
+		return ""
+	}
+	return fmt.Sprint(v.Optional.OrElse(nil))
+}
+
+// Set implements flag.Value
+//
+// The first time the flag is given, any existing value not set by a flag (e.g. a default) is replaced rather than appended to
+func (v *SliceValue[T]) Set(s string) error {
+	cv, err := parseValue[T](s, v.Converters)
+	if err == nil {
+		var current []T
+		if v.Optional.Source() == SourceFlag {
+			current = v.Optional.OrElse(nil)
+		}
+		v.Optional.SetFrom(SourceFlag, append(current, cv))
+	}
+	return err
+}
+
+// Get implements flag.Getter
+func (v *SliceValue[T]) Get() any {
+	return v.Optional.OrElse(nil)
+}
+
+// IsBoolFlag indicates to the flag package whether the flag is a boolean flag (i.e. whether T is bool)
+func (v *SliceValue[T]) IsBoolFlag() bool {
+	return reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Bool
+}
+
+func flagSet(fs *flag.FlagSet) *flag.FlagSet {
+	if fs == nil {
+		return flag.CommandLine
+	}
+	return fs
+}
+
+func parseValue[T any](s string, converters []gopt.Converter[T]) (T, error) {
+	if v, ok := any(s).(T); ok {
+		return v, nil
+	}
+	for _, c := range converters {
+		if v, ok := c(s); ok {
+			return v, nil
+		}
+	}
+	for _, c := range convert.Default[T]() {
+		if v, ok := c(s); ok {
+			return v, nil
+		}
+	}
+	var zero T
+	return zero, fmt.Errorf("cannot parse %q as %T", s, zero)
+}
@@ -0,0 +1,178 @@
+package flagopt
+
+import (
+	"bytes"
+	"flag"
+	"github.com/go-andiamo/gopt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVar(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	retries := Var[int](fs, "retries", "number of retries")
+	name := Var[string](fs, "name", "name")
+	timeout := Var[time.Duration](fs, "timeout", "timeout")
+	verbose := Var[bool](fs, "v", "verbose")
+	quiet := Var[bool](fs, "q", "quiet")
+	empty := Var[string](fs, "empty", "empty")
+	missing := Var[int](fs, "missing", "missing")
+
+	err := fs.Parse([]string{"-retries=0", "-name", "foo", "-timeout=5s", "-v", "-q=false", "-empty="})
+	require.NoError(t, err)
+
+	require.True(t, retries.WasSet())
+	require.True(t, retries.IsPresent())
+	require.Equal(t, 0, retries.OrElse(-1))
+	require.Equal(t, SourceFlag, retries.Source())
+	require.Equal(t, "foo", name.OrElse(""))
+	require.Equal(t, 5*time.Second, timeout.OrElse(0))
+	require.True(t, verbose.OrElse(false))
+	require.True(t, quiet.WasSet())
+	require.False(t, quiet.OrElse(true))
+	require.True(t, empty.WasSet())
+	require.False(t, empty.IsPresent())
+	require.False(t, missing.WasSet())
+	require.False(t, missing.IsPresent())
+}
+
+func TestVar_InvalidValue(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	retries := Var[int](fs, "retries", "number of retries")
+	err := fs.Parse([]string{"-retries=x"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `cannot parse "x" as int`)
+	require.False(t, retries.WasSet())
+}
+
+func TestVar_Converters(t *testing.T) {
+	type level int
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	lvl := Var[level](fs, "level", "level", func(value any) (level, bool) {
+		switch value.(string) {
+		case "low":
+			return 1, true
+		case "high":
+			return 2, true
+		}
+		return 0, false
+	})
+	err := fs.Parse([]string{"-level=high"})
+	require.NoError(t, err)
+	require.Equal(t, level(2), lvl.OrElse(0))
+}
+
+func TestVar_CommandLine(t *testing.T) {
+	o := Var[int](nil, "flagopt-test-commandline", "test")
+	require.NotNil(t, flag.CommandLine.Lookup("flagopt-test-commandline"))
+	require.False(t, o.WasSet())
+}
+
+func TestBind(t *testing.T) {
+	type config struct {
+		Port gopt.Optional[int]
+	}
+	cfg := &config{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	Bind[int](fs, &cfg.Port, "port", "port")
+	err := fs.Parse([]string{"-port", "8080"})
+	require.NoError(t, err)
+	require.Equal(t, 8080, cfg.Port.OrElse(0))
+}
+
+func TestSliceVar(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	tags := SliceVar[string](fs, "tag", "tags")
+	ports := SliceVar[int](fs, "port", "ports")
+	missing := SliceVar[int](fs, "missing", "missing")
+	err := fs.Parse([]string{"-tag=a", "-port=1", "-tag", "b", "-port=2"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, tags.OrElse(nil))
+	require.Equal(t, SourceFlag, tags.Source())
+	require.Equal(t, []int{1, 2}, ports.OrElse(nil))
+	require.False(t, missing.WasSet())
+
+	err = fs.Parse([]string{"-port=x"})
+	require.Error(t, err)
+	require.Equal(t, []int{1, 2}, ports.OrElse(nil))
+}
+
+func TestBindSlice(t *testing.T) {
+	var flags gopt.Optional[[]bool]
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindSlice[bool](fs, &flags, "f", "flags")
+	err := fs.Parse([]string{"-f", "-f=false", "-f"})
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false, true}, flags.OrElse(nil))
+}
+
+func TestBindSlice_ReplacesDefault(t *testing.T) {
+	type config struct {
+		Tags gopt.Optional[[]string]
+	}
+	defaults := []string{"x", "y"}
+	cfg := config{}
+	cfg.Tags.SetFrom(gopt.SourceDefault, defaults)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindSlice[string](fs, &cfg.Tags, "tag", "tags")
+	err := fs.Parse([]string{"-tag=a", "-tag=b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, cfg.Tags.OrElse(nil))
+	require.Equal(t, SourceFlag, cfg.Tags.Source())
+	require.Equal(t, []string{"x", "y"}, defaults)
+
+	// default is retained if the flag is not given...
+	cfg = config{}
+	cfg.Tags.SetFrom(gopt.SourceDefault, defaults)
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	BindSlice[string](fs, &cfg.Tags, "tag", "tags")
+	err = fs.Parse([]string{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "y"}, cfg.Tags.OrElse(nil))
+	require.Equal(t, gopt.SourceDefault, cfg.Tags.Source())
+}
+
+func TestValue(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	retries := Var[int](fs, "retries", "number of retries")
+	tags := SliceVar[string](fs, "tag", "tags")
+	rf := fs.Lookup("retries")
+	tf := fs.Lookup("tag")
+	require.Equal(t, "", rf.Value.String())
+	require.Equal(t, "", tf.Value.String())
+	require.Equal(t, 0, rf.Value.(flag.Getter).Get())
+	require.Nil(t, tf.Value.(flag.Getter).Get())
+	require.False(t, rf.Value.(interface{ IsBoolFlag() bool }).IsBoolFlag())
+	require.False(t, tf.Value.(interface{ IsBoolFlag() bool }).IsBoolFlag())
+
+	err := fs.Parse([]string{"-retries=3", "-tag=a", "-tag=b"})
+	require.NoError(t, err)
+	require.Equal(t, 3, retries.OrElse(0))
+	require.Equal(t, "3", rf.Value.String())
+	require.Equal(t, "[a b]", tf.Value.String())
+	require.Equal(t, 3, rf.Value.(flag.Getter).Get())
+	require.Equal(t, []string{"a", "b"}, tf.Value.(flag.Getter).Get())
+	require.Equal(t, []string{"a", "b"}, tags.OrElse(nil))
+
+	var nilValue *Value[int]
+	require.Equal(t, "", nilValue.String())
+	var nilSliceValue *SliceValue[int]
+	require.Equal(t, "", nilSliceValue.String())
+}
+
+func TestUsage(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	buf := &bytes.Buffer{}
+	fs.SetOutput(buf)
+	_ = Var[int](fs, "retries", "number of `n` retries")
+	_ = Var[bool](fs, "v", "verbose")
+	fs.PrintDefaults()
+	out := buf.String()
+	require.True(t, strings.Contains(out, "-retries n"), out)
+	require.True(t, strings.Contains(out, "-v\tverbose") || strings.Contains(out, "-v\n"), out)
+	require.False(t, strings.Contains(out, "default"), out)
+}
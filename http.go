@@ -0,0 +1,163 @@
+package gopt
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxMemory is the max memory used by ExtractForm when parsing multipart forms (the same as http.Request.FormValue)
+const defaultMaxMemory = 32 << 20
+
+// ExtractQuery extracts an optional value, of the specified type, from url.Values (e.g. a request's URL query)
+//
+// If the key is not present, an empty (unset) optional is returned.  If the key is present but the value is empty
+// (e.g. "?foo=" or "?foo"), an optional that is set but not present is returned.  Otherwise, the (first) value is
+// converted to the specified type and the optional is set (with source SourceHTTP) and present
+//
+// If the value cannot be converted (e.g. "?limit=abc" for an int), an empty (unset) optional is returned - as with
+// Extract, where a value that is not of the specified type (and is not converted) yields an empty optional.  So an
+// invalid value can be distinguished from an empty value (set but not present) and, by checking whether the key is in
+// the values, from a missing key
+//
+// As with Extract, values of the specified type are used directly - for string (the first value) and []string (all
+// values, with each value split as a comma-separated list, e.g. "?tag=a,b&tag=c" yields ["a" "b" "c"]).  For other
+// types, converters are passed the first value (as a string) and, if no converter converts the value, the value is
+// parsed by the built-in parsing for the specified type...
+//   int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64 - integers (parsed using strconv)
+//   float32, float64 - floating-point numbers (parsed using strconv)
+//   bool - booleans (as accepted by strconv.ParseBool - e.g. "true", "1", "false", "0")
+//   time.Time - HTTP dates (as accepted by http.ParseTime - e.g. "Mon, 02 Jan 2006 15:04:05 GMT")
+//   time.Duration - durations (as accepted by time.ParseDuration - e.g. "1h30m")
+func ExtractQuery[T any](values url.Values, key string, converters ...Converter[T]) *Optional[T] {
+	vs, ok := values[key]
+	return extractHttpValues[T](vs, ok, converters...)
+}
+
+// ExtractHeader extracts an optional value, of the specified type, from an http.Header
+//
+// The key is canonicalised (see http.CanonicalHeaderKey) and the value is converted as described by ExtractQuery
+func ExtractHeader[T any](header http.Header, key string, converters ...Converter[T]) *Optional[T] {
+	vs, ok := header[http.CanonicalHeaderKey(key)]
+	return extractHttpValues[T](vs, ok, converters...)
+}
+
+// ExtractForm extracts an optional value, of the specified type, from a request's form (including both the URL query
+// and the body of POST, PUT and PATCH requests)
+//
+// If the request's form has not already been parsed, it is parsed (as with http.Request.FormValue) and the value is
+// converted as described by ExtractQuery
+func ExtractForm[T any](r *http.Request, key string, converters ...Converter[T]) *Optional[T] {
+	if r.Form == nil {
+		_ = r.ParseMultipartForm(defaultMaxMemory)
+	}
+	vs, ok := r.Form[key]
+	return extractHttpValues[T](vs, ok, converters...)
+}
+
+func extractHttpValues[T any](values []string, ok bool, converters ...Converter[T]) *Optional[T] {
+	result := Empty[T]()
+	if !ok {
+		return result
+	}
+	first := ""
+	if len(values) > 0 {
+		first = values[0]
+	}
+	var v T
+	switch vt := any(&v).(type) {
+	case *string:
+		if *vt = first; first != "" {
+			result.setValue(v, SourceHTTP)
+		} else {
+			result.clear(SourceHTTP)
+		}
+		return result
+	case *[]string:
+		if *vt = splitHttpList(values); len(*vt) > 0 {
+			result.setValue(v, SourceHTTP)
+		} else {
+			result.clear(SourceHTTP)
+		}
+		return result
+	}
+	if first == "" {
+		result.clear(SourceHTTP)
+	} else if cv, ok := runConverters[T](first, converters...); ok {
+		result.setValue(cv, SourceHTTP)
+	} else if parseHttpValue(first, &v) {
+		result.setValue(v, SourceHTTP)
+	}
+	return result
+}
+
+func splitHttpList(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
+
+func parseHttpValue(s string, v any) (ok bool) {
+	var err error
+	switch vt := v.(type) {
+	case *int:
+		*vt, err = strconv.Atoi(s)
+	case *int8:
+		err = parseHttpInt(s, 8, func(i int64) { *vt = int8(i) })
+	case *int16:
+		err = parseHttpInt(s, 16, func(i int64) { *vt = int16(i) })
+	case *int32:
+		err = parseHttpInt(s, 32, func(i int64) { *vt = int32(i) })
+	case *int64:
+		*vt, err = strconv.ParseInt(s, 10, 64)
+	case *uint:
+		err = parseHttpUint(s, strconv.IntSize, func(u uint64) { *vt = uint(u) })
+	case *uint8:
+		err = parseHttpUint(s, 8, func(u uint64) { *vt = uint8(u) })
+	case *uint16:
+		err = parseHttpUint(s, 16, func(u uint64) { *vt = uint16(u) })
+	case *uint32:
+		err = parseHttpUint(s, 32, func(u uint64) { *vt = uint32(u) })
+	case *uint64:
+		*vt, err = strconv.ParseUint(s, 10, 64)
+	case *float32:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		*vt = float32(f)
+	case *float64:
+		*vt, err = strconv.ParseFloat(s, 64)
+	case *bool:
+		*vt, err = strconv.ParseBool(s)
+	case *time.Time:
+		*vt, err = http.ParseTime(s)
+	case *time.Duration:
+		*vt, err = time.ParseDuration(s)
+	default:
+		return false
+	}
+	return err == nil
+}
+
+func parseHttpInt(s string, bitSize int, set func(i int64)) error {
+	i, err := strconv.ParseInt(s, 10, bitSize)
+	if err == nil {
+		set(i)
+	}
+	return err
+}
+
+func parseHttpUint(s string, bitSize int, set func(u uint64)) error {
+	u, err := strconv.ParseUint(s, 10, bitSize)
+	if err == nil {
+		set(u)
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+package gopt
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractQuery(t *testing.T) {
+	values, err := url.ParseQuery("page=2&size=&flag&active=true&bad=x&name=foo&tag=a,b&tag=c&tag=&big=300&f=1.5&d=1m&neg=-1")
+	require.NoError(t, err)
+
+	page := ExtractQuery[int](values, "page")
+	require.True(t, page.WasSet())
+	require.True(t, page.IsPresent())
+	require.Equal(t, 2, page.OrElse(0))
+	require.Equal(t, SourceHTTP, page.Source())
+
+	size := ExtractQuery[int](values, "size")
+	require.True(t, size.WasSet())
+	require.False(t, size.IsPresent())
+
+	flag := ExtractQuery[string](values, "flag")
+	require.True(t, flag.WasSet())
+	require.False(t, flag.IsPresent())
+
+	missing := ExtractQuery[int](values, "missing")
+	require.False(t, missing.WasSet())
+	require.False(t, missing.IsPresent())
+
+	active := ExtractQuery[bool](values, "active")
+	require.True(t, active.OrElse(false))
+
+	// an invalid value is unset (distinct from an empty value, which is set but not present)...
+	bad := ExtractQuery[int](values, "bad")
+	require.False(t, bad.WasSet())
+	require.False(t, bad.IsPresent())
+	require.True(t, values.Has("bad"))
+
+	name := ExtractQuery[string](values, "name")
+	require.Equal(t, "foo", name.OrElse(""))
+
+	tags := ExtractQuery[[]string](values, "tag")
+	require.Equal(t, []string{"a", "b", "c"}, tags.OrElse(nil))
+	tags = ExtractQuery[[]string](values, "size")
+	require.True(t, tags.WasSet())
+	require.False(t, tags.IsPresent())
+	tags = ExtractQuery[[]string](values, "missing")
+	require.False(t, tags.WasSet())
+
+	require.False(t, ExtractQuery[int8](values, "big").IsPresent())
+	require.Equal(t, int16(300), ExtractQuery[int16](values, "big").OrElse(0))
+	require.Equal(t, int32(300), ExtractQuery[int32](values, "big").OrElse(0))
+	require.Equal(t, int64(300), ExtractQuery[int64](values, "big").OrElse(0))
+	require.Equal(t, int8(-1), ExtractQuery[int8](values, "neg").OrElse(0))
+	require.False(t, ExtractQuery[uint](values, "neg").IsPresent())
+	require.False(t, ExtractQuery[uint8](values, "big").IsPresent())
+	require.Equal(t, uint(300), ExtractQuery[uint](values, "big").OrElse(0))
+	require.Equal(t, uint16(300), ExtractQuery[uint16](values, "big").OrElse(0))
+	require.Equal(t, uint32(300), ExtractQuery[uint32](values, "big").OrElse(0))
+	require.Equal(t, uint64(300), ExtractQuery[uint64](values, "big").OrElse(0))
+	require.Equal(t, float32(1.5), ExtractQuery[float32](values, "f").OrElse(0))
+	require.Equal(t, 1.5, ExtractQuery[float64](values, "f").OrElse(0))
+	require.Equal(t, time.Minute, ExtractQuery[time.Duration](values, "d").OrElse(0))
+
+	type custom struct{ s string }
+	c := ExtractQuery[custom](values, "name")
+	require.False(t, c.WasSet())
+	require.False(t, c.IsPresent())
+	c = ExtractQuery[custom](values, "name", func(value any) (custom, bool) {
+		return custom{s: value.(string)}, true
+	})
+	require.True(t, c.IsPresent())
+	require.Equal(t, "foo", c.OrElse(custom{}).s)
+
+	require.False(t, ExtractQuery[int](nil, "page").WasSet())
+}
+
+func TestExtractQuery_Converters(t *testing.T) {
+	values, err := url.ParseQuery("name=foo&tag=a,b&tag=c&page=two&empty=&limit=abc")
+	require.NoError(t, err)
+
+	// as with Extract, values of the specified type (string and []string) are used directly - not converted...
+	upper := func(value any) (string, bool) {
+		if s, ok := value.(string); ok {
+			return strings.ToUpper(s), true
+		}
+		return "", false
+	}
+	name := ExtractQuery[string](values, "name", upper)
+	require.Equal(t, "foo", name.OrElse(""))
+	require.Equal(t, SourceHTTP, name.Source())
+	require.False(t, ExtractQuery[string](values, "empty", upper).IsPresent())
+	pipes := func(value any) ([]string, bool) {
+		if s, ok := value.(string); ok {
+			return strings.Split(s, "|"), true
+		}
+		return nil, false
+	}
+	tags := ExtractQuery[[]string](values, "tag", pipes)
+	require.Equal(t, []string{"a", "b", "c"}, tags.OrElse(nil))
+
+	// for other types, converters are used before built-in parsing...
+	words := func(value any) (int, bool) {
+		if value == "two" {
+			return 2, true
+		}
+		return 0, false
+	}
+	require.Equal(t, 2, ExtractQuery[int](values, "page", words).OrElse(0))
+	negate := func(value any) (int, bool) {
+		if s, ok := value.(string); ok && s == "1" {
+			return -1, true
+		}
+		return 0, false
+	}
+	values.Set("one", "1")
+	require.Equal(t, -1, ExtractQuery[int](values, "one", negate).OrElse(0))
+	// converter declining falls back to built-in parsing...
+	require.Equal(t, 1, ExtractQuery[int](values, "one", words).OrElse(0))
+	// neither converting nor parsing is unset...
+	limit := ExtractQuery[int](values, "limit", words)
+	require.False(t, limit.WasSet())
+	require.Equal(t, SourceNone, limit.Source())
+}
+
+func TestExtractHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+	header.Set("Content-Length", "123")
+	header.Add("Accept", "text/html, application/json")
+	header.Add("Accept", "*/*")
+	header["X-Empty"] = []string{""}
+
+	ims := ExtractHeader[time.Time](header, "if-modified-since")
+	require.True(t, ims.IsPresent())
+	require.Equal(t, time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), ims.OrElse(time.Time{}))
+
+	cl := ExtractHeader[int64](header, "content-length")
+	require.Equal(t, int64(123), cl.OrElse(0))
+
+	accept := ExtractHeader[[]string](header, "ACCEPT")
+	require.Equal(t, []string{"text/html", "application/json", "*/*"}, accept.OrElse(nil))
+
+	empty := ExtractHeader[string](header, "x-empty")
+	require.True(t, empty.WasSet())
+	require.False(t, empty.IsPresent())
+
+	missing := ExtractHeader[string](header, "x-missing")
+	require.False(t, missing.WasSet())
+}
+
+func TestExtractForm(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/?q=1", strings.NewReader("name=foo&age=42&empty="))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	require.Equal(t, "foo", ExtractForm[string](r, "name").OrElse(""))
+	require.Equal(t, 42, ExtractForm[int](r, "age").OrElse(0))
+	require.Equal(t, 1, ExtractForm[int](r, "q").OrElse(0))
+	empty := ExtractForm[string](r, "empty")
+	require.True(t, empty.WasSet())
+	require.False(t, empty.IsPresent())
+	require.False(t, ExtractForm[string](r, "missing").WasSet())
+}
@@ -0,0 +1,254 @@
+// Package layer - layered merging of structs of gopt.Optional fields
+/*
+Configuration typically comes from several layers - e.g. defaults, a config file, environment variables and
+command-line flags (in increasing order of precedence).  Merge merges layers (structs of the same type) where, for each
+optional field, the highest-precedence layer that set the field (see gopt.Optional.WasSet) supplies the value - e.g.
+	cfg, report, err := layer.Merge(
+		layer.Named("defaults", &defaults),
+		layer.Named("file", &fileCfg),
+		layer.Named("env", &envCfg),
+		layer.Named("flags", &flagCfg))
+	fmt.Print(report) // which layer supplied each field
+*/
+package layer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-andiamo/gopt"
+	"reflect"
+	"strings"
+)
+
+// NotStruct is the error returned by Merge when the layer type is not a struct
+var NotStruct = errors.New("layer type must be a struct")
+
+// Layer is a single (named) layer passed to Merge
+type Layer[T any] struct {
+	// Name is the name of the layer (as reported in the Report)
+	Name string
+	// Value is the layer value (a nil value is ignored)
+	Value *T
+}
+
+// Named creates a named Layer
+func Named[T any](name string, value *T) Layer[T] {
+	return Layer[T]{
+		Name:  name,
+		Value: value,
+	}
+}
+
+// FieldReport is a single field entry in the Report returned by Merge
+type FieldReport struct {
+	// Field is the name of the field (nested struct fields are dot separated, e.g. "Outer.Inner")
+	Field string
+	// Layer is the name of the layer that supplied the value ("" if no layer supplied the value)
+	Layer string
+	// Index is the index of the layer that supplied the value (-1 if no layer supplied the value)
+	Index int
+	// Set is whether the merged field value was set (always true for non-optional fields supplied by a layer)
+	Set bool
+	// Present is whether the merged field value is present (always true for non-optional fields supplied by a layer)
+	Present bool
+	// Source is the source of the merged field value (for optional fields)
+	Source gopt.Source
+	// Value is the merged field value
+	Value any
+}
+
+// String implements fmt.Stringer
+func (fr FieldReport) String() string {
+	v := fr.formatValue()
+	if fr.Index == -1 {
+		return fmt.Sprintf("%s = %v (not set)", fr.Field, v)
+	} else if fr.Source != gopt.SourceNone {
+		return fmt.Sprintf("%s = %v (layer: %s, source: %s)", fr.Field, v, fr.Layer, fr.Source)
+	}
+	return fmt.Sprintf("%s = %v (layer: %s)", fr.Field, v, fr.Layer)
+}
+
+// formatValue returns the value for formatting - optionals held by value are formatted via their (pointer receiver)
+// String method
+func (fr FieldReport) formatValue() any {
+	if rv := reflect.ValueOf(fr.Value); rv.IsValid() && rv.Kind() != reflect.Ptr {
+		pv := reflect.New(rv.Type())
+		pv.Elem().Set(rv)
+		if s, ok := pv.Interface().(fmt.Stringer); ok {
+			return s
+		}
+	}
+	return fr.Value
+}
+
+// Report is the report returned by Merge of which layer supplied each field
+type Report []FieldReport
+
+// Field returns the FieldReport for the named field (nested struct fields are dot separated, e.g. "Outer.Inner")
+func (r Report) Field(name string) (FieldReport, bool) {
+	for _, fr := range r {
+		if fr.Field == name {
+			return fr, true
+		}
+	}
+	return FieldReport{}, false
+}
+
+// String implements fmt.Stringer - with one line per field (suitable for --print-config style output)
+func (r Report) String() string {
+	var sb strings.Builder
+	for _, fr := range r {
+		sb.WriteString(fr.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Merge merges the supplied layers (in increasing order of precedence) into a new value
+//
+// For each Optional[T] (or non-nil *Optional[T]) field, the value is taken from the highest-precedence layer in which
+// the optional was set (see gopt.Optional.WasSet) - including optionals that were explicitly set but are not present
+// (e.g. a JSON null).  For other fields (i.e. non-optional fields, where it cannot be known whether the field was set)
+// the value is taken from the highest-precedence layer in which the value is not the zero value.  Nested structs (and
+// non-nil pointers to structs) are merged field by field - except structs with no exported fields (e.g. time.Time)
+// or with any unexported fields, which are merged as values (so their unexported state is never lost).  Unexported
+// fields of T itself are not merged and are left as the zero value
+//
+// The returned Report lists, for each field, which layer supplied the value
+func Merge[T any](layers ...Layer[T]) (*T, Report, error) {
+	result := new(T)
+	rv := reflect.ValueOf(result).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, NotStruct
+	}
+	lvs := make([]reflect.Value, len(layers))
+	names := make([]string, len(layers))
+	for i, l := range layers {
+		if l.Value != nil {
+			lvs[i] = reflect.ValueOf(l.Value).Elem()
+		}
+		names[i] = l.Name
+	}
+	m := &merger{names: names, report: Report{}}
+	m.mergeStruct(rv, lvs, "")
+	return result, m.report, nil
+}
+
+type optional interface {
+	WasSet() bool
+	IsPresent() bool
+	Source() gopt.Source
+}
+
+var optionalType = reflect.TypeOf((*optional)(nil)).Elem()
+
+type merger struct {
+	names  []string
+	report Report
+}
+
+func (m *merger) mergeStruct(rv reflect.Value, lvs []reflect.Value, prefix string) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := prefix + sf.Name
+		fvs := make([]reflect.Value, len(lvs))
+		for li, lv := range lvs {
+			if lv.IsValid() {
+				fvs[li] = lv.Field(i)
+			}
+		}
+		fv := rv.Field(i)
+		switch {
+		case reflect.PtrTo(sf.Type).Implements(optionalType):
+			m.mergeField(fv, fvs, name, func(v reflect.Value) bool {
+				return v.Addr().Interface().(optional).WasSet()
+			})
+		case sf.Type.Kind() == reflect.Ptr && sf.Type.Implements(optionalType):
+			m.mergeField(fv, fvs, name, func(v reflect.Value) bool {
+				return !v.IsNil() && v.Interface().(optional).WasSet()
+			})
+		case sf.Type.Kind() == reflect.Struct && mergedByField(sf.Type):
+			m.mergeStruct(fv, fvs, name+".")
+		case sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct && mergedByField(sf.Type.Elem()):
+			m.mergeStructPtr(fv, fvs, name)
+		default:
+			m.mergeField(fv, fvs, name, func(v reflect.Value) bool {
+				return !v.IsZero()
+			})
+		}
+	}
+}
+
+func (m *merger) mergeStructPtr(fv reflect.Value, fvs []reflect.Value, name string) {
+	evs := make([]reflect.Value, len(fvs))
+	found := false
+	for i, v := range fvs {
+		if v.IsValid() && !v.IsNil() {
+			evs[i] = v.Elem()
+			found = true
+		}
+	}
+	if found {
+		fv.Set(reflect.New(fv.Type().Elem()))
+		m.mergeStruct(fv.Elem(), evs, name+".")
+	}
+}
+
+func (m *merger) mergeField(fv reflect.Value, fvs []reflect.Value, name string, supplied func(v reflect.Value) bool) {
+	fr := FieldReport{
+		Field: name,
+		Index: -1,
+	}
+	for i := len(fvs) - 1; i >= 0; i-- {
+		if v := fvs[i]; v.IsValid() && supplied(v) {
+			if v.Kind() == reflect.Ptr {
+				// copy pointed to values (so that the merged value does not share, e.g., the layer's optional)
+				nv := reflect.New(v.Type().Elem())
+				nv.Elem().Set(v.Elem())
+				v = nv
+			}
+			fv.Set(v)
+			fr.Index = i
+			fr.Layer = m.names[i]
+			fr.Set = true
+			fr.Present = true
+			break
+		}
+	}
+	if o, ok := optionalOf(fv); ok {
+		fr.Set = o.WasSet()
+		fr.Present = o.IsPresent()
+		fr.Source = o.Source()
+	}
+	fr.Value = fv.Interface()
+	m.report = append(m.report, fr)
+}
+
+// mergedByField determines whether a struct type is merged field by field - structs with no exported fields (e.g.
+// time.Time) or with any unexported fields are merged as values (so that unexported state is never lost)
+func mergedByField(rt reflect.Type) bool {
+	exported := false
+	for i := 0; i < rt.NumField(); i++ {
+		if !rt.Field(i).IsExported() {
+			return false
+		}
+		exported = true
+	}
+	return exported
+}
+
+func optionalOf(fv reflect.Value) (optional, bool) {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() || !fv.Type().Implements(optionalType) {
+			return nil, false
+		}
+		return fv.Interface().(optional), true
+	} else if fv.CanAddr() && fv.Addr().Type().Implements(optionalType) {
+		return fv.Addr().Interface().(optional), true
+	}
+	return nil, false
+}
@@ -0,0 +1,196 @@
+package layer
+
+import (
+	"encoding/json"
+	"github.com/go-andiamo/gopt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+type database struct {
+	Host gopt.Optional[string] `json:"host"`
+	Port gopt.Optional[int]    `json:"port"`
+}
+
+type config struct {
+	Name     gopt.Optional[string]  `json:"name"`
+	Port     gopt.Optional[int]     `json:"port"`
+	Debug    gopt.Optional[bool]    `json:"debug"`
+	Email    *gopt.Optional[string] `json:"email"`
+	Timeout  int                    `json:"timeout"`
+	Database database               `json:"database"`
+	Replica  *database              `json:"replica"`
+	private  string
+}
+
+func defaultOf[T any](v T) gopt.Optional[T] {
+	o := gopt.Empty[T]()
+	o.SetFrom(gopt.SourceDefault, v)
+	return *o
+}
+
+func TestMerge(t *testing.T) {
+	defaults := &config{
+		Name:    defaultOf("app"),
+		Port:    defaultOf(8080),
+		Debug:   defaultOf(false),
+		Timeout: 30,
+		Database: database{
+			Host: defaultOf("localhost"),
+			Port: defaultOf(5432),
+		},
+	}
+	file := &config{}
+	err := json.Unmarshal([]byte(`{"name": null, "port": 9090, "email": "ops@example.com", "database": {"host": "db.example.com"}, "replica": {"host": "replica.example.com"}}`), file)
+	require.NoError(t, err)
+	env := &config{}
+	env.Debug.Set(true)
+	env.Timeout = 60
+	flags := &config{}
+	flags.Port.Set(0)
+
+	merged, report, err := Merge(
+		Named("defaults", defaults),
+		Named("file", file),
+		Named("env", env),
+		Named[config]("missing", nil),
+		Named("flags", flags))
+	require.NoError(t, err)
+
+	require.True(t, merged.Name.WasSet())
+	require.False(t, merged.Name.IsPresent())
+	require.True(t, merged.Port.IsPresent())
+	require.Equal(t, 0, merged.Port.OrElse(-1))
+	require.True(t, merged.Debug.OrElse(false))
+	require.NotNil(t, merged.Email)
+	require.Equal(t, "ops@example.com", merged.Email.OrElse(""))
+	require.False(t, merged.Email == file.Email)
+	require.Equal(t, 60, merged.Timeout)
+	require.Equal(t, "db.example.com", merged.Database.Host.OrElse(""))
+	require.Equal(t, 5432, merged.Database.Port.OrElse(0))
+	require.NotNil(t, merged.Replica)
+	require.False(t, merged.Replica == file.Replica)
+	require.Equal(t, "replica.example.com", merged.Replica.Host.OrElse(""))
+	require.False(t, merged.Replica.Port.WasSet())
+
+	require.Equal(t, 9, len(report))
+	fr, ok := report.Field("Name")
+	require.True(t, ok)
+	require.Equal(t, "file", fr.Layer)
+	require.Equal(t, 1, fr.Index)
+	require.True(t, fr.Set)
+	require.False(t, fr.Present)
+	require.Equal(t, gopt.SourceJSON, fr.Source)
+	fr, _ = report.Field("Port")
+	require.Equal(t, "flags", fr.Layer)
+	require.Equal(t, 4, fr.Index)
+	require.Equal(t, gopt.SourceExplicit, fr.Source)
+	fr, _ = report.Field("Debug")
+	require.Equal(t, "env", fr.Layer)
+	fr, _ = report.Field("Email")
+	require.Equal(t, "file", fr.Layer)
+	require.True(t, fr.Present)
+	fr, _ = report.Field("Timeout")
+	require.Equal(t, "env", fr.Layer)
+	require.True(t, fr.Set)
+	require.True(t, fr.Present)
+	require.Equal(t, 60, fr.Value)
+	fr, _ = report.Field("Database.Host")
+	require.Equal(t, "file", fr.Layer)
+	fr, _ = report.Field("Database.Port")
+	require.Equal(t, "defaults", fr.Layer)
+	require.Equal(t, gopt.SourceDefault, fr.Source)
+	fr, _ = report.Field("Replica.Port")
+	require.Equal(t, -1, fr.Index)
+	require.Equal(t, "", fr.Layer)
+	require.False(t, fr.Set)
+	_, ok = report.Field("Xxx")
+	require.False(t, ok)
+
+	out := report.String()
+	require.True(t, strings.Contains(out, "Port = 0 (layer: flags, source: explicit)\n"), out)
+	require.True(t, strings.Contains(out, "Timeout = 60 (layer: env)\n"), out)
+	require.True(t, strings.Contains(out, "Database.Port = 5432 (layer: defaults, source: default)\n"), out)
+}
+
+func TestMerge_NoLayers(t *testing.T) {
+	merged, report, err := Merge[config]()
+	require.NoError(t, err)
+	require.NotNil(t, merged)
+	require.Nil(t, merged.Email)
+	require.Nil(t, merged.Replica)
+	fr, _ := report.Field("Email")
+	require.Equal(t, -1, fr.Index)
+	require.Equal(t, "Email = <not present> (not set)", fr.String())
+}
+
+func TestMerge_NotStruct(t *testing.T) {
+	s := "x"
+	_, _, err := Merge(Named("a", &s))
+	require.Equal(t, NotStruct, err)
+}
+
+func TestMerge_OpaqueStructs(t *testing.T) {
+	type schedule struct {
+		Start time.Time
+		End   *time.Time
+		At    gopt.Optional[time.Time]
+	}
+	start := time.Unix(1000, 0)
+	end := time.Unix(2000, 0)
+	base := &schedule{Start: time.Unix(1, 0)}
+	override := &schedule{Start: start, End: &end}
+	override.At.Set(end)
+	merged, report, err := Merge(Named("base", base), Named("override", override))
+	require.NoError(t, err)
+	require.True(t, start.Equal(merged.Start))
+	require.True(t, end.Equal(*merged.End))
+	require.True(t, end.Equal(merged.At.OrElse(time.Time{})))
+	fr, ok := report.Field("Start")
+	require.True(t, ok)
+	require.Equal(t, "override", fr.Layer)
+	fr, ok = report.Field("End")
+	require.True(t, ok)
+	require.Equal(t, "override", fr.Layer)
+
+	merged, report, err = Merge(Named("base", base))
+	require.NoError(t, err)
+	require.True(t, time.Unix(1, 0).Equal(merged.Start))
+	require.Nil(t, merged.End)
+	fr, _ = report.Field("End")
+	require.Equal(t, -1, fr.Index)
+}
+
+type secret struct {
+	Name  string
+	token string
+}
+
+func TestMerge_UnexportedFields(t *testing.T) {
+	type settings struct {
+		Secret    secret
+		SecretPtr *secret
+	}
+	base := &settings{Secret: secret{Name: "base", token: "t1"}}
+	override := &settings{SecretPtr: &secret{Name: "override", token: "t2"}}
+	merged, report, err := Merge(Named("base", base), Named("override", override))
+	require.NoError(t, err)
+	require.Equal(t, secret{Name: "base", token: "t1"}, merged.Secret)
+	require.Equal(t, secret{Name: "override", token: "t2"}, *merged.SecretPtr)
+	fr, ok := report.Field("Secret")
+	require.True(t, ok)
+	require.Equal(t, "base", fr.Layer)
+	fr, ok = report.Field("SecretPtr")
+	require.True(t, ok)
+	require.Equal(t, "override", fr.Layer)
+	_, ok = report.Field("Secret.Name")
+	require.False(t, ok)
+
+	// merged as whole values - not field by field...
+	override.Secret = secret{token: "t3"}
+	merged, _, err = Merge(Named("base", base), Named("override", override))
+	require.NoError(t, err)
+	require.Equal(t, secret{token: "t3"}, merged.Secret)
+}
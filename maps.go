@@ -1,7 +1,7 @@
 package gopt
 
 import (
-	"strconv"
+	"sort"
 	"strings"
 )
 
@@ -25,6 +25,22 @@ func Extract[K comparable, T any](m map[K]any, key K, converters ...Converter[T]
 	return result
 }
 
+// ExtractWith extracts an optional value, of the specified type, from a map - where presence is determined by the supplied presence policy
+//
+// If the key is present (and the value is present, according to the policy, and of the specified type) then an optional with the value is returned, otherwise an empty optional is returned
+func ExtractWith[K comparable, T any](policy PresencePolicy, m map[K]any, key K, converters ...Converter[T]) *Optional[T] {
+	policy = presencePolicy(policy)
+	result := Empty[T]().SetPolicy(policy)
+	if rv, ok := m[key]; ok && policy.IsPresent(rv) {
+		if v, ok := rv.(T); ok {
+			result = OfWith[T](policy, v)
+		} else if v, ok := runConverters(rv, converters...); ok {
+			result = OfWith[T](policy, v)
+		}
+	}
+	return result
+}
+
 // ExtractJson extracts an optional value, of the specified type, from a map[string]any
 //
 // If the key is present (and the value is non-nil and of the specified type) then an optional with the value is returned, otherwise an empty optional is returned
@@ -67,101 +83,45 @@ func ExtractJson[T any](m map[string]any, key string, converters ...Converter[T]
 //  o, _ := ExtractJsonPath[string](m, "foo.bar[-1].baz")
 // would yield a present Optional with value "Y"
 //
+// Indexes may be chained (e.g. "matrix[0][1]") and properties whose names contain "." or "[" may be addressed using
+// bracket-quoted keys (e.g. `labels["app.kubernetes.io/name"]`) or by escaping with a backslash (e.g. `labels.app\.kubernetes\.io/name`)
 //
 // The second result arg of ExtractJsonPath is a slice of bools indicating the whether the path items existed - if the returned
 // Optional is not present it may be that the final property of the path was not found (or the incorrect type) or that the path was not found...
-// if the path was not found, then the final element in that second slice return arg will be false
+// if the path was not found, then the final element in that second slice return arg will be false.  For a path that is
+// a single property (e.g. "foo"), the second result arg is nil
+//
+// Note: the path grammar has been extended from earlier versions, where a path was simply split on "." - the following
+// changes affect existing paths:
+//   a backslash is now an escape character (e.g. `a\.b` is now the property "a.b", previously the properties `a\` then "b")
+//   a part that is a bracket-quoted key (e.g. `["a.b"]`) is now a key, previously it was the literal property name
+// Paths that are not valid in the extended grammar (e.g. "foo[bar]" or "") are still interpreted as in earlier
+// versions (e.g. "foo[bar]" is the literal property "foo[bar]") - ExtractJsonPathFrom, CompilePath and ExtractPath report
+// such paths as errors
 func ExtractJsonPath[T any](m map[string]any, path string, converters ...Converter[T]) (*Optional[T], []bool) {
-	parts := strings.Split(path, ".")
-	l := len(parts)
-	if l == 1 && !(strings.HasSuffix(parts[0], "]") && strings.Contains(parts[0], "[")) {
-		return ExtractJson[T](m, parts[0]), nil
-	}
-	result := Empty[T]()
-	pathPresent := make([]bool, 0, l-1)
-	curr := m
-	ok := false
-	for i, part := range parts {
-		if pty, isIndexed, idx := getProperty(part); isIndexed {
-			if curr, result, pathPresent, ok = extractPathIndexed[T](pty, idx, i == l-1, curr, result, pathPresent, converters...); !ok {
-				break
-			}
-		} else if curr, result, pathPresent, ok = extractPathProperty[T](pty, i == l-1, curr, result, pathPresent, converters...); !ok {
-			break
-		}
-	}
-	return result, pathPresent
-}
-
-func extractPathProperty[T any](pty string, last bool, curr map[string]any, result *Optional[T], pathPresent []bool, converters ...Converter[T]) (map[string]any, *Optional[T], []bool, bool) {
-	isOk := false
-	if rv, ok := curr[pty]; ok {
-		if last {
-			if av, ok := rv.(T); ok && isPresent(rv) {
-				isOk = true
-				result = Of[T](av)
-			} else if cv, ok := runConverters[T](rv, converters...); ok {
-				isOk = true
-				result = Of[T](cv)
-			}
-		} else if curr, ok = rv.(map[string]any); ok {
-			isOk = true
-			pathPresent = append(pathPresent, true)
-		} else {
-			pathPresent = append(pathPresent, false)
-		}
-	} else {
-		pathPresent = append(pathPresent, false)
+	steps, err := parsePath(path)
+	legacy := err != nil
+	if legacy {
+		steps = legacyPathSteps(path)
 	}
-	return curr, result, pathPresent, isOk
-}
-
-func extractPathIndexed[T any](pty string, idx int, last bool, curr map[string]any, result *Optional[T], pathPresent []bool, converters ...Converter[T]) (map[string]any, *Optional[T], []bool, bool) {
-	isOk := false
-	if rv, ok := curr[pty]; ok {
-		pathPresent = append(pathPresent, true)
-		if sv, ok := rv.([]any); ok {
-			if idx < 0 {
-				idx = len(sv) + idx
-			}
-			if idx >= 0 && idx < len(sv) {
-				v := sv[idx]
-				if last {
-					pathPresent = append(pathPresent, true)
-					if av, ok := v.(T); ok && isPresent(v) {
-						result = Of[T](av)
-						isOk = true
-					} else if cv, ok := runConverters[T](v, converters...); ok {
-						result = Of[T](cv)
-						isOk = true
-					}
-				} else if curr, ok = v.(map[string]any); ok {
-					pathPresent = append(pathPresent, true)
-					isOk = true
-				} else {
-					pathPresent = append(pathPresent, false)
-				}
-			} else {
-				pathPresent = append(pathPresent, false)
-			}
-		} else {
-			pathPresent = append(pathPresent, false)
-		}
-	} else {
-		pathPresent = append(pathPresent, false)
+	if len(steps) == 1 && !steps[0].indexed && !(legacy && strings.HasSuffix(path, "]") && strings.Contains(path, "[")) {
+		return ExtractJson[T](m, steps[0].key, converters...), nil
 	}
-	return curr, result, pathPresent, isOk
+	return extractPathSteps[T](m, steps, true, converters...)
 }
 
-func getProperty(pathPart string) (string, bool, int) {
-	if oat := strings.LastIndexByte(pathPart, '['); oat != -1 && strings.HasSuffix(pathPart, "]") {
-		pty := pathPart[:oat]
-		idxs := pathPart[oat+1 : len(pathPart)-1]
-		if idx, err := strconv.ParseInt(idxs, 10, 64); err == nil {
-			return pty, true, int(idx)
-		}
+// ExtractJsonPathFrom is the same as ExtractJsonPath, except that the root may be any value (e.g. a []any, where the path starts
+// with an index such as "[0].foo") and an error (a *PathSyntaxError) is returned if the supplied path is invalid
+//
+// Unlike ExtractJsonPath, the second result arg is never nil for a valid path (for a single property path it is empty)
+// and converters are not called for nil values
+func ExtractJsonPathFrom[T any](root any, path string, converters ...Converter[T]) (*Optional[T], []bool, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return Empty[T](), nil, err
 	}
-	return pathPart, false, -1
+	result, pathPresent := extractPathSteps[T](root, steps, false, converters...)
+	return result, pathPresent, nil
 }
 
 func runConverters[T any](value any, converters ...Converter[T]) (result T, ok bool) {
@@ -185,6 +145,16 @@ func Get[K comparable, T any](m map[K]T, key K) *Optional[T] {
 	return Empty[T]()
 }
 
+// GetWith obtains an optional from a map - where presence is determined by the supplied presence policy
+//
+// If the key is present (and the value is present, according to the policy) then an optional with the value is returned, otherwise an empty optional is returned
+func GetWith[K comparable, T any](policy PresencePolicy, m map[K]T, key K) *Optional[T] {
+	if v, ok := m[key]; ok {
+		return OfWith(policy, v)
+	}
+	return Empty[T]().SetPolicy(policy)
+}
+
 // OptMap can be used to cast an existing map for optional/functional methods
 type OptMap[K comparable, V any] map[K]V
 
@@ -195,6 +165,13 @@ func (m OptMap[K, V]) Get(key K) *Optional[V] {
 	return Get(m, key)
 }
 
+// GetWith returns an optional of the value in the map - where presence is determined by the supplied presence policy
+//
+// If the value is not present (according to the policy) then an empty optional is returned
+func (m OptMap[K, V]) GetWith(policy PresencePolicy, key K) *Optional[V] {
+	return GetWith(policy, m, key)
+}
+
 // Default returns the value (if present and non-nil) otherwise returns the default value
 func (m OptMap[K, V]) Default(key K, def V) V {
 	return Get(m, key).Default(def)
@@ -287,3 +264,185 @@ func (m OptMap[K, V]) ReplaceIfPresent(key K, v V) bool {
 	}
 	return false
 }
+
+// Compute computes a new mapping for the specified key using the supplied function - which is passed an optional of the
+// current value (empty if the key is absent or the value is nil)
+//
+// If the function returns a present optional, the key is set to its value - otherwise the key is deleted
+//
+// returns an optional of the new value
+func (m OptMap[K, V]) Compute(key K, f func(key K, current *Optional[V]) *Optional[V]) *Optional[V] {
+	if f == nil {
+		return Get(m, key)
+	}
+	rv := f(key, Get(m, key))
+	if rv.IsPresent() {
+		m[key] = rv.value
+		return Of(rv.value)
+	}
+	delete(m, key)
+	return Empty[V]()
+}
+
+// Merge if the specified key is absent (not present or nil-value) sets it to the specified value, otherwise sets it to
+// the result of calling the supplied function with the existing and specified values
+//
+// If the resulting value is nil, the key is deleted
+//
+// returns the new value
+func (m OptMap[K, V]) Merge(key K, v V, f func(old V, new V) V) V {
+	rv := v
+	if ov, ok := m[key]; ok && isPresent(ov) && f != nil {
+		rv = f(ov, v)
+	}
+	if isPresent(rv) {
+		m[key] = rv
+	} else {
+		delete(m, key)
+	}
+	return rv
+}
+
+// GetOrDefault returns the value (if present and non-nil) otherwise returns the default value (the same as Default)
+func (m OptMap[K, V]) GetOrDefault(key K, def V) V {
+	return m.Default(key, def)
+}
+
+// RemoveIf removes all entries (with non-nil values) for which the supplied predicate returns true
+//
+// returns the number of entries removed
+func (m OptMap[K, V]) RemoveIf(pred func(key K, v V) bool) int {
+	count := 0
+	if pred != nil {
+		for k, v := range m {
+			if isPresent(v) && pred(k, v) {
+				delete(m, k)
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Filter returns a new map containing the entries (with non-nil values) for which the supplied predicate returns true
+//
+// If the supplied predicate is nil, all entries with non-nil values are returned
+func (m OptMap[K, V]) Filter(pred func(key K, v V) bool) OptMap[K, V] {
+	result := OptMap[K, V]{}
+	for k, v := range m {
+		if isPresent(v) && (pred == nil || pred(k, v)) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Keys returns the keys of the entries with non-nil values (in no particular order)
+func (m OptMap[K, V]) Keys() []K {
+	result := make([]K, 0, len(m))
+	for k, v := range m {
+		if isPresent(v) {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// Values returns the non-nil values (in no particular order)
+func (m OptMap[K, V]) Values() []V {
+	result := make([]V, 0, len(m))
+	for _, v := range m {
+		if isPresent(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Union returns a new map containing the entries (with non-nil values) of both this and the other map
+//
+// Where a key has non-nil values in both maps, the supplied resolve function is called to determine the value (if the
+// resolve function is nil, the other map's value is used) - if the resolved value is nil, the key is omitted
+func (m OptMap[K, V]) Union(other OptMap[K, V], resolve func(key K, v V, otherV V) V) OptMap[K, V] {
+	result := m.Filter(nil)
+	for k, ov := range other {
+		if !isPresent(ov) {
+			continue
+		}
+		if v, ok := result[k]; ok && resolve != nil {
+			if rv := resolve(k, v, ov); isPresent(rv) {
+				result[k] = rv
+			} else {
+				delete(result, k)
+			}
+		} else {
+			result[k] = ov
+		}
+	}
+	return result
+}
+
+// Intersect returns a new map containing the entries whose keys have non-nil values in both this and the other map
+//
+// The supplied resolve function is called to determine the value (if the resolve function is nil, this map's value is
+// used) - if the resolved value is nil, the key is omitted
+func (m OptMap[K, V]) Intersect(other OptMap[K, V], resolve func(key K, v V, otherV V) V) OptMap[K, V] {
+	result := OptMap[K, V]{}
+	for k, v := range m {
+		if !isPresent(v) {
+			continue
+		}
+		if ov, ok := other[k]; ok && isPresent(ov) {
+			if resolve != nil {
+				v = resolve(k, v, ov)
+			}
+			if isPresent(v) {
+				result[k] = v
+			}
+		}
+	}
+	return result
+}
+
+// MapValues returns a new map with the non-nil values of the supplied map mapped using the supplied function
+//
+// If the mapped value is nil, the key is omitted
+func MapValues[K comparable, V any, U any](m OptMap[K, V], f func(key K, v V) U) OptMap[K, U] {
+	result := OptMap[K, U]{}
+	for k, v := range m {
+		if isPresent(v) {
+			if u := f(k, v); isPresent(u) {
+				result[k] = u
+			}
+		}
+	}
+	return result
+}
+
+// Ordered is a constraint that permits any ordered type (any type that supports the operators < <= >= >)
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~string
+}
+
+// Entry is a key/value entry (as returned by SortedEntries)
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// SortedEntries returns the entries (with non-nil values) of the supplied map, sorted by key
+func SortedEntries[K Ordered, V any](m OptMap[K, V]) []Entry[K, V] {
+	result := make([]Entry[K, V], 0, len(m))
+	for k, v := range m {
+		if isPresent(v) {
+			result = append(result, Entry[K, V]{Key: k, Value: v})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"sort"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -363,6 +365,58 @@ func TestGet(t *testing.T) {
 	require.False(t, o2.IsPresent())
 }
 
+func TestExtractWith(t *testing.T) {
+	m := map[string]any{
+		"str":   "Str",
+		"blank": "  ",
+		"int":   0,
+		"nil":   nil,
+	}
+	s := ExtractWith[string, string](BlankStringIsEmpty, m, "str")
+	require.True(t, s.IsPresent())
+	require.Equal(t, BlankStringIsEmpty, s.Policy())
+	s = ExtractWith[string, string](BlankStringIsEmpty, m, "blank")
+	require.False(t, s.IsPresent())
+	require.Equal(t, BlankStringIsEmpty, s.Policy())
+	s = ExtractWith[string, string](nil, m, "blank")
+	require.True(t, s.IsPresent())
+	s = ExtractWith[string, string](nil, m, "nil")
+	require.False(t, s.IsPresent())
+
+	i := ExtractWith[string, int](ZeroIsEmpty, m, "int")
+	require.False(t, i.IsPresent())
+	i = ExtractWith[string, int](NilOnly, m, "int")
+	require.True(t, i.IsPresent())
+
+	intToStr := func(v any) (string, bool) {
+		if i, ok := v.(int); ok {
+			return fmt.Sprintf("%d", i), true
+		}
+		return "", false
+	}
+	s = ExtractWith[string, string](ZeroIsEmpty, m, "int", intToStr)
+	require.False(t, s.IsPresent())
+	s = ExtractWith[string, string](BlankStringIsEmpty, m, "int", intToStr)
+	require.True(t, s.IsPresent())
+	require.Equal(t, "0", s.OrElse(""))
+}
+
+func TestGetWith(t *testing.T) {
+	m := map[string]string{
+		"foo": "foo value",
+		"bar": " ",
+	}
+	o := GetWith(BlankStringIsEmpty, m, "foo")
+	require.True(t, o.IsPresent())
+	o = GetWith(BlankStringIsEmpty, m, "bar")
+	require.False(t, o.IsPresent())
+	o = GetWith(BlankStringIsEmpty, m, "baz")
+	require.False(t, o.IsPresent())
+	require.Equal(t, BlankStringIsEmpty, o.Policy())
+	o = GetWith(nil, m, "bar")
+	require.True(t, o.IsPresent())
+}
+
 func TestOptMap_Get(t *testing.T) {
 	m := map[string]any{
 		"foo": "foo value",
@@ -377,6 +431,18 @@ func TestOptMap_Get(t *testing.T) {
 	require.False(t, ov.IsPresent())
 }
 
+func TestOptMap_GetWith(t *testing.T) {
+	m := map[string][]int{
+		"foo": {1},
+		"bar": {},
+	}
+	om := OptMap[string, []int](m)
+	require.True(t, om.GetWith(EmptyCollectionIsEmpty, "foo").IsPresent())
+	require.False(t, om.GetWith(EmptyCollectionIsEmpty, "bar").IsPresent())
+	require.False(t, om.GetWith(EmptyCollectionIsEmpty, "baz").IsPresent())
+	require.True(t, om.Get("bar").IsPresent())
+}
+
 func TestOptMap_IfPresent(t *testing.T) {
 	m := map[string]any{
 		"foo": "foo value",
@@ -552,3 +618,173 @@ func TestOptMap_ReplaceIfPresent(t *testing.T) {
 	_, ok = m["foo"]
 	require.False(t, ok)
 }
+
+func TestOptMap_Compute(t *testing.T) {
+	om := OptMap[string, any]{
+		"foo": "foo value",
+		"bar": nil,
+	}
+	var current *Optional[any]
+	f := func(k string, c *Optional[any]) *Optional[any] {
+		current = c
+		if k == "baz" {
+			return Empty[any]()
+		}
+		return Of[any](k + " computed")
+	}
+	o := om.Compute("foo", f)
+	require.True(t, current.IsPresent())
+	require.Equal(t, "foo value", current.OrElse(nil))
+	require.Equal(t, "foo computed", o.OrElse(nil))
+	require.Equal(t, "foo computed", om["foo"])
+
+	o = om.Compute("bar", f)
+	require.False(t, current.IsPresent())
+	require.Equal(t, "bar computed", o.OrElse(nil))
+	require.Equal(t, "bar computed", om["bar"])
+
+	om["baz"] = "baz value"
+	o = om.Compute("baz", f)
+	require.False(t, o.IsPresent())
+	_, ok := om["baz"]
+	require.False(t, ok)
+
+	o = om.Compute("foo", func(k string, c *Optional[any]) *Optional[any] {
+		return nil
+	})
+	require.False(t, o.IsPresent())
+	_, ok = om["foo"]
+	require.False(t, ok)
+
+	o = om.Compute("bar", nil)
+	require.Equal(t, "bar computed", o.OrElse(nil))
+}
+
+func TestOptMap_Merge(t *testing.T) {
+	om := OptMap[string, any]{
+		"count": 1,
+		"nil":   nil,
+	}
+	add := func(old any, new any) any {
+		if new == nil {
+			return nil
+		}
+		return old.(int) + new.(int)
+	}
+	require.Equal(t, 3, om.Merge("count", 2, add))
+	require.Equal(t, 3, om["count"])
+	require.Equal(t, 5, om.Merge("nil", 5, add))
+	require.Equal(t, 5, om["nil"])
+	require.Equal(t, 1, om.Merge("new", 1, add))
+	require.Equal(t, 1, om["new"])
+	require.Nil(t, om.Merge("count", nil, add))
+	_, ok := om["count"]
+	require.False(t, ok)
+	require.Equal(t, 2, om.Merge("new", 2, nil))
+}
+
+func TestOptMap_GetOrDefault(t *testing.T) {
+	om := OptMap[string, any]{
+		"foo": "foo value",
+		"bar": nil,
+	}
+	require.Equal(t, "foo value", om.GetOrDefault("foo", "default"))
+	require.Equal(t, "default", om.GetOrDefault("bar", "default"))
+	require.Equal(t, "default", om.GetOrDefault("baz", "default"))
+}
+
+func TestOptMap_RemoveIf(t *testing.T) {
+	om := OptMap[string, any]{
+		"a":   1,
+		"b":   2,
+		"c":   3,
+		"nil": nil,
+	}
+	count := om.RemoveIf(func(k string, v any) bool {
+		require.NotNil(t, v)
+		return v.(int) > 1
+	})
+	require.Equal(t, 2, count)
+	require.Equal(t, 2, len(om))
+	require.Equal(t, 0, om.RemoveIf(nil))
+}
+
+func TestOptMap_Filter(t *testing.T) {
+	om := OptMap[string, any]{
+		"a":   1,
+		"b":   2,
+		"nil": nil,
+	}
+	f := om.Filter(func(k string, v any) bool {
+		return v.(int) > 1
+	})
+	require.Equal(t, OptMap[string, any]{"b": 2}, f)
+	require.Equal(t, OptMap[string, any]{"a": 1, "b": 2}, om.Filter(nil))
+	require.Equal(t, 3, len(om))
+}
+
+func TestOptMap_KeysValues(t *testing.T) {
+	om := OptMap[string, any]{
+		"a":   1,
+		"b":   2,
+		"nil": nil,
+	}
+	keys := om.Keys()
+	sort.Strings(keys)
+	require.Equal(t, []string{"a", "b"}, keys)
+	values := om.Values()
+	require.Equal(t, 2, len(values))
+	require.Contains(t, values, 1)
+	require.Contains(t, values, 2)
+	require.Equal(t, 0, len(OptMap[string, any]{}.Keys()))
+}
+
+func TestOptMap_Union(t *testing.T) {
+	m1 := OptMap[string, any]{"a": 1, "b": 2, "n1": nil, "n2": 3}
+	m2 := OptMap[string, any]{"b": 20, "c": 30, "n1": 10, "n2": nil}
+	u := m1.Union(m2, nil)
+	require.Equal(t, OptMap[string, any]{"a": 1, "b": 20, "c": 30, "n1": 10, "n2": 3}, u)
+	u = m1.Union(m2, func(k string, v any, otherV any) any {
+		return v.(int) + otherV.(int)
+	})
+	require.Equal(t, 22, u["b"])
+	u = m1.Union(m2, func(k string, v any, otherV any) any {
+		return nil
+	})
+	_, ok := u["b"]
+	require.False(t, ok)
+	require.Equal(t, 4, len(u))
+}
+
+func TestOptMap_Intersect(t *testing.T) {
+	m1 := OptMap[string, any]{"a": 1, "b": 2, "n1": nil, "n2": 3}
+	m2 := OptMap[string, any]{"b": 20, "c": 30, "n1": 10, "n2": nil}
+	require.Equal(t, OptMap[string, any]{"b": 2}, m1.Intersect(m2, nil))
+	require.Equal(t, OptMap[string, any]{"b": 20}, m1.Intersect(m2, func(k string, v any, otherV any) any {
+		return otherV
+	}))
+	require.Equal(t, OptMap[string, any]{}, m1.Intersect(m2, func(k string, v any, otherV any) any {
+		return nil
+	}))
+}
+
+func TestMapValues(t *testing.T) {
+	om := OptMap[string, any]{"a": 1, "b": 2, "nil": nil}
+	mv := MapValues(om, func(k string, v any) *string {
+		if v.(int) > 1 {
+			return nil
+		}
+		s := k + strconv.Itoa(v.(int))
+		return &s
+	})
+	require.Equal(t, 1, len(mv))
+	require.Equal(t, "a1", *mv["a"])
+}
+
+func TestSortedEntries(t *testing.T) {
+	om := OptMap[string, any]{"c": 3, "a": 1, "b": 2, "nil": nil}
+	require.Equal(t, []Entry[string, any]{{"a", 1}, {"b", 2}, {"c", 3}}, SortedEntries(om))
+	type id int
+	im := OptMap[id, string]{3: "c", 1: "a", 2: "b"}
+	require.Equal(t, []Entry[id, string]{{1, "a"}, {2, "b"}, {3, "c"}}, SortedEntries(im))
+}
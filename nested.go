@@ -0,0 +1,202 @@
+package gopt
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// NoKeys is the error returned by OptMap.SetIn when no keys are supplied
+	NoKeys = errors.New("no keys supplied")
+	// KeysConflict is the error returned (wrapped) by OptMap.SetIn when an existing value is not a map (or a map with a
+	// different key type) or the value cannot be assigned to the map
+	KeysConflict = errors.New("keys conflict with existing value")
+	// NilMap is the error returned by OptMap.SetIn when the map is nil
+	NilMap = errors.New("nil map")
+)
+
+// GetIn obtains an optional from nested maps - by successively looking up each of the supplied keys
+//
+// For example:
+//   m := map[string]map[string]map[string]int{
+//     "db": {
+//       "primary": {
+//         "port": 5432,
+//       },
+//     },
+//   }
+//   o := GetIn[string, int](m, "db", "primary", "port")
+// would yield a present Optional with value 5432
+//
+// The nested maps may be of any map type (including OptMap and map[K]any) where the supplied key can be used as the map
+// key (keys are converted to the map key type where they are of the same kind - e.g. a string key can be used with a
+// map[MyString]V).  Where the nested maps have different key types, use any as the key type (e.g. GetIn[any, int](m, region, name))
+//
+// If any key is not found (or an intermediate value is not a map), or the final value is nil or not of the specified
+// type, an empty optional is returned
+func GetIn[K any, V any](m any, keys ...K) *Optional[V] {
+	if v, ok := getIn(m, keys); ok {
+		if tv, ok := v.(V); ok {
+			return Of(tv)
+		}
+	}
+	return Empty[V]()
+}
+
+// GetIn obtains an optional of the value in nested maps (see GetIn)
+//
+// The returned optional is of type any - use MapTo to obtain a typed optional, e.g.
+//   port := MapTo[int](m.GetIn("db", "primary", "port"))
+func (m OptMap[K, V]) GetIn(keys ...K) *Optional[any] {
+	return GetIn[K, any](map[K]V(m), keys...)
+}
+
+// SetIn sets the value in nested maps - by successively looking up each of the supplied keys and setting the value
+// for the final key
+//
+// Missing (or nil) intermediate maps are created - where the map value type is a map type, a map of that type is
+// created, otherwise (e.g. where the map value type is any) a map[K]any is created
+//
+// Returns NoKeys if no keys are supplied, NilMap if the map is nil, or an error wrapping KeysConflict if an existing intermediate value is not a
+// map (or the value cannot be assigned to the final map)
+func (m OptMap[K, V]) SetIn(v any, keys ...K) error {
+	if len(keys) == 0 {
+		return NoKeys
+	} else if m == nil {
+		return NilMap
+	}
+	current := reflect.ValueOf(map[K]V(m))
+	for i, k := range keys {
+		kv, ok := nestedKey(current.Type(), k)
+		if !ok {
+			return keysError(keys, i, fmt.Sprintf("key type %T cannot be used with %s", k, current.Type()))
+		}
+		et := current.Type().Elem()
+		if i == len(keys)-1 {
+			vv := reflect.ValueOf(v)
+			if !vv.IsValid() {
+				switch et.Kind() {
+				case reflect.Interface, reflect.Map, reflect.Slice, reflect.Ptr, reflect.Func, reflect.Chan:
+					vv = reflect.Zero(et)
+				default:
+					return keysError(keys, i, fmt.Sprintf("cannot assign nil to %s", et))
+				}
+			} else if !vv.Type().AssignableTo(et) {
+				return keysError(keys, i, fmt.Sprintf("cannot assign %T to %s", v, et))
+			}
+			current.SetMapIndex(kv, vv)
+			break
+		}
+		child := nestedMap(current.MapIndex(kv))
+		if !child.IsValid() {
+			var nm reflect.Value
+			if et.Kind() == reflect.Map {
+				nm = reflect.MakeMap(et)
+			} else if mt := reflect.TypeOf(map[K]any{}); mt.AssignableTo(et) {
+				nm = reflect.MakeMap(mt)
+			} else {
+				return keysError(keys, i, fmt.Sprintf("cannot create map for %s", et))
+			}
+			current.SetMapIndex(kv, nm)
+			child = nm
+		} else if child.Kind() != reflect.Map {
+			return keysError(keys, i, fmt.Sprintf("expected map but found %s", child.Type()))
+		}
+		current = child
+	}
+	return nil
+}
+
+// DeleteIn deletes the value in nested maps - by successively looking up each of the supplied keys and deleting the
+// final key
+//
+// Any intermediate maps left empty by the deletion are also deleted (pruned) from their parents (the OptMap itself
+// is never pruned)
+//
+// returns true if the final key was found (and deleted)
+func (m OptMap[K, V]) DeleteIn(keys ...K) bool {
+	if len(keys) == 0 {
+		return false
+	}
+	chain := make([]reflect.Value, 0, len(keys))
+	kvs := make([]reflect.Value, 0, len(keys))
+	current := reflect.ValueOf(map[K]V(m))
+	for i, k := range keys {
+		kv, ok := nestedKey(current.Type(), k)
+		if !ok {
+			return false
+		}
+		chain = append(chain, current)
+		kvs = append(kvs, kv)
+		if i < len(keys)-1 {
+			if current = nestedMap(current.MapIndex(kv)); !current.IsValid() || current.Kind() != reflect.Map {
+				return false
+			}
+		}
+	}
+	last := len(keys) - 1
+	if !chain[last].MapIndex(kvs[last]).IsValid() {
+		return false
+	}
+	chain[last].SetMapIndex(kvs[last], reflect.Value{})
+	for i := last; i > 0 && chain[i].Len() == 0; i-- {
+		chain[i-1].SetMapIndex(kvs[i-1], reflect.Value{})
+	}
+	return true
+}
+
+func getIn[K any](m any, keys []K) (any, bool) {
+	current := reflect.ValueOf(m)
+	for _, k := range keys {
+		if current = nestedMap(current); !current.IsValid() || current.Kind() != reflect.Map {
+			return nil, false
+		}
+		kv, ok := nestedKey(current.Type(), k)
+		if !ok {
+			return nil, false
+		}
+		if current = current.MapIndex(kv); !current.IsValid() {
+			return nil, false
+		}
+	}
+	if !current.IsValid() {
+		return nil, false
+	}
+	return current.Interface(), true
+}
+
+// nestedMap dereferences interfaces and pointers - returning an invalid value if nil
+func nestedMap(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Interface || rv.Kind() == reflect.Ptr) {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	if rv.IsValid() && rv.Kind() == reflect.Map && rv.IsNil() {
+		return reflect.Value{}
+	}
+	return rv
+}
+
+// nestedKey returns the supplied key as a value that can be used as a key of the supplied map type
+func nestedKey(mt reflect.Type, key any) (reflect.Value, bool) {
+	kv := reflect.ValueOf(key)
+	if !kv.IsValid() {
+		if mt.Key().Kind() == reflect.Interface {
+			return reflect.Zero(mt.Key()), true
+		}
+		return kv, false
+	}
+	if kv.Type().AssignableTo(mt.Key()) {
+		return kv, true
+	} else if kv.Kind() == mt.Key().Kind() && kv.Type().ConvertibleTo(mt.Key()) {
+		return kv.Convert(mt.Key()), true
+	}
+	return kv, false
+}
+
+func keysError[K any](keys []K, i int, msg string) error {
+	return fmt.Errorf("%w: %v: %s", KeysConflict, keys[:i+1], msg)
+}
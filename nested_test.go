@@ -0,0 +1,195 @@
+package gopt
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetIn(t *testing.T) {
+	m := map[string]map[string]map[string]int{
+		"db": {
+			"primary": {
+				"port": 5432,
+			},
+			"empty": nil,
+		},
+	}
+	o := GetIn[string, int](m, "db", "primary", "port")
+	require.True(t, o.IsPresent())
+	require.Equal(t, 5432, o.OrElse(0))
+	require.False(t, GetIn[string, int](m, "db", "primary", "missing").IsPresent())
+	require.False(t, GetIn[string, int](m, "db", "missing", "port").IsPresent())
+	require.False(t, GetIn[string, int](m, "db", "empty", "port").IsPresent())
+	require.False(t, GetIn[string, int](m, "db", "primary", "port", "more").IsPresent())
+	require.False(t, GetIn[string, string](m, "db", "primary", "port").IsPresent())
+
+	inner := GetIn[string, map[string]int](m, "db", "primary")
+	require.True(t, inner.IsPresent())
+	require.Equal(t, 5432, inner.OrElse(nil)["port"])
+	require.True(t, GetIn[string, map[string]map[string]map[string]int](m).IsPresent())
+
+	require.False(t, GetIn[string, int](nil, "db").IsPresent())
+	var mp *map[string]int
+	require.False(t, GetIn[string, int](mp, "db").IsPresent())
+	pm := &map[string]int{"a": 1}
+	require.Equal(t, 1, GetIn[string, int](pm, "a").OrElse(0))
+}
+
+func TestGetIn_AnyMaps(t *testing.T) {
+	m := map[string]any{
+		"a": map[string]any{
+			"b": OptMap[string, any]{
+				"c": "value",
+				"n": nil,
+			},
+		},
+		"s": "not a map",
+	}
+	require.Equal(t, "value", GetIn[string, string](m, "a", "b", "c").OrElse(""))
+	require.False(t, GetIn[string, any](m, "a", "b", "n").IsPresent())
+	require.False(t, GetIn[string, any](m, "s", "x").IsPresent())
+}
+
+type nestedKeyType string
+
+func TestGetIn_TypedKeys(t *testing.T) {
+	type region int
+	m := map[region]map[nestedKeyType]bool{
+		1: {"enabled": true},
+	}
+	// different key types at each level (using any keys)...
+	require.True(t, GetIn[any, bool](m, region(1), nestedKeyType("enabled")).OrElse(false))
+	require.False(t, GetIn[any, bool](m, region(2), nestedKeyType("enabled")).IsPresent())
+	require.True(t, GetIn[any, bool](m, 1, "enabled").OrElse(false))
+	// convertible keys of the same kind...
+	require.True(t, GetIn[int, bool](map[region]bool{1: true}, 1).OrElse(false))
+	require.True(t, GetIn[string, bool](m[1], "enabled").OrElse(false))
+	// keys of a different kind...
+	require.False(t, GetIn[string, bool](m, "1").IsPresent())
+
+	am := map[any]int{nil: 1, "a": 2}
+	require.Equal(t, 1, GetIn[any, int](am, nil).OrElse(0))
+	require.Equal(t, 2, GetIn[any, int](am, "a").OrElse(0))
+	require.False(t, GetIn[any, int](map[string]int{}, nil).IsPresent())
+}
+
+func TestOptMap_GetIn(t *testing.T) {
+	m := OptMap[string, map[string]int]{
+		"a": {"b": 1},
+	}
+	o := m.GetIn("a", "b")
+	require.Equal(t, 1, o.OrElse(nil))
+	require.Equal(t, 1, MapTo[int](m.GetIn("a", "b")).OrElse(0))
+	require.False(t, m.GetIn("a", "c").IsPresent())
+}
+
+func TestOptMap_SetIn(t *testing.T) {
+	m := OptMap[string, map[string]map[string]int]{}
+	err := m.SetIn(5432, "db", "primary", "port")
+	require.NoError(t, err)
+	require.Equal(t, 5432, m["db"]["primary"]["port"])
+	err = m.SetIn(1, "db", "primary", "weight")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(m["db"]["primary"]))
+
+	err = m.SetIn("x", "db", "primary", "port")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, KeysConflict))
+	require.Equal(t, "keys conflict with existing value: [db primary port]: cannot assign string to int", err.Error())
+	err = m.SetIn(nil, "db", "primary", "port")
+	require.True(t, errors.Is(err, KeysConflict))
+	err = m.SetIn(nil, "db", "primary")
+	require.NoError(t, err)
+	require.Nil(t, m["db"]["primary"])
+	err = m.SetIn(1, "db", "primary", "port")
+	require.NoError(t, err)
+	require.Equal(t, 1, m["db"]["primary"]["port"])
+
+	err = m.SetIn(1)
+	require.Equal(t, NoKeys, err)
+	err = m.SetIn(1, "db", "primary", "port", "x")
+	require.True(t, errors.Is(err, KeysConflict))
+	require.Equal(t, "keys conflict with existing value: [db primary port]: expected map but found int", err.Error())
+	err = m.SetIn(1, "a", "b", "c", "d")
+	require.True(t, errors.Is(err, KeysConflict))
+	require.Equal(t, "keys conflict with existing value: [a b c]: cannot create map for int", err.Error())
+
+	var nm OptMap[string, any]
+	err = nm.SetIn(1, "a")
+	require.Equal(t, NilMap, err)
+	require.Nil(t, nm)
+}
+
+func TestOptMap_SetIn_AnyMaps(t *testing.T) {
+	m := OptMap[string, any]{
+		"s": "not a map",
+		"n": nil,
+	}
+	err := m.SetIn("value", "a", "b", "c")
+	require.NoError(t, err)
+	require.Equal(t, "value", GetIn[string, string](m, "a", "b", "c").OrElse(""))
+	_, ok := m["a"].(map[string]any)
+	require.True(t, ok)
+	err = m.SetIn(1, "n", "x")
+	require.NoError(t, err)
+	require.Equal(t, 1, GetIn[string, int](m, "n", "x").OrElse(0))
+	err = m.SetIn(1, "s", "x")
+	require.True(t, errors.Is(err, KeysConflict))
+	require.Equal(t, "keys conflict with existing value: [s]: expected map but found string", err.Error())
+
+	// existing (json style) maps are used...
+	jm := OptMap[string, any]{"obj": map[string]any{"x": 1}}
+	err = jm.SetIn(2, "obj", "y")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"x": 1, "y": 2}, jm["obj"])
+	err = jm.SetIn(nil, "obj", "x")
+	require.NoError(t, err)
+	require.Nil(t, jm["obj"].(map[string]any)["x"])
+
+	// intermediate map with a different key type...
+	km := OptMap[string, any]{"ints": map[int]any{}}
+	err = km.SetIn(1, "ints", "x")
+	require.True(t, errors.Is(err, KeysConflict))
+	require.Equal(t, "keys conflict with existing value: [ints x]: key type string cannot be used with map[int]interface {}", err.Error())
+
+	// value type that is not a map and cannot hold a map...
+	im := OptMap[string, int]{}
+	err = im.SetIn(1, "a", "b")
+	require.True(t, errors.Is(err, KeysConflict))
+	require.Equal(t, "keys conflict with existing value: [a]: cannot create map for int", err.Error())
+}
+
+func TestOptMap_DeleteIn(t *testing.T) {
+	m := OptMap[string, any]{}
+	require.NoError(t, m.SetIn(1, "a", "b", "c"))
+	require.NoError(t, m.SetIn(2, "a", "b", "d"))
+	require.NoError(t, m.SetIn(3, "a", "x"))
+	require.NoError(t, m.SetIn(4, "z"))
+
+	require.True(t, m.DeleteIn("a", "b", "c"))
+	require.False(t, m.DeleteIn("a", "b", "c"))
+	require.Equal(t, 2, GetIn[string, int](m, "a", "b", "d").OrElse(0))
+	require.True(t, m.DeleteIn("a", "b", "d"))
+	// "b" pruned but not "a" (still has "x")...
+	require.False(t, GetIn[string, any](m, "a", "b").IsPresent())
+	require.True(t, GetIn[string, any](m, "a").IsPresent())
+	require.True(t, m.DeleteIn("a", "x"))
+	_, ok := m["a"]
+	require.False(t, ok)
+	require.Equal(t, 1, len(m))
+
+	require.False(t, m.DeleteIn())
+	require.False(t, m.DeleteIn("z", "y"))
+	require.False(t, m.DeleteIn("missing", "y"))
+	require.True(t, m.DeleteIn("z"))
+	require.Equal(t, 0, len(m))
+	// root is never pruned...
+	require.NotNil(t, m)
+
+	tm := OptMap[string, map[string]int]{"a": {"b": 1}}
+	require.True(t, tm.DeleteIn("a", "b"))
+	require.Equal(t, 0, len(tm))
+	im := OptMap[int, map[string]int]{1: {"b": 1}}
+	require.False(t, im.DeleteIn(1, 2))
+}
@@ -2,8 +2,10 @@ package gopt
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 )
 
@@ -12,6 +14,24 @@ type String *string
 // NotPresent is the error returned from Optional.Get when the value is not present
 var NotPresent = errors.New("not present")
 
+// ValueNotAssignable is the error returned from Optional.SetAnyFrom when the value is not assignable to the optional's type
+var ValueNotAssignable = errors.New("value not assignable")
+
+// NilReceiverError is the error returned (or panicked) when a mutating method is called on a nil *Optional
+//
+// Read-only methods treat a nil *Optional as an unset, empty optional - but mutating methods cannot; Scan and
+// UnmarshalJSON return a *NilReceiverError, the other mutating methods (Clear, ClearFrom, OrElseSet, Set, SetAnyFrom,
+// SetFrom, SetPolicy, UnSet and WasSetElseSet) panic with a *NilReceiverError
+type NilReceiverError struct {
+	// Method is the name of the method called on the nil *Optional
+	Method string
+}
+
+// Error implements error
+func (e *NilReceiverError) Error() string {
+	return e.Method + " called on nil optional"
+}
+
 // Of creates a new optional with the supplied value
 func Of[T any](value T) *Optional[T] {
 	return &Optional[T]{
@@ -76,38 +96,46 @@ func ConvertTo[F any, T any](o *Optional[F], f func(v F) *Optional[T]) *Optional
 	return Empty[T]()
 }
 
-func isPresent(v any) bool {
-	vo := reflect.ValueOf(v)
-	switch vk := vo.Kind(); vk {
-	case reflect.Ptr:
-		vk = vo.Elem().Kind()
-		if vk == reflect.Invalid {
-			return false
-		}
-	case reflect.Map, reflect.Slice, reflect.Interface:
-		return !vo.IsNil()
-	}
-	return v != nil
-}
-
 type Optional[T any] struct {
 	present bool
 	value   T
 	set     bool
+	source  Source
+	policy  PresencePolicy
 }
 
-// AsEmpty returns a new empty optional of the same type
+// AsEmpty returns a new empty optional of the same type (and with the same presence policy)
 func (o *Optional[T]) AsEmpty() *Optional[T] {
 	return &Optional[T]{
 		present: false,
+		policy:  o.presencePolicy(),
 	}
 }
 
 // Clear clears the optional
 //
-// Clearing sets the present to false, the set flag to false and the value to an empty value
+// Clearing sets the present to false, the set flag to false, the source to SourceNone and the value to an empty value
+//
+// Panics with a *NilReceiverError if called on a nil optional
 func (o *Optional[T]) Clear() *Optional[T] {
-	o.clear(false)
+	o.mustNotBeNil("Clear")
+	o.clear(SourceNone)
+	return o
+}
+
+// ClearFrom clears the value (so that the optional is not present) but marks the optional as set, recording the supplied source (see Source)
+//
+// This is useful where a source explicitly supplies an empty value (e.g. a JSON null or an empty environment variable)
+//
+// If the supplied source is SourceNone, SourceExplicit is recorded
+//
+// Panics with a *NilReceiverError if called on a nil optional
+func (o *Optional[T]) ClearFrom(source Source) *Optional[T] {
+	o.mustNotBeNil("ClearFrom")
+	if source == SourceNone {
+		source = SourceExplicit
+	}
+	o.clear(source)
 	return o
 }
 
@@ -115,20 +143,22 @@ func (o *Optional[T]) Clear() *Optional[T] {
 //
 // Otherwise returns an empty optional
 func (o *Optional[T]) Filter(f func(v T) bool) *Optional[T] {
-	if o.present && f != nil && f(o.value) {
+	if o.IsPresent() && f != nil && f(o.value) {
 		return &Optional[T]{
 			present: true,
 			value:   o.value,
+			policy:  o.policy,
 		}
 	}
 	return &Optional[T]{
 		present: false,
+		policy:  o.presencePolicy(),
 	}
 }
 
 // Get returns the value and an error if the value is not present
 func (o *Optional[T]) Get() (T, error) {
-	if !o.present {
+	if !o.IsPresent() {
 		return o.emptyValue(), NotPresent
 	}
 	return o.value, nil
@@ -136,7 +166,7 @@ func (o *Optional[T]) Get() (T, error) {
 
 // Default returns the value if present, otherwise returns the provided default value
 func (o *Optional[T]) Default(v T) T {
-	if !o.present {
+	if !o.IsPresent() {
 		return v
 	}
 	return o.value
@@ -149,7 +179,7 @@ func (o *Optional[T]) Default(v T) T {
 // GetOk is similar to Get, except that rather than returning an error if not present it
 // returns a boolean
 func (o *Optional[T]) GetOk() (T, bool) {
-	if o.present {
+	if o.IsPresent() {
 		return o.value, true
 	}
 	return o.emptyValue(), false
@@ -159,7 +189,7 @@ func (o *Optional[T]) GetOk() (T, bool) {
 //
 // otherwise the other value is returned
 func (o *Optional[T]) IfElse(condition bool, other T) T {
-	if condition && o.present {
+	if condition && o.IsPresent() {
 		return o.value
 	}
 	return other
@@ -167,7 +197,7 @@ func (o *Optional[T]) IfElse(condition bool, other T) T {
 
 // IfPresent if the value is present, calls the supplied function with the value, otherwise does nothing
 func (o *Optional[T]) IfPresent(f func(v T)) *Optional[T] {
-	if o.present && f != nil {
+	if o.IsPresent() && f != nil {
 		f(o.value)
 	}
 	return o
@@ -175,9 +205,9 @@ func (o *Optional[T]) IfPresent(f func(v T)) *Optional[T] {
 
 // IfPresentOtherwise if the value is present, calls the supplied function with the value, otherwise calls the other function
 func (o *Optional[T]) IfPresentOtherwise(f func(v T), other func()) *Optional[T] {
-	if o.present && f != nil {
+	if o.IsPresent() && f != nil {
 		f(o.value)
-	} else if !o.present && other != nil {
+	} else if !o.IsPresent() && other != nil {
 		other()
 	}
 	return o
@@ -189,9 +219,9 @@ func (o *Optional[T]) IfPresentOtherwise(f func(v T), other func()) *Optional[T]
 //
 // otherwise, does nothing
 func (o *Optional[T]) IfSet(f func(v T), notPresent func()) *Optional[T] {
-	if o.set && o.present && f != nil {
+	if o.WasSet() && o.IsPresent() && f != nil {
 		f(o.value)
-	} else if o.set && !o.present && notPresent != nil {
+	} else if o.WasSet() && !o.IsPresent() && notPresent != nil {
 		notPresent()
 	}
 	return o
@@ -203,11 +233,11 @@ func (o *Optional[T]) IfSet(f func(v T), notPresent func()) *Optional[T] {
 //
 // otherwise, calls the other func
 func (o *Optional[T]) IfSetOtherwise(f func(v T), notPresent func(), other func()) *Optional[T] {
-	if o.set && o.present && f != nil {
+	if o.WasSet() && o.IsPresent() && f != nil {
 		f(o.value)
-	} else if o.set && !o.present && notPresent != nil {
+	} else if o.WasSet() && !o.IsPresent() && notPresent != nil {
 		notPresent()
-	} else if !o.set && !o.present && other != nil {
+	} else if !o.WasSet() && !o.IsPresent() && other != nil {
 		other()
 	}
 	return o
@@ -215,7 +245,7 @@ func (o *Optional[T]) IfSetOtherwise(f func(v T), notPresent func(), other func(
 
 // IsPresent returns true if the value is present, otherwise false
 func (o *Optional[T]) IsPresent() bool {
-	return o.present
+	return o != nil && o.present
 }
 
 // Get if the value is present and the result of calling the supplied mapping function returns non-nil, returns
@@ -223,9 +253,9 @@ func (o *Optional[T]) IsPresent() bool {
 //
 // Otherwise returns an empty optional
 func (o *Optional[T]) Map(f func(v T) any) *Optional[any] {
-	if o.present && f != nil {
+	if o.IsPresent() && f != nil {
 		v := f(o.value)
-		if isPresent(v) {
+		if o.isPresent(v) {
 			return Of(v)
 		}
 	}
@@ -239,8 +269,11 @@ func (o *Optional[T]) Map(f func(v T) any) *Optional[any] {
 // If the value is present, returns the marshalled data for the value
 //
 // Otherwise, returns the marshalled data for null
+//
+// MarshalJSON has a pointer receiver (so that it is safe to call on a nil optional) - encoding/json only calls it for
+// addressable optionals, so optionals held by value in maps or interfaces should be held as *Optional
 func (o *Optional[T]) MarshalJSON() ([]byte, error) {
-	if !o.present {
+	if !o.IsPresent() {
 		return []byte("null"), nil
 	}
 	return json.Marshal(o.value)
@@ -248,7 +281,7 @@ func (o *Optional[T]) MarshalJSON() ([]byte, error) {
 
 // OrElse returns the value if present, otherwise returns other
 func (o *Optional[T]) OrElse(other T) T {
-	if o.present {
+	if o.IsPresent() {
 		return o.value
 	}
 	return other
@@ -258,7 +291,7 @@ func (o *Optional[T]) OrElse(other T) T {
 //
 // if the supplied error is nil and the value is not present, a NotPresent error is returned
 func (o *Optional[T]) OrElseError(err error) error {
-	if !o.present {
+	if !o.IsPresent() {
 		if err == nil {
 			return NotPresent
 		}
@@ -271,7 +304,7 @@ func (o *Optional[T]) OrElseError(err error) error {
 //
 // if the supplied function is nil and the value is not present, returns a default empty value
 func (o *Optional[T]) OrElseGet(f func() T) T {
-	if o.present {
+	if o.IsPresent() {
 		return o.value
 	} else if f != nil {
 		return f()
@@ -281,67 +314,157 @@ func (o *Optional[T]) OrElseGet(f func() T) T {
 
 // OrElsePanic if the value is not present, panics with the supplied value, otherwise does nothing
 func (o *Optional[T]) OrElsePanic(v any) *Optional[T] {
-	if !o.present {
+	if !o.IsPresent() {
 		panic(v)
 	}
 	return o
 }
 
 // OrElseSet if the value is not present it is set to the supplied value
+//
+// Panics with a *NilReceiverError if called on a nil optional
 func (o *Optional[T]) OrElseSet(v T) *Optional[T] {
+	o.mustNotBeNil("OrElseSet")
 	if !o.present {
-		if isPresent(v) {
-			o.present = true
-			o.value = v
-		} else {
-			o.present = false
-			o.value = o.emptyValue()
-		}
-		o.set = true
+		o.setValue(v, SourceDefault)
 	}
 	return o
 }
 
+// Policy returns the presence policy used by the optional
+func (o *Optional[T]) Policy() PresencePolicy {
+	return presencePolicy(o.presencePolicy())
+}
+
 // Scan implements sql.Scan
+//
+// Returns a *NilReceiverError if called on a nil optional
 func (o *Optional[T]) Scan(value interface{}) error {
+	if o == nil {
+		return &NilReceiverError{Method: "Scan"}
+	}
 	if value == nil {
-		o.clear(true)
+		o.clear(SourceSQL)
 	} else if av, ok := value.(T); ok {
-		o.present = true
-		o.value = av
-		o.set = true
+		o.setValue(av, SourceSQL)
 	} else if ok, err := o.callScannable(value); ok {
 		return err
 	} else if bd, ok := value.([]byte); ok {
 		var uv T
 		if o.isString() {
-			o.present = true
-			o.set = true
-			o.value = any(string(bd)).(T)
+			o.setValue(any(string(bd)).(T), SourceSQL)
 		} else {
 			if unErr := json.Unmarshal(bd, &uv); unErr == nil {
-				if isPresent(uv) {
-					o.present = true
-					o.value = uv
-				} else {
-					o.present = false
-					o.value = o.emptyValue()
-				}
-				o.set = true
+				o.setValue(uv, SourceSQL)
 			} else {
-				o.clear(true)
+				o.clear(SourceSQL)
 				return unErr
 			}
 		}
 	} else {
-		o.clear(true)
+		o.clear(SourceSQL)
+	}
+	return nil
+}
+
+// Set sets the value explicitly (with a source of SourceExplicit)
+//
+// Panics with a *NilReceiverError if called on a nil optional
+func (o *Optional[T]) Set(v T) *Optional[T] {
+	o.mustNotBeNil("Set")
+	o.setValue(v, SourceExplicit)
+	return o
+}
+
+// SetAnyFrom sets the value (which must be assignable to T), recording the supplied source (see Source) - it allows
+// optionals to be set without knowing T (e.g. when setting optional struct fields using reflection, see ValueType)
+//
+// If the supplied value is nil, the optional is cleared (see ClearFrom).  If the value is not assignable to T, the
+// optional is unchanged and an error wrapping ValueNotAssignable is returned
+//
+// If the supplied source is SourceNone, SourceExplicit is recorded
+//
+// Panics with a *NilReceiverError if called on a nil optional
+func (o *Optional[T]) SetAnyFrom(source Source, v any) error {
+	o.mustNotBeNil("SetAnyFrom")
+	if source == SourceNone {
+		source = SourceExplicit
+	}
+	if v == nil {
+		o.clear(source)
+	} else if tv, ok := v.(T); ok {
+		o.setValue(tv, source)
+	} else {
+		return fmt.Errorf("%w: %T to %s", ValueNotAssignable, v, o.ValueType())
 	}
 	return nil
 }
 
-// UnSet clears the set flag (see WasSet)
+// SetFrom sets the value, recording the supplied source (see Source)
+//
+// If the supplied source is SourceNone, SourceExplicit is recorded
+//
+// Panics with a *NilReceiverError if called on a nil optional
+func (o *Optional[T]) SetFrom(source Source, v T) *Optional[T] {
+	o.mustNotBeNil("SetFrom")
+	if source == SourceNone {
+		source = SourceExplicit
+	}
+	o.setValue(v, source)
+	return o
+}
+
+// SetPolicy sets the presence policy used by the optional and re-evaluates whether the current value is present
+//
+// The policy is used by subsequent setting operations (UnmarshalJSON, Scan, OrElseSet etc.) - for example, to treat
+// blank strings in JSON as not present:
+//   type Request struct {
+//     Name gopt.Optional[string]
+//   }
+//   req := &Request{}
+//   req.Name.SetPolicy(gopt.BlankStringIsEmpty)
+//   _ = json.Unmarshal(data, req)
+//
+// If the supplied policy is nil, the NilOnly policy is used
+//
+// Panics with a *NilReceiverError if called on a nil optional
+func (o *Optional[T]) SetPolicy(policy PresencePolicy) *Optional[T] {
+	o.mustNotBeNil("SetPolicy")
+	o.policy = policy
+	if o.present && !o.isPresent(o.value) {
+		o.present = false
+		o.value = o.emptyValue()
+	}
+	return o
+}
+
+// Source returns the source of the last setting operation (see WasSet)
+//
+// Returns SourceNone if the value was not set
+func (o *Optional[T]) Source() Source {
+	if o == nil {
+		return SourceNone
+	}
+	return o.source
+}
+
+// String implements fmt.Stringer
+//
+// If the value is present, returns the formatted value - otherwise returns "<not present>"
+func (o *Optional[T]) String() string {
+	if !o.IsPresent() {
+		return "<not present>"
+	}
+	return fmt.Sprintf("%v", o.value)
+}
+
+// UnSet clears the set flag (see WasSet) and the source (see Source)
+//
+// Panics with a *NilReceiverError if called on a nil optional
 func (o *Optional[T]) UnSet() *Optional[T] {
+	o.mustNotBeNil("UnSet")
 	o.set = false
+	o.source = SourceNone
 	return o
 }
 
@@ -351,16 +474,19 @@ func (o *Optional[T]) UnSet() *Optional[T] {
 //
 // Otherwise, unmarshal the data as the value and sets the optional to present (unless the result of
 // unmarshalling the value returns an error - in which case the present is set to false)
+//
+// Returns a *NilReceiverError if called on a nil optional
 func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if o == nil {
+		return &NilReceiverError{Method: "UnmarshalJSON"}
+	}
 	if len(data) == 4 && data[0] == 'n' && data[1] == 'u' && data[2] == 'l' && data[3] == 'l' {
-		o.present = false
-		o.value = o.emptyValue()
-		o.set = true
+		o.clear(SourceJSON)
 		return nil
 	}
 	v := o.value
 	err := json.Unmarshal(data, &v)
-	if err == nil && isPresent(v) {
+	if err == nil && o.isPresent(v) {
 		o.present = true
 		o.value = v
 	} else {
@@ -368,21 +494,46 @@ func (o *Optional[T]) UnmarshalJSON(data []byte) error {
 		o.value = o.emptyValue()
 	}
 	o.set = true
+	o.source = SourceJSON
 	return err
 }
 
+// Value implements driver.Valuer
+//
+// If the value is not present, returns nil
+//
+// Otherwise, if the value implements driver.Valuer returns the result of calling its Value method; if the value
+// can be converted to a driver.Value that is returned - otherwise the value is marshalled as JSON (see Scan)
+func (o *Optional[T]) Value() (driver.Value, error) {
+	if !o.IsPresent() {
+		return nil, nil
+	}
+	if vv, ok := any(o.value).(driver.Valuer); ok {
+		return vv.Value()
+	} else if dv, err := driver.DefaultParameterConverter.ConvertValue(o.value); err == nil {
+		return dv, nil
+	}
+	return json.Marshal(o.value)
+}
+
+// ValueType returns the type of the optional's value (i.e. the reflect.Type of T)
+func (o *Optional[T]) ValueType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
 // WasSet returns true if the last setting operation set the value, otherwise false
 //
-// Setting operations are UnmarshalJSON, Scan and OrElseSet
+// Setting operations are UnmarshalJSON, Scan, OrElseSet, WasSetElseSet, Set, SetAnyFrom and SetFrom (see Source for which
+// setting operation set the value)
 //
 // Use UnSet() to clear this flag alone
 func (o *Optional[T]) WasSet() bool {
-	return o.set
+	return o != nil && o.set
 }
 
 // WasSetElse returns the value if present and set, otherwise returns other
 func (o *Optional[T]) WasSetElse(other T) T {
-	if o.present && o.set {
+	if o.IsPresent() && o.WasSet() {
 		return o.value
 	}
 	return other
@@ -392,7 +543,7 @@ func (o *Optional[T]) WasSetElse(other T) T {
 //
 // if the supplied error is nil and the value is not present and set, a NotPresentError is returned
 func (o *Optional[T]) WasSetElseError(err error) error {
-	if o.present && o.set {
+	if o.IsPresent() && o.WasSet() {
 		return nil
 	} else if err == nil {
 		return NotPresent
@@ -404,7 +555,7 @@ func (o *Optional[T]) WasSetElseError(err error) error {
 //
 // if the supplied function is nil and the value is not present and set, returns a default empty value
 func (o *Optional[T]) WasSetElseGet(f func() T) T {
-	if o.present && o.set {
+	if o.IsPresent() && o.WasSet() {
 		return o.value
 	} else if f != nil {
 		return f()
@@ -414,35 +565,61 @@ func (o *Optional[T]) WasSetElseGet(f func() T) T {
 
 // WasSetElsePanic if the value is not present and set, panics with the supplied value, otherwise does nothing
 func (o *Optional[T]) WasSetElsePanic(v any) *Optional[T] {
-	if o.present && o.set {
+	if o.IsPresent() && o.WasSet() {
 		return o
 	}
 	panic(v)
 }
 
 // WasSetElseSet if the value is not present and set it is set to the value supplied
+//
+// Panics with a *NilReceiverError if called on a nil optional
 func (o *Optional[T]) WasSetElseSet(v T) *Optional[T] {
+	o.mustNotBeNil("WasSetElseSet")
 	if !o.present || !o.set {
-		if isPresent(v) {
-			o.present = true
-			o.value = v
-		} else {
-			o.present = false
-			o.value = o.emptyValue()
-		}
-		o.set = true
+		o.setValue(v, SourceDefault)
 	}
 	return o
 }
 
+func (o *Optional[T]) mustNotBeNil(method string) {
+	if o == nil {
+		panic(&NilReceiverError{Method: method})
+	}
+}
+
 func (o *Optional[T]) emptyValue() T {
 	return (Optional[T]{}).value
 }
 
-func (o *Optional[T]) clear(set bool) {
+func (o *Optional[T]) setValue(v T, source Source) {
+	if o.isPresent(v) {
+		o.present = true
+		o.value = v
+	} else {
+		o.present = false
+		o.value = o.emptyValue()
+	}
+	o.set = true
+	o.source = source
+}
+
+func (o *Optional[T]) isPresent(v any) bool {
+	return presencePolicy(o.presencePolicy()).IsPresent(v)
+}
+
+func (o *Optional[T]) presencePolicy() PresencePolicy {
+	if o == nil {
+		return nil
+	}
+	return o.policy
+}
+
+func (o *Optional[T]) clear(source Source) {
 	o.value = o.emptyValue()
 	o.present = false
-	o.set = set
+	o.set = source != SourceNone
+	o.source = source
 }
 
 func (o *Optional[T]) isString() bool {
@@ -465,10 +642,11 @@ func (o *Optional[T]) callScannable(value interface{}) (bool, error) {
 		err := sanv.Scan(value)
 		if err == nil {
 			o.value = anv.(T)
-			o.present = isPresent(anv)
+			o.present = o.isPresent(anv)
 			o.set = true
+			o.source = SourceSQL
 		} else {
-			o.clear(true)
+			o.clear(SourceSQL)
 		}
 		return true, err
 	}
@@ -1,9 +1,12 @@
 package gopt
 
 import (
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/require"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -426,6 +429,81 @@ func TestOptional_MarshalUnmarshalJSON(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestOptional_MarshalJSON_Containers(t *testing.T) {
+	m := map[string]*Optional[int]{
+		"foo": Of(1),
+		"bar": Empty[int](),
+		"baz": nil,
+	}
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, `{"bar":null,"baz":null,"foo":1}`, string(data))
+	m2 := map[string]*Optional[int]{}
+	err = json.Unmarshal(data, &m2)
+	require.NoError(t, err)
+	require.True(t, m2["foo"].present)
+	require.Equal(t, 1, m2["foo"].value)
+
+	sl := []Optional[string]{*Of("a"), *Empty[string](), *Of("")}
+	data, err = json.Marshal(sl)
+	require.NoError(t, err)
+	require.Equal(t, `["a",null,""]`, string(data))
+	sl2 := make([]Optional[string], 0)
+	err = json.Unmarshal(data, &sl2)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(sl2))
+	require.True(t, sl2[0].present)
+	require.False(t, sl2[1].present)
+	require.True(t, sl2[2].present)
+
+	arr := [2]Optional[float64]{*Of(1.5), {}}
+	data, err = json.Marshal(&arr)
+	require.NoError(t, err)
+	require.Equal(t, `[1.5,null]`, string(data))
+
+	var a any = Of("abc")
+	data, err = json.Marshal(a)
+	require.NoError(t, err)
+	require.Equal(t, `"abc"`, string(data))
+	data, err = json.Marshal(map[string]any{"foo": Of(1), "bar": Empty[string]()})
+	require.NoError(t, err)
+	require.Equal(t, `{"bar":null,"foo":1}`, string(data))
+
+	type aStruct struct {
+		Foo Optional[string] `json:"foo"`
+		Bar Optional[int]    `json:"bar"`
+	}
+	data, err = json.Marshal(&aStruct{Foo: *Of("aaa")})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":"aaa","bar":null}`, string(data))
+	as := aStruct{}
+	err = json.Unmarshal(data, &as)
+	require.NoError(t, err)
+	require.True(t, as.Foo.IsPresent())
+	require.False(t, as.Bar.IsPresent())
+	require.True(t, as.Bar.WasSet())
+
+	ms := map[string]*aStruct{"x": {Bar: *Of(2)}}
+	data, err = json.Marshal(ms)
+	require.NoError(t, err)
+	require.Equal(t, `{"x":{"foo":null,"bar":2}}`, string(data))
+	ms2 := map[string]*aStruct{}
+	err = json.Unmarshal(data, &ms2)
+	require.NoError(t, err)
+	require.Equal(t, 2, ms2["x"].Bar.value)
+
+	nested := map[string][]Optional[int]{"foo": {*Of(1), {}}}
+	data, err = json.Marshal(nested)
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":[1,null]}`, string(data))
+	nested2 := map[string][]Optional[int]{}
+	err = json.Unmarshal(data, &nested2)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(nested2["foo"]))
+	require.True(t, nested2["foo"][0].present)
+	require.False(t, nested2["foo"][1].present)
+}
+
 func TestOptional_OrElse(t *testing.T) {
 	o := Empty[string]()
 	v := o.OrElse("bbb")
@@ -499,6 +577,38 @@ func TestOptional_OrElseSet(t *testing.T) {
 	require.False(t, o.IsPresent())
 }
 
+func TestOptional_Policy(t *testing.T) {
+	o := Of("")
+	require.Equal(t, NilOnly, o.Policy())
+	o = OfWith(BlankStringIsEmpty, "")
+	require.Equal(t, BlankStringIsEmpty, o.Policy())
+}
+
+func TestOptional_SetPolicy(t *testing.T) {
+	o := Of(" ")
+	require.True(t, o.IsPresent())
+	o2 := o.SetPolicy(BlankStringIsEmpty)
+	require.Same(t, o, o2)
+	require.False(t, o.IsPresent())
+	require.Equal(t, "", o.OrElse(""))
+
+	o.SetPolicy(nil)
+	require.Equal(t, NilOnly, o.Policy())
+	o.OrElseSet("")
+	require.True(t, o.IsPresent())
+
+	oi := Of(0).SetPolicy(ZeroIsEmpty)
+	require.False(t, oi.IsPresent())
+	oi.WasSetElseSet(0)
+	require.False(t, oi.IsPresent())
+	require.True(t, oi.WasSet())
+	f := oi.OrElseSet(1).Filter(func(v int) bool {
+		return true
+	})
+	require.True(t, f.IsPresent())
+	require.Equal(t, ZeroIsEmpty, f.Policy())
+}
+
 func TestOptional_Scan(t *testing.T) {
 	var o Optional[string]
 	err := o.Scan("str")
@@ -575,6 +685,139 @@ func TestOptional_Scan(t *testing.T) {
 	require.Equal(t, "abc", o6.OrElse(""))
 }
 
+func TestOptional_Set(t *testing.T) {
+	o := Empty[string]()
+	require.Equal(t, SourceNone, o.Source())
+	o2 := o.Set("abc")
+	require.Equal(t, o, o2)
+	require.True(t, o.IsPresent())
+	require.True(t, o.WasSet())
+	require.Equal(t, SourceExplicit, o.Source())
+	require.Equal(t, "abc", o.OrElse(""))
+
+	om := Of(map[string]any{}).Set(nil)
+	require.False(t, om.IsPresent())
+	require.True(t, om.WasSet())
+}
+
+func TestOptional_SetFrom(t *testing.T) {
+	o := Empty[string]().SetFrom(SourceJSON, "abc")
+	require.True(t, o.IsPresent())
+	require.Equal(t, SourceJSON, o.Source())
+	o.SetFrom(SourceNone, "xyz")
+	require.Equal(t, SourceExplicit, o.Source())
+	require.Equal(t, "xyz", o.OrElse(""))
+}
+
+func TestOptional_Source(t *testing.T) {
+	o := Of("abc")
+	require.Equal(t, SourceNone, o.Source())
+	err := o.UnmarshalJSON([]byte(`"xyz"`))
+	require.NoError(t, err)
+	require.Equal(t, SourceJSON, o.Source())
+	err = o.UnmarshalJSON([]byte(`null`))
+	require.NoError(t, err)
+	require.Equal(t, SourceJSON, o.Source())
+	err = o.Scan("xyz")
+	require.NoError(t, err)
+	require.Equal(t, SourceSQL, o.Source())
+	err = o.Scan(nil)
+	require.NoError(t, err)
+	require.Equal(t, SourceSQL, o.Source())
+	o.OrElseSet("def")
+	require.Equal(t, SourceDefault, o.Source())
+	o.Set("explicit")
+	require.Equal(t, SourceExplicit, o.Source())
+	o.WasSetElseSet("def")
+	require.Equal(t, SourceExplicit, o.Source())
+	o.UnSet()
+	require.Equal(t, SourceNone, o.Source())
+	o.WasSetElseSet("def")
+	require.Equal(t, SourceDefault, o.Source())
+	o.Clear()
+	require.Equal(t, SourceNone, o.Source())
+
+	o4 := OfNillable[*scannable](nil)
+	err = o4.Scan("abc")
+	require.NoError(t, err)
+	require.Equal(t, SourceSQL, o4.Source())
+}
+
+func TestOptional_SetAnyFrom(t *testing.T) {
+	o := Empty[int]()
+	require.Equal(t, reflect.TypeOf(0), o.ValueType())
+	err := o.SetAnyFrom(SourceJSON, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, o.OrElse(0))
+	require.Equal(t, SourceJSON, o.Source())
+
+	err = o.SetAnyFrom(SourceJSON, "1")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ValueNotAssignable))
+	require.Equal(t, "value not assignable: string to int", err.Error())
+	require.Equal(t, 1, o.OrElse(0))
+
+	err = o.SetAnyFrom(SourceNone, nil)
+	require.NoError(t, err)
+	require.True(t, o.WasSet())
+	require.False(t, o.IsPresent())
+	require.Equal(t, SourceExplicit, o.Source())
+
+	var no *Optional[any]
+	require.Equal(t, reflect.TypeOf((*any)(nil)).Elem(), no.ValueType())
+	require.Panics(t, func() {
+		_ = no.SetAnyFrom(SourceNone, 1)
+	})
+}
+
+func TestOptional_String(t *testing.T) {
+	require.Equal(t, "abc", Of("abc").String())
+	require.Equal(t, "1", Of(1).String())
+	require.Equal(t, "<not present>", Empty[int]().String())
+	require.Equal(t, "1.5", fmt.Sprintf("%s", Of(1.5)))
+	require.Equal(t, "[a b]", fmt.Sprintf("%v", map[string]*Optional[[]string]{"foo": Of([]string{"a", "b"})}["foo"]))
+}
+
+func TestOptional_Value(t *testing.T) {
+	v, err := Empty[string]().Value()
+	require.NoError(t, err)
+	require.Nil(t, v)
+	v, err = Of("abc").Value()
+	require.NoError(t, err)
+	require.Equal(t, "abc", v)
+	v, err = Of(1).Value()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), v)
+	v, err = Of(valuer{value: "from valuer"}).Value()
+	require.NoError(t, err)
+	require.Equal(t, "from valuer", v)
+	v, err = Of(map[string]any{"foo": "bar"}).Value()
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"foo":"bar"}`), v)
+
+	var dv driver.Valuer = Of(1.5)
+	v, err = dv.Value()
+	require.NoError(t, err)
+	require.Equal(t, 1.5, v)
+	v, err = driver.DefaultParameterConverter.ConvertValue(Of(true))
+	require.NoError(t, err)
+	require.Equal(t, true, v)
+
+	o := Empty[map[string]any]()
+	v, _ = Of(map[string]any{"foo": "bar"}).Value()
+	err = o.Scan(v)
+	require.NoError(t, err)
+	require.Equal(t, "bar", o.OrElse(nil)["foo"])
+}
+
+type valuer struct {
+	value string
+}
+
+func (v valuer) Value() (driver.Value, error) {
+	return v.value, nil
+}
+
 func TestOptional_UnSet(t *testing.T) {
 	o := Of("abc")
 	require.True(t, o.IsPresent())
@@ -753,3 +996,159 @@ func TestEmpties(t *testing.T) {
 	require.False(t, EmptyByte().IsPresent())
 	require.False(t, EmptyRune().IsPresent())
 }
+
+func TestOptional_NilReceiver(t *testing.T) {
+	var o *Optional[string]
+	require.False(t, o.IsPresent())
+	require.False(t, o.WasSet())
+	require.Equal(t, SourceNone, o.Source())
+	require.Equal(t, NilOnly, o.Policy())
+
+	oe := o.AsEmpty()
+	require.NotNil(t, oe)
+	require.False(t, oe.IsPresent())
+	of := o.Filter(func(v string) bool {
+		return true
+	})
+	require.NotNil(t, of)
+	require.False(t, of.IsPresent())
+
+	v, err := o.Get()
+	require.Equal(t, NotPresent, err)
+	require.Equal(t, "", v)
+	require.Equal(t, "def", o.Default("def"))
+	v, ok := o.GetOk()
+	require.False(t, ok)
+	require.Equal(t, "", v)
+	require.Equal(t, "other", o.IfElse(true, "other"))
+
+	called := false
+	otherCalled := false
+	notPresentCalled := false
+	f := func(v string) {
+		called = true
+	}
+	other := func() {
+		otherCalled = true
+	}
+	notPresent := func() {
+		notPresentCalled = true
+	}
+	require.Nil(t, o.IfPresent(f))
+	require.False(t, called)
+	require.Nil(t, o.IfPresentOtherwise(f, other))
+	require.False(t, called)
+	require.True(t, otherCalled)
+	otherCalled = false
+	require.Nil(t, o.IfSet(f, notPresent))
+	require.False(t, called)
+	require.False(t, notPresentCalled)
+	require.Nil(t, o.IfSetOtherwise(f, notPresent, other))
+	require.False(t, called)
+	require.False(t, notPresentCalled)
+	require.True(t, otherCalled)
+
+	om := o.Map(func(v string) any {
+		return v
+	})
+	require.False(t, om.IsPresent())
+
+	data, err := o.MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, "null", string(data))
+	require.Equal(t, "<not present>", o.String())
+	ov, err := o.Value()
+	require.NoError(t, err)
+	require.Nil(t, ov)
+	data, err = json.Marshal(o)
+	require.NoError(t, err)
+	require.Equal(t, "null", string(data))
+	require.Equal(t, "<not present>", fmt.Sprint(o))
+	dv, err := driver.DefaultParameterConverter.ConvertValue(o)
+	require.NoError(t, err)
+	require.Nil(t, dv)
+	type withPtr struct {
+		Foo *Optional[string] `json:"foo"`
+	}
+	data, err = json.Marshal(&withPtr{})
+	require.NoError(t, err)
+	require.Equal(t, `{"foo":null}`, string(data))
+
+	require.Equal(t, "other", o.OrElse("other"))
+	require.Equal(t, NotPresent, o.OrElseError(nil))
+	require.Equal(t, "other", o.OrElseGet(func() string {
+		return "other"
+	}))
+	require.Equal(t, "", o.OrElseGet(nil))
+	require.PanicsWithValue(t, "whoops", func() {
+		o.OrElsePanic("whoops")
+	})
+
+	require.Equal(t, "other", o.WasSetElse("other"))
+	require.Equal(t, NotPresent, o.WasSetElseError(nil))
+	require.Equal(t, "other", o.WasSetElseGet(func() string {
+		return "other"
+	}))
+	require.PanicsWithValue(t, "whoops", func() {
+		o.WasSetElsePanic("whoops")
+	})
+
+	err = o.Scan("abc")
+	require.Error(t, err)
+	nre, ok := err.(*NilReceiverError)
+	require.True(t, ok)
+	require.Equal(t, "Scan", nre.Method)
+	require.Equal(t, "Scan called on nil optional", err.Error())
+	err = o.UnmarshalJSON([]byte(`"abc"`))
+	require.Error(t, err)
+	nre, ok = err.(*NilReceiverError)
+	require.True(t, ok)
+	require.Equal(t, "UnmarshalJSON", nre.Method)
+
+	requireNilReceiverPanic(t, "Clear", func() {
+		o.Clear()
+	})
+	requireNilReceiverPanic(t, "OrElseSet", func() {
+		o.OrElseSet("abc")
+	})
+	requireNilReceiverPanic(t, "Set", func() {
+		o.Set("abc")
+	})
+	requireNilReceiverPanic(t, "SetFrom", func() {
+		o.SetFrom(SourceJSON, "abc")
+	})
+	requireNilReceiverPanic(t, "UnSet", func() {
+		o.UnSet()
+	})
+	requireNilReceiverPanic(t, "WasSetElseSet", func() {
+		o.WasSetElseSet("abc")
+	})
+	requireNilReceiverPanic(t, "SetPolicy", func() {
+		o.SetPolicy(ZeroIsEmpty)
+	})
+}
+
+func requireNilReceiverPanic(t *testing.T, method string, f func()) {
+	defer func() {
+		r := recover()
+		require.NotNil(t, r)
+		nre, ok := r.(*NilReceiverError)
+		require.True(t, ok)
+		require.Equal(t, method, nre.Method)
+	}()
+	f()
+}
+
+func TestOptional_ClearFrom(t *testing.T) {
+	o := Of("abc")
+	o.ClearFrom(SourceJSON)
+	require.False(t, o.IsPresent())
+	require.True(t, o.WasSet())
+	require.Equal(t, SourceJSON, o.Source())
+	o.ClearFrom(SourceNone)
+	require.Equal(t, SourceExplicit, o.Source())
+	var nilO *Optional[string]
+	require.Panics(t, func() {
+		nilO.ClearFrom(SourceJSON)
+	})
+}
@@ -0,0 +1,566 @@
+package gopt
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// OrderedMapNotObject is the error returned by OrderedOptMap.UnmarshalJSON when the JSON is not an object
+var OrderedMapNotObject = errors.New("ordered map json must be an object")
+
+// OrderedOptMap is a map, with the same optional/functional methods as OptMap, that preserves insertion order
+//
+// Iteration (Range, Entries, Keys and Values) and JSON marshalling are in insertion order - setting the value of an
+// existing key does not change its position (use MoveToFront or MoveToBack to re-position keys).  Unmarshalling JSON
+// preserves the document order - and, where values are optionals (e.g. OrderedOptMap[string, Optional[int]]), null
+// entries are preserved in place
+//
+// The zero value is an empty map ready to use.  OrderedOptMap is not safe for concurrent use
+type OrderedOptMap[K comparable, V any] struct {
+	entries map[K]*orderedEntry[K, V]
+	root    *orderedEntry[K, V]
+}
+
+type orderedEntry[K comparable, V any] struct {
+	key   K
+	value V
+	prev  *orderedEntry[K, V]
+	next  *orderedEntry[K, V]
+}
+
+// NewOrderedOptMap creates a new, empty, OrderedOptMap
+func NewOrderedOptMap[K comparable, V any]() *OrderedOptMap[K, V] {
+	result := &OrderedOptMap[K, V]{}
+	result.init()
+	return result
+}
+
+// OrderedOptMapOf creates a new OrderedOptMap containing the supplied entries (in order)
+func OrderedOptMapOf[K comparable, V any](entries ...Entry[K, V]) *OrderedOptMap[K, V] {
+	result := NewOrderedOptMap[K, V]()
+	for _, e := range entries {
+		result.Put(e.Key, e.Value)
+	}
+	return result
+}
+
+// Get returns an optional of the value in the map
+//
+// If the value is not present (or is nil) then an empty optional is returned
+func (m *OrderedOptMap[K, V]) Get(key K) *Optional[V] {
+	if v, ok := m.load(key); ok && isPresent(v) {
+		return Of(v)
+	}
+	return Empty[V]()
+}
+
+// GetWith returns an optional of the value in the map - where presence is determined by the supplied presence policy
+//
+// If the value is not present (according to the policy) then an empty optional is returned
+func (m *OrderedOptMap[K, V]) GetWith(policy PresencePolicy, key K) *Optional[V] {
+	if v, ok := m.load(key); ok {
+		return OfWith(policy, v)
+	}
+	return Empty[V]().SetPolicy(policy)
+}
+
+// Default returns the value (if present and non-nil) otherwise returns the default value
+func (m *OrderedOptMap[K, V]) Default(key K, def V) V {
+	return m.Get(key).Default(def)
+}
+
+// GetOrDefault returns the value (if present and non-nil) otherwise returns the default value (the same as Default)
+func (m *OrderedOptMap[K, V]) GetOrDefault(key K, def V) V {
+	return m.Default(key, def)
+}
+
+// IfPresent if the key is present (and the value is non-nil) calls the supplied function with the key and value
+//
+// otherwise does nothing
+func (m *OrderedOptMap[K, V]) IfPresent(key K, f func(key K, v V)) *OrderedOptMap[K, V] {
+	if f != nil {
+		if v, ok := m.load(key); ok && isPresent(v) {
+			f(key, v)
+		}
+	}
+	return m
+}
+
+// IfPresentOtherwise if the key is present (and the value is non-nil) calls the supplied function with the key and value
+//
+// otherwise calls the other function with they key
+func (m *OrderedOptMap[K, V]) IfPresentOtherwise(key K, f func(key K, v V), other func(key K)) *OrderedOptMap[K, V] {
+	if v, ok := m.load(key); ok && isPresent(v) {
+		if f != nil {
+			f(key, v)
+		}
+	} else if other != nil {
+		other(key)
+	}
+	return m
+}
+
+// ComputeIfAbsent if the specified key is not present (or the value is nil) sets the value according to the specified function
+//
+// returns either the existing value or the newly set value
+func (m *OrderedOptMap[K, V]) ComputeIfAbsent(key K, f func(key K) V) V {
+	if v, ok := m.load(key); ok && isPresent(v) {
+		return v
+	}
+	var rv V
+	if f != nil {
+		rv = f(key)
+		if isPresent(rv) {
+			m.Put(key, rv)
+		}
+	}
+	return rv
+}
+
+// ComputeIfPresent if the specified key is present (and the value is non-nil) attempts to compute a new mapping using the supplied function
+//
+// If the supplied function is called but returns a nil value, the key is deleted
+func (m *OrderedOptMap[K, V]) ComputeIfPresent(key K, f func(key K, v V) V) V {
+	var rv V
+	if v, ok := m.load(key); ok && isPresent(v) && f != nil {
+		rv = f(key, v)
+		if isPresent(rv) {
+			m.Put(key, rv)
+		} else {
+			m.Delete(key)
+		}
+	}
+	return rv
+}
+
+// Compute computes a new mapping for the specified key using the supplied function (see OptMap.Compute)
+func (m *OrderedOptMap[K, V]) Compute(key K, f func(key K, current *Optional[V]) *Optional[V]) *Optional[V] {
+	if f == nil {
+		return m.Get(key)
+	}
+	rv := f(key, m.Get(key))
+	if rv.IsPresent() {
+		m.Put(key, rv.value)
+		return Of(rv.value)
+	}
+	m.Delete(key)
+	return Empty[V]()
+}
+
+// Merge merges the specified value into the mapping for the specified key (see OptMap.Merge)
+func (m *OrderedOptMap[K, V]) Merge(key K, v V, f func(old V, new V) V) V {
+	rv := v
+	if ov, ok := m.load(key); ok && isPresent(ov) && f != nil {
+		rv = f(ov, v)
+	}
+	if isPresent(rv) {
+		m.Put(key, rv)
+	} else {
+		m.Delete(key)
+	}
+	return rv
+}
+
+// PutIfAbsent if the specified key if absent (not present or nil-value) it is set to the specified value
+//
+// returns true if the value was set
+func (m *OrderedOptMap[K, V]) PutIfAbsent(key K, v V) bool {
+	if ov, ok := m.load(key); !ok || !isPresent(ov) {
+		m.Put(key, v)
+		return true
+	}
+	return false
+}
+
+// ReplaceIfPresent is the specified key is present (and the value is non-nil) it is replaced with the specified value
+//
+// returns true if the value was replaced (if the specified replacement value is nil, the key is deleted)
+func (m *OrderedOptMap[K, V]) ReplaceIfPresent(key K, v V) bool {
+	if isPresent(v) {
+		if ov, ok := m.load(key); ok && isPresent(ov) {
+			m.Put(key, v)
+			return true
+		}
+		return false
+	}
+	return m.Delete(key)
+}
+
+// RemoveIf removes all entries (with non-nil values) for which the supplied predicate returns true
+//
+// returns the number of entries removed
+func (m *OrderedOptMap[K, V]) RemoveIf(pred func(key K, v V) bool) int {
+	count := 0
+	if pred != nil && m.root != nil {
+		for e := m.root.next; e != m.root; {
+			next := e.next
+			if isPresent(e.value) && pred(e.key, e.value) {
+				m.remove(e)
+				count++
+			}
+			e = next
+		}
+	}
+	return count
+}
+
+// Filter returns a new map containing the entries (with non-nil values) for which the supplied predicate returns true (in order)
+//
+// If the supplied predicate is nil, all entries with non-nil values are returned
+func (m *OrderedOptMap[K, V]) Filter(pred func(key K, v V) bool) *OrderedOptMap[K, V] {
+	result := NewOrderedOptMap[K, V]()
+	m.Range(func(key K, v V) bool {
+		if isPresent(v) && (pred == nil || pred(key, v)) {
+			result.Put(key, v)
+		}
+		return true
+	})
+	return result
+}
+
+// Keys returns the keys of the entries with non-nil values (in order)
+func (m *OrderedOptMap[K, V]) Keys() []K {
+	result := make([]K, 0, m.Len())
+	m.Range(func(key K, v V) bool {
+		if isPresent(v) {
+			result = append(result, key)
+		}
+		return true
+	})
+	return result
+}
+
+// Values returns the non-nil values (in order)
+func (m *OrderedOptMap[K, V]) Values() []V {
+	result := make([]V, 0, m.Len())
+	m.Range(func(key K, v V) bool {
+		if isPresent(v) {
+			result = append(result, v)
+		}
+		return true
+	})
+	return result
+}
+
+// Union returns a new map containing the entries (with non-nil values) of both this and the other map (see OptMap.Union)
+//
+// The entries of this map come first (in order), followed by the entries of the other map whose keys are not in this map (in order)
+func (m *OrderedOptMap[K, V]) Union(other *OrderedOptMap[K, V], resolve func(key K, v V, otherV V) V) *OrderedOptMap[K, V] {
+	result := m.Filter(nil)
+	other.Range(func(key K, ov V) bool {
+		if !isPresent(ov) {
+			return true
+		}
+		if v, ok := result.load(key); ok && resolve != nil {
+			if rv := resolve(key, v, ov); isPresent(rv) {
+				result.Put(key, rv)
+			} else {
+				result.Delete(key)
+			}
+		} else {
+			result.Put(key, ov)
+		}
+		return true
+	})
+	return result
+}
+
+// Intersect returns a new map containing the entries whose keys have non-nil values in both this and the other map
+// (see OptMap.Intersect) - in the order of this map
+func (m *OrderedOptMap[K, V]) Intersect(other *OrderedOptMap[K, V], resolve func(key K, v V, otherV V) V) *OrderedOptMap[K, V] {
+	result := NewOrderedOptMap[K, V]()
+	m.Range(func(key K, v V) bool {
+		if !isPresent(v) {
+			return true
+		}
+		if ov, ok := other.load(key); ok && isPresent(ov) {
+			if resolve != nil {
+				v = resolve(key, v, ov)
+			}
+			if isPresent(v) {
+				result.Put(key, v)
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// Put sets the value for the specified key
+//
+// If the key is already in the map, its position is unchanged - otherwise it is added at the back
+func (m *OrderedOptMap[K, V]) Put(key K, v V) {
+	m.init()
+	if e, ok := m.entries[key]; ok {
+		e.value = v
+		return
+	}
+	e := &orderedEntry[K, V]{key: key, value: v}
+	m.entries[key] = e
+	m.insertBefore(e, m.root)
+}
+
+// Delete deletes the specified key
+//
+// returns true if the key was in the map
+func (m *OrderedOptMap[K, V]) Delete(key K) bool {
+	if e, ok := m.entries[key]; ok {
+		m.remove(e)
+		return true
+	}
+	return false
+}
+
+// Has returns true if the key is in the map (even if its value is nil)
+func (m *OrderedOptMap[K, V]) Has(key K) bool {
+	_, ok := m.load(key)
+	return ok
+}
+
+// Len returns the number of entries in the map
+func (m *OrderedOptMap[K, V]) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.entries)
+}
+
+// Clear removes all entries from the map
+func (m *OrderedOptMap[K, V]) Clear() {
+	m.entries = nil
+	m.root = nil
+	m.init()
+}
+
+// MoveToFront moves the specified key to the front of the map
+//
+// returns false if the key is not in the map
+func (m *OrderedOptMap[K, V]) MoveToFront(key K) bool {
+	if e, ok := m.entries[key]; ok {
+		m.unlink(e)
+		m.insertBefore(e, m.root.next)
+		return true
+	}
+	return false
+}
+
+// MoveToBack moves the specified key to the back of the map
+//
+// returns false if the key is not in the map
+func (m *OrderedOptMap[K, V]) MoveToBack(key K) bool {
+	if e, ok := m.entries[key]; ok {
+		m.unlink(e)
+		m.insertBefore(e, m.root)
+		return true
+	}
+	return false
+}
+
+// Range calls the supplied function for each entry (including entries with nil values), in order - if the function
+// returns false, iteration stops
+//
+// The function may modify the map - Range visits the entries that were in the map when Range was called, in that
+// order: entries deleted (or cleared) before they are visited are not visited, entries added during iteration are not
+// visited, entries moved during iteration (see MoveToFront and MoveToBack) are visited once in their original position
+// and entries whose value is replaced before they are visited are visited with the new value
+func (m *OrderedOptMap[K, V]) Range(f func(key K, v V) bool) {
+	if m == nil || m.root == nil {
+		return
+	}
+	snapshot := make([]*orderedEntry[K, V], 0, len(m.entries))
+	for e := m.root.next; e != m.root; e = e.next {
+		snapshot = append(snapshot, e)
+	}
+	for _, e := range snapshot {
+		if m.entries[e.key] != e {
+			continue
+		}
+		if !f(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Entries returns all entries (including entries with nil values), in order
+func (m *OrderedOptMap[K, V]) Entries() []Entry[K, V] {
+	result := make([]Entry[K, V], 0, m.Len())
+	m.Range(func(key K, v V) bool {
+		result = append(result, Entry[K, V]{Key: key, Value: v})
+		return true
+	})
+	return result
+}
+
+// OptMap returns an (unordered) OptMap copy of the map
+func (m *OrderedOptMap[K, V]) OptMap() OptMap[K, V] {
+	result := make(OptMap[K, V], m.Len())
+	m.Range(func(key K, v V) bool {
+		result[key] = v
+		return true
+	})
+	return result
+}
+
+// MarshalJSON implements json.Marshaler - the JSON object properties are in map order
+//
+// Keys must be strings, integers or implement encoding.TextMarshaler
+func (m OrderedOptMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	var err error
+	m.Range(func(key K, v V) bool {
+		var ks string
+		if ks, err = orderedKeyString(key); err != nil {
+			return false
+		}
+		var kb, vb []byte
+		if kb, err = json.Marshal(ks); err != nil {
+			return false
+		}
+		if vb, err = json.Marshal(&v); err != nil {
+			return false
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(vb)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler - the entries are in the order of the JSON object properties (any existing
+// entries are removed)
+//
+// Keys must be strings, integers or implement encoding.TextUnmarshaler.  If a property appears more than once, the last
+// value is used (at the position of the first)
+func (m *OrderedOptMap[K, V]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if d, ok := t.(json.Delim); !ok || d != '{' {
+		return OrderedMapNotObject
+	}
+	result := NewOrderedOptMap[K, V]()
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		ks, _ := t.(string)
+		key, err := orderedKeyParse[K](ks)
+		if err != nil {
+			return err
+		}
+		var v V
+		if e, ok := result.entries[key]; ok {
+			v = e.value
+		}
+		if err = dec.Decode(&v); err != nil {
+			return err
+		}
+		result.Put(key, v)
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	m.entries = result.entries
+	m.root = result.root
+	return nil
+}
+
+func orderedKeyString(key any) (string, error) {
+	if tm, ok := key.(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	rv := reflect.ValueOf(key)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported ordered map key type %T", key)
+}
+
+func orderedKeyParse[K comparable](s string) (key K, err error) {
+	if tu, ok := any(&key).(encoding.TextUnmarshaler); ok {
+		err = tu.UnmarshalText([]byte(s))
+		return
+	}
+	rv := reflect.ValueOf(&key).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, rv.Type().Bits()); err == nil {
+			rv.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, rv.Type().Bits()); err == nil {
+			rv.SetUint(u)
+		}
+	default:
+		err = fmt.Errorf("unsupported ordered map key type %T", key)
+	}
+	return
+}
+
+func (m *OrderedOptMap[K, V]) init() {
+	if m.root == nil {
+		m.root = &orderedEntry[K, V]{}
+		m.root.next = m.root
+		m.root.prev = m.root
+		m.entries = map[K]*orderedEntry[K, V]{}
+	}
+}
+
+func (m *OrderedOptMap[K, V]) load(key K) (V, bool) {
+	if m != nil {
+		if e, ok := m.entries[key]; ok {
+			return e.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+func (m *OrderedOptMap[K, V]) remove(e *orderedEntry[K, V]) {
+	m.unlink(e)
+	delete(m.entries, e.key)
+}
+
+func (m *OrderedOptMap[K, V]) unlink(e *orderedEntry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev = nil
+	e.next = nil
+}
+
+func (m *OrderedOptMap[K, V]) insertBefore(e *orderedEntry[K, V], at *orderedEntry[K, V]) {
+	e.prev = at.prev
+	e.next = at
+	at.prev.next = e
+	at.prev = e
+}
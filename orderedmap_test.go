@@ -0,0 +1,396 @@
+package gopt
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestOrderedOptMap(t *testing.T) {
+	m := NewOrderedOptMap[string, any]()
+	m.Put("c", 3)
+	m.Put("a", 1)
+	m.Put("nil", nil)
+	m.Put("b", 2)
+	m.Put("a", 10)
+	require.Equal(t, 4, m.Len())
+	require.Equal(t, []string{"c", "a", "b"}, m.Keys())
+	require.Equal(t, []any{3, 10, 2}, m.Values())
+	require.Equal(t, []Entry[string, any]{{"c", 3}, {"a", 10}, {"nil", nil}, {"b", 2}}, m.Entries())
+
+	require.True(t, m.Get("a").IsPresent())
+	require.False(t, m.Get("nil").IsPresent())
+	require.False(t, m.Get("x").IsPresent())
+	require.True(t, m.Has("nil"))
+	require.False(t, m.Has("x"))
+	require.Equal(t, 10, m.Default("a", 0))
+	require.Equal(t, 0, m.GetOrDefault("nil", 0))
+
+	require.True(t, m.Delete("c"))
+	require.False(t, m.Delete("c"))
+	require.Equal(t, []string{"a", "b"}, m.Keys())
+	require.Equal(t, OptMap[string, any]{"a": 10, "nil": nil, "b": 2}, m.OptMap())
+
+	m.Clear()
+	require.Equal(t, 0, m.Len())
+	m.Put("z", 26)
+	require.Equal(t, []string{"z"}, m.Keys())
+}
+
+func TestOrderedOptMap_ZeroValue(t *testing.T) {
+	var m OrderedOptMap[string, int]
+	require.False(t, m.Get("a").IsPresent())
+	require.Equal(t, 0, m.Len())
+	require.False(t, m.Delete("a"))
+	require.False(t, m.MoveToFront("a"))
+	require.Equal(t, 0, len(m.Entries()))
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, `{}`, string(data))
+	require.True(t, m.PutIfAbsent("a", 1))
+	require.Equal(t, 1, m.Get("a").OrElse(0))
+
+	var nm *OrderedOptMap[string, int]
+	require.Equal(t, 0, nm.Len())
+	require.False(t, nm.Has("a"))
+	require.False(t, nm.Get("a").IsPresent())
+}
+
+func TestOrderedOptMap_GetWith(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, string]{"a", ""}, Entry[string, string]{"b", "x"})
+	require.True(t, m.Get("a").IsPresent())
+	require.False(t, m.GetWith(ZeroIsEmpty, "a").IsPresent())
+	require.True(t, m.GetWith(ZeroIsEmpty, "b").IsPresent())
+	require.False(t, m.GetWith(ZeroIsEmpty, "c").IsPresent())
+}
+
+func TestOrderedOptMap_IfPresent(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, any]{"a", 1}, Entry[string, any]{"nil", nil})
+	called := 0
+	m.IfPresent("a", func(key string, v any) {
+		called++
+	}).IfPresent("nil", func(key string, v any) {
+		t.Fatal("should not be called")
+	})
+	require.Equal(t, 1, called)
+	m.IfPresentOtherwise("nil", func(key string, v any) {
+		t.Fatal("should not be called")
+	}, func(key string) {
+		called++
+	})
+	require.Equal(t, 2, called)
+}
+
+func TestOrderedOptMap_Compute(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, any]{"a", 1}, Entry[string, any]{"nil", nil}, Entry[string, any]{"b", 2})
+	require.Equal(t, 1, m.ComputeIfAbsent("a", func(key string) any {
+		t.Fatal("should not be called")
+		return nil
+	}))
+	require.Equal(t, 5, m.ComputeIfAbsent("nil", func(key string) any {
+		return 5
+	}))
+	require.Equal(t, 3, m.ComputeIfAbsent("c", func(key string) any {
+		return 3
+	}))
+	require.Equal(t, []string{"a", "nil", "b", "c"}, m.Keys())
+
+	require.Equal(t, 20, m.ComputeIfPresent("b", func(key string, v any) any {
+		return v.(int) * 10
+	}))
+	require.Nil(t, m.ComputeIfPresent("a", func(key string, v any) any {
+		return nil
+	}))
+	require.Equal(t, []string{"nil", "b", "c"}, m.Keys())
+
+	o := m.Compute("c", func(key string, current *Optional[any]) *Optional[any] {
+		return Of[any](current.OrElse(0).(int) + 1)
+	})
+	require.Equal(t, 4, o.OrElse(nil))
+	o = m.Compute("nil", func(key string, current *Optional[any]) *Optional[any] {
+		return Empty[any]()
+	})
+	require.False(t, o.IsPresent())
+	require.Equal(t, []string{"b", "c"}, m.Keys())
+	require.Equal(t, 4, m.Compute("c", nil).OrElse(nil))
+
+	add := func(old any, new any) any {
+		return old.(int) + new.(int)
+	}
+	require.Equal(t, 21, m.Merge("b", 1, add))
+	require.Equal(t, 1, m.Merge("d", 1, add))
+	require.Nil(t, m.Merge("b", nil, nil))
+	require.Equal(t, []string{"c", "d"}, m.Keys())
+}
+
+func TestOrderedOptMap_PutIfAbsentReplaceIfPresent(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, any]{"a", 1}, Entry[string, any]{"nil", nil})
+	require.False(t, m.PutIfAbsent("a", 2))
+	require.True(t, m.PutIfAbsent("nil", 2))
+	require.True(t, m.PutIfAbsent("b", 3))
+	require.Equal(t, []any{1, 2, 3}, m.Values())
+
+	require.True(t, m.ReplaceIfPresent("a", 10))
+	require.False(t, m.ReplaceIfPresent("x", 10))
+	require.True(t, m.ReplaceIfPresent("a", nil))
+	require.False(t, m.ReplaceIfPresent("a", nil))
+	require.Equal(t, []string{"nil", "b"}, m.Keys())
+}
+
+func TestOrderedOptMap_RemoveIfFilter(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, any]{"a", 1}, Entry[string, any]{"nil", nil}, Entry[string, any]{"b", 2}, Entry[string, any]{"c", 3})
+	f := m.Filter(func(key string, v any) bool {
+		return v.(int) > 1
+	})
+	require.Equal(t, []string{"b", "c"}, f.Keys())
+	require.Equal(t, 3, m.Filter(nil).Len())
+	require.Equal(t, 2, m.RemoveIf(func(key string, v any) bool {
+		return v.(int) != 2
+	}))
+	require.Equal(t, []Entry[string, any]{{"nil", nil}, {"b", 2}}, m.Entries())
+	require.Equal(t, 0, m.RemoveIf(nil))
+}
+
+func TestOrderedOptMap_UnionIntersect(t *testing.T) {
+	m1 := OrderedOptMapOf(Entry[string, any]{"a", 1}, Entry[string, any]{"b", 2}, Entry[string, any]{"n", nil})
+	m2 := OrderedOptMapOf(Entry[string, any]{"c", 30}, Entry[string, any]{"b", 20}, Entry[string, any]{"n", 10})
+	u := m1.Union(m2, nil)
+	require.Equal(t, []Entry[string, any]{{"a", 1}, {"b", 20}, {"c", 30}, {"n", 10}}, u.Entries())
+	u = m1.Union(m2, func(key string, v any, otherV any) any {
+		return nil
+	})
+	require.Equal(t, []string{"a", "c", "n"}, u.Keys())
+	require.Equal(t, []string{"a", "b"}, m1.Union(nil, nil).Keys())
+
+	i := m1.Intersect(m2, nil)
+	require.Equal(t, []Entry[string, any]{{"b", 2}}, i.Entries())
+	i = m1.Intersect(m2, func(key string, v any, otherV any) any {
+		return v.(int) + otherV.(int)
+	})
+	require.Equal(t, []Entry[string, any]{{"b", 22}}, i.Entries())
+	require.Equal(t, 0, m1.Intersect(nil, nil).Len())
+}
+
+func TestOrderedOptMap_Move(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, int]{"a", 1}, Entry[string, int]{"b", 2}, Entry[string, int]{"c", 3})
+	require.True(t, m.MoveToFront("c"))
+	require.Equal(t, []string{"c", "a", "b"}, m.Keys())
+	require.True(t, m.MoveToBack("c"))
+	require.Equal(t, []string{"a", "b", "c"}, m.Keys())
+	require.True(t, m.MoveToBack("a"))
+	require.True(t, m.MoveToFront("b"))
+	require.Equal(t, []string{"b", "c", "a"}, m.Keys())
+	require.False(t, m.MoveToFront("x"))
+	require.False(t, m.MoveToBack("x"))
+}
+
+func TestOrderedOptMap_Range(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, int]{"a", 1}, Entry[string, int]{"b", 2}, Entry[string, int]{"c", 3})
+	keys := make([]string, 0)
+	m.Range(func(key string, v int) bool {
+		keys = append(keys, key)
+		m.Delete(key)
+		return key != "b"
+	})
+	require.Equal(t, []string{"a", "b"}, keys)
+	require.Equal(t, []string{"c"}, m.Keys())
+}
+
+func TestOrderedOptMap_Range_Modifications(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, int]{"a", 1}, Entry[string, int]{"b", 2}, Entry[string, int]{"c", 3}, Entry[string, int]{"d", 4})
+	keys := make([]string, 0)
+	m.Range(func(key string, v int) bool {
+		keys = append(keys, key)
+		if key == "a" {
+			// delete the entries ahead (and then the current), add a new entry...
+			m.Delete("b")
+			m.Delete("c")
+			m.Delete("a")
+			m.Put("e", 5)
+		}
+		return true
+	})
+	require.Equal(t, []string{"a", "d"}, keys)
+	require.Equal(t, []string{"d", "e"}, m.Keys())
+
+	// deleted then re-added before visit is not visited, replaced value is visited with the new value...
+	m = OrderedOptMapOf(Entry[string, int]{"a", 1}, Entry[string, int]{"b", 2}, Entry[string, int]{"c", 3})
+	values := make([]int, 0)
+	m.Range(func(key string, v int) bool {
+		values = append(values, v)
+		if key == "a" {
+			m.Delete("b")
+			m.Put("b", 20)
+			m.Put("c", 30)
+		}
+		return true
+	})
+	require.Equal(t, []int{1, 30}, values)
+	require.Equal(t, []string{"a", "c", "b"}, m.Keys())
+
+	keys = keys[:0]
+	m.Range(func(key string, v int) bool {
+		keys = append(keys, key)
+		m.Clear()
+		m.Put("x", 1)
+		return true
+	})
+	require.Equal(t, []string{"a"}, keys)
+	require.Equal(t, []string{"x"}, m.Keys())
+}
+
+func TestOrderedOptMap_Range_Moves(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, int]{"a", 1}, Entry[string, int]{"b", 2}, Entry[string, int]{"c", 3})
+	keys := make([]string, 0)
+	m.Range(func(key string, v int) bool {
+		keys = append(keys, key)
+		m.MoveToBack(key)
+		return true
+	})
+	require.Equal(t, []string{"a", "b", "c"}, keys)
+	require.Equal(t, []string{"a", "b", "c"}, m.Keys())
+
+	keys = keys[:0]
+	m.Range(func(key string, v int) bool {
+		keys = append(keys, key)
+		m.MoveToFront(key)
+		return true
+	})
+	require.Equal(t, []string{"a", "b", "c"}, keys)
+	require.Equal(t, []string{"c", "b", "a"}, m.Keys())
+
+	keys = keys[:0]
+	m.Range(func(key string, v int) bool {
+		keys = append(keys, key)
+		if key == "c" {
+			m.MoveToBack("a")
+		}
+		return true
+	})
+	require.Equal(t, []string{"c", "b", "a"}, keys)
+}
+
+func TestOrderedOptMap_JSON(t *testing.T) {
+	const doc = `{"zebra":1,"apple":null,"mango":3}`
+	m := NewOrderedOptMap[string, Optional[int]]()
+	err := json.Unmarshal([]byte(doc), m)
+	require.NoError(t, err)
+	require.Equal(t, 3, m.Len())
+	es := m.Entries()
+	require.Equal(t, "zebra", es[0].Key)
+	require.Equal(t, "apple", es[1].Key)
+	require.True(t, es[1].Value.WasSet())
+	require.False(t, es[1].Value.IsPresent())
+	require.Equal(t, "mango", es[2].Key)
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, doc, string(data))
+
+	// in a struct...
+	type payload struct {
+		Fields OrderedOptMap[string, any]  `json:"fields"`
+		Ptr    *OrderedOptMap[string, any] `json:"ptr"`
+	}
+	const pdoc = `{"fields":{"b":{"y":2,"x":1},"a":[1,2],"c":null},"ptr":{"2":"two","1":"one"}}`
+	p := &payload{}
+	err = json.Unmarshal([]byte(pdoc), p)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "a"}, p.Fields.Keys())
+	require.True(t, p.Fields.Has("c"))
+	require.Equal(t, []string{"2", "1"}, p.Ptr.Keys())
+	data, err = json.Marshal(p)
+	require.NoError(t, err)
+	// nested plain maps are not ordered (marshalled with sorted keys)...
+	require.Equal(t, strings.Replace(pdoc, `{"y":2,"x":1}`, `{"x":1,"y":2}`, 1), string(data))
+}
+
+func TestOrderedOptMap_JSON_NestedOrdered(t *testing.T) {
+	const doc = `{"outer":{"z":1,"a":2},"empty":{}}`
+	m := NewOrderedOptMap[string, *OrderedOptMap[string, int]]()
+	err := json.Unmarshal([]byte(doc), m)
+	require.NoError(t, err)
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, doc, string(data))
+}
+
+func TestOrderedOptMap_JSON_Keys(t *testing.T) {
+	const doc = `{"3":"c","-1":"minus","10":"ten"}`
+	m := NewOrderedOptMap[int, string]()
+	err := json.Unmarshal([]byte(doc), m)
+	require.NoError(t, err)
+	require.Equal(t, []int{3, -1, 10}, m.Keys())
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, doc, string(data))
+
+	um := NewOrderedOptMap[uint8, string]()
+	err = json.Unmarshal([]byte(`{"1":"a","2":"b"}`), um)
+	require.NoError(t, err)
+	require.Equal(t, []uint8{1, 2}, um.Keys())
+	err = json.Unmarshal([]byte(`{"300":"a"}`), um)
+	require.Error(t, err)
+
+	fm := NewOrderedOptMap[float64, string]()
+	fm.Put(1.5, "x")
+	_, err = json.Marshal(fm)
+	require.Error(t, err)
+	err = json.Unmarshal([]byte(`{"1.5":"a"}`), fm)
+	require.Error(t, err)
+}
+
+type testTextKey struct {
+	a, b string
+}
+
+func (k testTextKey) MarshalText() ([]byte, error) {
+	return []byte(k.a + ":" + k.b), nil
+}
+
+func (k *testTextKey) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), ":", 2)
+	if len(parts) != 2 {
+		return errors.New("invalid key")
+	}
+	k.a, k.b = parts[0], parts[1]
+	return nil
+}
+
+func TestOrderedOptMap_JSON_TextKeys(t *testing.T) {
+	const doc = `{"x:1":true,"a:2":false}`
+	m := NewOrderedOptMap[testTextKey, bool]()
+	err := json.Unmarshal([]byte(doc), m)
+	require.NoError(t, err)
+	require.Equal(t, []testTextKey{{"x", "1"}, {"a", "2"}}, m.Keys())
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, doc, string(data))
+	err = json.Unmarshal([]byte(`{"bad":true}`), m)
+	require.Error(t, err)
+}
+
+func TestOrderedOptMap_UnmarshalJSON(t *testing.T) {
+	m := OrderedOptMapOf(Entry[string, int]{"old", 1})
+	err := json.Unmarshal([]byte(`{"a":1,"b":2,"a":3}`), m)
+	require.NoError(t, err)
+	require.Equal(t, []Entry[string, int]{{"a", 3}, {"b", 2}}, m.Entries())
+
+	err = json.Unmarshal([]byte(`null`), m)
+	require.NoError(t, err)
+	require.Equal(t, 2, m.Len())
+
+	err = json.Unmarshal([]byte(`[1,2]`), m)
+	require.Error(t, err)
+	err = m.UnmarshalJSON([]byte(`[1,2]`))
+	require.Equal(t, OrderedMapNotObject, err)
+	err = json.Unmarshal([]byte(`{"a":"not an int"}`), m)
+	require.Error(t, err)
+	require.Equal(t, 2, m.Len())
+	err = m.UnmarshalJSON([]byte(`{"a":1`))
+	require.Error(t, err)
+	err = m.UnmarshalJSON([]byte(``))
+	require.Error(t, err)
+}
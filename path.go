@@ -0,0 +1,688 @@
+package gopt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathSyntaxError is the error returned when a path (as used by ExtractJsonPath) is invalid
+type PathSyntaxError struct {
+	// Path is the path
+	Path string
+	// Position is the (byte) position in the path at which the error was detected
+	Position int
+	// Message describes the error
+	Message string
+}
+
+// Error implements error
+func (e *PathSyntaxError) Error() string {
+	return fmt.Sprintf("invalid path %q at position %d: %s", e.Path, e.Position, e.Message)
+}
+
+// Path is a compiled path (as used by ExtractJsonPath) - a Path is immutable and is safe for concurrent use
+//
+// Use CompilePath or MustCompilePath to create a Path and ExtractPath to extract values using it
+type Path struct {
+	path  string
+	steps []pathStep
+}
+
+// CompilePath parses the supplied path (see ExtractJsonPath for the path grammar) into a reusable Path
+//
+// Returns a *PathSyntaxError if the path is invalid
+func CompilePath(path string) (*Path, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Path{
+		path:  path,
+		steps: steps,
+	}, nil
+}
+
+// MustCompilePath is the same as CompilePath, except that it panics if the path is invalid
+func MustCompilePath(path string) *Path {
+	p, err := CompilePath(path)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the original path string
+func (p *Path) String() string {
+	return p.path
+}
+
+// ExtractPath extracts an optional value, of the specified type, using a compiled Path
+//
+// The root would normally be a map[string]any (but may be any value, e.g. a []any where the path starts with an index)
+//
+// The result args are the same as for ExtractJsonPathFrom
+func ExtractPath[T any](p *Path, root any, converters ...Converter[T]) (*Optional[T], []bool) {
+	return extractPathSteps[T](root, p.steps, false, converters...)
+}
+
+// pathStep is a single step of a parsed path - either a property key or an array index
+type pathStep struct {
+	key     string
+	index   int
+	indexed bool
+}
+
+func (s pathStep) child(v any) (any, bool) {
+	if s.indexed {
+		if sv, ok := v.([]any); ok {
+			idx := s.index
+			if idx < 0 {
+				idx = len(sv) + idx
+			}
+			if idx >= 0 && idx < len(sv) {
+				return sv[idx], true
+			}
+		}
+	} else if mv, ok := v.(map[string]any); ok {
+		cv, ok := mv[s.key]
+		return cv, ok
+	}
+	return nil, false
+}
+
+func (s pathStep) String() string {
+	if s.indexed {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return s.key
+}
+
+// legacyPathSteps splits a path (that is not valid according to the path grammar - see parsePath) as earlier versions
+// of ExtractJsonPath did - on "." with each part optionally suffixed by an index (e.g. "foo[0]")
+func legacyPathSteps(path string) []pathStep {
+	parts := strings.Split(path, ".")
+	steps := make([]pathStep, 0, len(parts))
+	for _, part := range parts {
+		if oat := strings.LastIndexByte(part, '['); oat != -1 && strings.HasSuffix(part, "]") {
+			if idx, err := strconv.ParseInt(part[oat+1:len(part)-1], 10, 64); err == nil {
+				steps = append(steps, pathStep{key: part[:oat]}, pathStep{index: int(idx), indexed: true})
+				continue
+			}
+		}
+		steps = append(steps, pathStep{key: part})
+	}
+	return steps
+}
+
+// parsePath parses a path into steps
+//
+// The path grammar is:
+//
+//	path     = segment *("." segment)
+//	segment  = name *bracket / 1*bracket
+//	name     = 1*(unescaped / "\" any-char)         ; unescaped is any char except ".", "[" and "\"
+//	bracket  = "[" (int / quoted) "]"
+//	quoted   = DQUOTE *(char / "\" any-char) DQUOTE / "'" *(char / "\" any-char) "'"
+func parsePath(path string) ([]pathStep, error) {
+	p := &pathParser{path: path}
+	steps := make([]pathStep, 0, strings.Count(path, ".")+strings.Count(path, "[")+1)
+	for {
+		segStart := len(steps)
+		if p.peek() != '[' {
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, pathStep{key: name})
+		}
+		for p.peek() == '[' {
+			step, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		}
+		if len(steps) == segStart {
+			return nil, p.error("empty path segment")
+		}
+		if p.eof() {
+			return steps, nil
+		} else if p.peek() != '.' {
+			return nil, p.error("expected '.' or '['")
+		}
+		p.pos++
+	}
+}
+
+type pathParser struct {
+	path string
+	pos  int
+}
+
+func (p *pathParser) error(msg string) error {
+	return &PathSyntaxError{
+		Path:     p.path,
+		Position: p.pos,
+		Message:  msg,
+	}
+}
+
+func (p *pathParser) eof() bool {
+	return p.pos >= len(p.path)
+}
+
+func (p *pathParser) peek() byte {
+	if p.pos < len(p.path) {
+		return p.path[p.pos]
+	}
+	return 0
+}
+
+func (p *pathParser) parseName() (string, error) {
+	var sb strings.Builder
+	start := p.pos
+	for !p.eof() {
+		c := p.path[p.pos]
+		if c == '.' || c == '[' {
+			break
+		} else if c == '\\' {
+			p.pos++
+			if p.eof() {
+				return "", p.error("unterminated escape")
+			}
+			c = p.path[p.pos]
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.error("empty path segment")
+	}
+	return sb.String(), nil
+}
+
+func (p *pathParser) parseBracket() (pathStep, error) {
+	p.pos++ // '['
+	var step pathStep
+	if c := p.peek(); c == '"' || c == '\'' {
+		key, err := p.parseQuoted(c)
+		if err != nil {
+			return step, err
+		}
+		step = pathStep{key: key}
+	} else {
+		start := p.pos
+		if c == '-' {
+			p.pos++
+		}
+		for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+			p.pos++
+		}
+		idx, err := strconv.ParseInt(p.path[start:p.pos], 10, 64)
+		if err != nil {
+			p.pos = start
+			return step, p.error("expected index or quoted key")
+		}
+		step = pathStep{index: int(idx), indexed: true}
+	}
+	if p.peek() != ']' {
+		return step, p.error("expected ']'")
+	}
+	p.pos++
+	return step, nil
+}
+
+func (p *pathParser) parseQuoted(quote byte) (string, error) {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.error("unterminated quoted key")
+		}
+		c := p.path[p.pos]
+		if c == quote {
+			p.pos++
+			return sb.String(), nil
+		} else if c == '\\' {
+			p.pos++
+			if p.eof() {
+				return "", p.error("unterminated escape")
+			}
+			c = p.path[p.pos]
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+}
+
+// extractPathSteps extracts the value at the path steps - if convertNil is true, converters are also called for a nil
+// final value (as ExtractJsonPath always has)
+func extractPathSteps[T any](root any, steps []pathStep, convertNil bool, converters ...Converter[T]) (*Optional[T], []bool) {
+	l := len(steps)
+	pathPresent := make([]bool, 0, l)
+	curr := root
+	for i, step := range steps {
+		v, ok := step.child(curr)
+		if i == l-1 {
+			if step.indexed {
+				pathPresent = append(pathPresent, ok)
+			}
+			if ok {
+				if cv, ok := convertValue[T](v, converters...); ok {
+					return Of[T](cv), pathPresent
+				} else if convertNil && !isPresent(v) {
+					if cv, ok := runConverters[T](v, converters...); ok {
+						return Of[T](cv), pathPresent
+					}
+				}
+			}
+			break
+		}
+		if ok && !steps[i+1].indexed {
+			_, ok = v.(map[string]any)
+		}
+		pathPresent = append(pathPresent, ok)
+		if !ok {
+			break
+		}
+		curr = v
+	}
+	return Empty[T](), pathPresent
+}
+
+// PathReason is the reason reported in a PathResult (see ExtractJsonPathDetailed)
+type PathReason int
+
+const (
+	// PathFound indicates that the path was found and the value was present (and of the required type)
+	PathFound PathReason = iota
+	// PathSyntaxInvalid indicates that the path was invalid
+	PathSyntaxInvalid
+	// PathKeyMissing indicates that a property of the path was not found
+	PathKeyMissing
+	// PathNotObject indicates that a property was referenced on a value that is not an object (map[string]any)
+	PathNotObject
+	// PathNotArray indicates that an index was referenced on a value that is not an array ([]any)
+	PathNotArray
+	// PathIndexOutOfRange indicates that an index was out of range of the array
+	PathIndexOutOfRange
+	// PathNullValue indicates that the path was found but the value was null (nil)
+	PathNullValue
+	// PathTypeMismatch indicates that the path was found but the value was not of the required type (and no converter accepted it)
+	PathTypeMismatch
+)
+
+var pathReasonNames = map[PathReason]string{
+	PathFound:           "found",
+	PathSyntaxInvalid:   "syntax invalid",
+	PathKeyMissing:      "key missing",
+	PathNotObject:       "not an object",
+	PathNotArray:        "not an array",
+	PathIndexOutOfRange: "index out of range",
+	PathNullValue:       "null value",
+	PathTypeMismatch:    "type mismatch",
+}
+
+// String implements fmt.Stringer
+func (r PathReason) String() string {
+	if s, ok := pathReasonNames[r]; ok {
+		return s
+	}
+	return "reason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// PathResult is the detailed result of ExtractJsonPathDetailed / ExtractPathDetailed
+type PathResult struct {
+	// Path is the path that was extracted
+	Path string
+	// Reason is the reason the path was (or was not) extracted
+	Reason PathReason
+	// Segment is the index of the path segment at which extraction failed (-1 if the path was found or the path was invalid)
+	Segment int
+	// SegmentText is the text of the path segment at which extraction failed (e.g. "bar" or "[2]")
+	SegmentText string
+	// SegmentPath is the path up to (and including) the segment at which extraction failed (e.g. "foo.bar[2]")
+	SegmentPath string
+	// Kind is the kind of the offending value - i.e. the value on which the segment could not be resolved, or the final
+	// value that was null or not of the required type (one of "object", "array", "string", "number", "boolean", "null"
+	// or, for non-JSON values, the Go type)
+	Kind string
+	// SyntaxError is the error if the path was invalid
+	SyntaxError *PathSyntaxError
+}
+
+// Found returns whether the path was found (and the value was present and of the required type)
+func (r PathResult) Found() bool {
+	return r.Reason == PathFound
+}
+
+// Err returns an error with a human-readable message describing why the path was not extracted (or nil if it was found)
+func (r PathResult) Err() error {
+	switch r.Reason {
+	case PathFound:
+		return nil
+	case PathSyntaxInvalid:
+		return r.SyntaxError
+	case PathKeyMissing:
+		return fmt.Errorf("%s: not found", r.SegmentPath)
+	case PathNotObject:
+		return fmt.Errorf("%s: expected object but found %s", r.SegmentPath, r.Kind)
+	case PathNotArray:
+		return fmt.Errorf("%s: expected array but found %s", r.SegmentPath, r.Kind)
+	case PathIndexOutOfRange:
+		return fmt.Errorf("%s: index out of range", r.SegmentPath)
+	case PathNullValue:
+		return fmt.Errorf("%s: must not be null", r.SegmentPath)
+	case PathTypeMismatch:
+		return fmt.Errorf("%s: unexpected %s value", r.SegmentPath, r.Kind)
+	}
+	return fmt.Errorf("%s: %s", r.SegmentPath, r.Reason)
+}
+
+// ExtractJsonPathDetailed is the same as ExtractJsonPath, except that the second result arg is a PathResult describing
+// why the path was not extracted (e.g. the segment at which the path was not found, the reason and the kind of the
+// offending value) - the PathResult.Err() method provides a human-readable error
+func ExtractJsonPathDetailed[T any](m map[string]any, path string, converters ...Converter[T]) (*Optional[T], PathResult) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return Empty[T](), PathResult{
+			Path:        path,
+			Reason:      PathSyntaxInvalid,
+			Segment:     -1,
+			SyntaxError: err.(*PathSyntaxError),
+		}
+	}
+	return extractPathDetailed[T](m, path, steps, converters...)
+}
+
+// ExtractPathDetailed is the same as ExtractPath, except that the second result arg is a PathResult (see ExtractJsonPathDetailed)
+func ExtractPathDetailed[T any](p *Path, root any, converters ...Converter[T]) (*Optional[T], PathResult) {
+	return extractPathDetailed[T](root, p.path, p.steps, converters...)
+}
+
+func extractPathDetailed[T any](root any, path string, steps []pathStep, converters ...Converter[T]) (*Optional[T], PathResult) {
+	result := PathResult{
+		Path:    path,
+		Segment: -1,
+	}
+	failed := func(i int, reason PathReason, v any) (*Optional[T], PathResult) {
+		result.Reason = reason
+		result.Segment = i
+		result.SegmentText = steps[i].String()
+		result.SegmentPath = formatPath(steps[:i+1])
+		result.Kind = valueKind(v)
+		return Empty[T](), result
+	}
+	curr := root
+	for i, step := range steps {
+		v, ok := step.child(curr)
+		if !ok {
+			if step.indexed {
+				if _, isArr := curr.([]any); isArr {
+					return failed(i, PathIndexOutOfRange, curr)
+				}
+				return failed(i, PathNotArray, curr)
+			} else if _, isObj := curr.(map[string]any); isObj {
+				return failed(i, PathKeyMissing, curr)
+			}
+			return failed(i, PathNotObject, curr)
+		}
+		curr = v
+	}
+	if !isPresent(curr) {
+		return failed(len(steps)-1, PathNullValue, curr)
+	} else if cv, ok := convertValue[T](curr, converters...); ok {
+		return Of[T](cv), result
+	}
+	return failed(len(steps)-1, PathTypeMismatch, curr)
+}
+
+// formatPath formats steps as a path string (quoting keys where necessary)
+func formatPath(steps []pathStep) string {
+	var sb strings.Builder
+	for i, step := range steps {
+		if step.indexed {
+			sb.WriteString(step.String())
+		} else if step.key == "" || strings.ContainsAny(step.key, `.[]\"'`) {
+			sb.WriteString(`["`)
+			sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(step.key))
+			sb.WriteString(`"]`)
+		} else {
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(step.key)
+		}
+	}
+	return sb.String()
+}
+
+func valueKind(v any) string {
+	if !isPresent(v) {
+		return "null"
+	}
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+var (
+	// PathNotFound is the error returned (wrapped) by SetJsonPath, DeleteJsonPath and UpdateJsonPath when the location referenced by the path cannot be found (or created)
+	PathNotFound = errors.New("JSON path location not found")
+	// PathConflict is the error returned (wrapped) by SetJsonPath and UpdateJsonPath when an existing value in the path is not of the required type (i.e. not an object or array)
+	PathConflict = errors.New("JSON path conflicts with existing value")
+	// InvalidUpdateFunc is the error returned by UpdateJsonPath when the supplied update func is nil
+	InvalidUpdateFunc = errors.New("JSON path update func must not be nil")
+)
+
+// SetPathOption is an option that can be passed to SetJsonPath and UpdateJsonPath
+type SetPathOption func(opts *setPathOptions)
+
+type setPathOptions struct {
+	grow     bool
+	noCreate bool
+}
+
+// GrowArrays is a SetPathOption that allows existing arrays to be grown when the path index is beyond the end of
+// the array (any elements between are set to nil)
+func GrowArrays() SetPathOption {
+	return func(opts *setPathOptions) {
+		opts.grow = true
+	}
+}
+
+// NoCreateIntermediate is a SetPathOption that prevents missing intermediate objects/arrays from being created
+func NoCreateIntermediate() SetPathOption {
+	return func(opts *setPathOptions) {
+		opts.noCreate = true
+	}
+}
+
+// SetJsonPath sets the value in a map[string]any at the location referenced by the supplied path (see ExtractJsonPath for the path grammar)
+//
+// Missing (or null) intermediate objects (map[string]any) and arrays ([]any) are created - unless the NoCreateIntermediate option is used.
+// Indexes may be negative (indicating relative index to the end) - an index beyond the end of an existing array is only
+// permitted if the GrowArrays option is used (newly created arrays are always sized to fit the index)
+//
+// Returns a *PathSyntaxError if the path is invalid, or an error wrapping PathNotFound or PathConflict if the value could not be set
+func SetJsonPath(m map[string]any, path string, v any, opts ...SetPathOption) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	o := &setPathOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	_, err = setPath(m, steps, 0, v, o, o.grow)
+	return err
+}
+
+// DeleteJsonPath deletes the value in a map[string]any at the location referenced by the supplied path (see ExtractJsonPath for the path grammar)
+//
+// If the parent of the referenced location is an array ([]any), the element is removed and subsequent elements are shifted down
+//
+// Returns a *PathSyntaxError if the path is invalid, or an error wrapping PathNotFound if the location was not found
+func DeleteJsonPath(m map[string]any, path string) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	_, err = deletePath(m, steps, 0)
+	return err
+}
+
+// UpdateJsonPath updates the value in a map[string]any at the location referenced by the supplied path (see ExtractJsonPath for the path grammar)
+//
+// The supplied function is called with the current value (as an optional - which is empty if the location was not found, or the
+// value was null or not of the specified type).  If the optional returned by the function is present, its value is set at the
+// location (see SetJsonPath) - otherwise, the location is deleted (if it was present)
+//
+// Returns InvalidUpdateFunc if the supplied function is nil, a *PathSyntaxError if the path is invalid, or an error wrapping
+// PathNotFound or PathConflict if the value could not be set
+func UpdateJsonPath[T any](m map[string]any, path string, f func(current *Optional[T]) *Optional[T], opts ...SetPathOption) error {
+	if f == nil {
+		return InvalidUpdateFunc
+	}
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	current, _ := extractPathSteps[T](m, steps, false)
+	if updated := f(current); updated.IsPresent() {
+		o := &setPathOptions{}
+		for _, opt := range opts {
+			opt(o)
+		}
+		_, err = setPath(m, steps, 0, updated.value, o, o.grow)
+	} else if current.IsPresent() {
+		_, err = deletePath(m, steps, 0)
+	}
+	return err
+}
+
+func setPath(node any, steps []pathStep, i int, v any, opts *setPathOptions, grow bool) (any, error) {
+	step := steps[i]
+	last := i == len(steps)-1
+	var child any
+	if step.indexed {
+		sv, ok := node.([]any)
+		if !ok {
+			return node, pathError(PathConflict, steps, i, "expected array but found "+valueKind(node))
+		}
+		idx := step.index
+		if idx < 0 {
+			idx = len(sv) + idx
+		}
+		if idx < 0 || (idx >= len(sv) && !grow) {
+			return node, pathError(PathNotFound, steps, i, "index out of range")
+		} else if idx >= len(sv) {
+			sv = append(sv, make([]any, idx+1-len(sv))...)
+		}
+		if last {
+			sv[idx] = v
+			return sv, nil
+		}
+		if child, grow, ok = newPathNode(sv[idx], steps[i+1], opts); !ok {
+			return node, pathError(PathNotFound, steps, i, "not found")
+		}
+		nc, err := setPath(child, steps, i+1, v, opts, grow)
+		if err == nil {
+			sv[idx] = nc
+		}
+		return sv, err
+	}
+	mv, ok := node.(map[string]any)
+	if !ok {
+		return node, pathError(PathConflict, steps, i, "expected object but found "+valueKind(node))
+	} else if mv == nil {
+		return node, pathError(PathNotFound, steps, i, "nil map")
+	} else if last {
+		mv[step.key] = v
+		return mv, nil
+	}
+	if child, grow, ok = newPathNode(mv[step.key], steps[i+1], opts); !ok {
+		return node, pathError(PathNotFound, steps, i, "not found")
+	}
+	nc, err := setPath(child, steps, i+1, v, opts, grow)
+	if err == nil {
+		mv[step.key] = nc
+	}
+	return mv, err
+}
+
+// newPathNode returns the existing node - or, if the existing node is nil (including a nil map[string]any or []any), a
+// new node (as required by the next step)
+func newPathNode(existing any, next pathStep, opts *setPathOptions) (node any, grow bool, ok bool) {
+	if !isNilPathNode(existing) {
+		return existing, opts.grow, true
+	} else if opts.noCreate {
+		return nil, false, false
+	} else if next.indexed {
+		return make([]any, 0), true, true
+	}
+	return map[string]any{}, opts.grow, true
+}
+
+func isNilPathNode(node any) bool {
+	switch nv := node.(type) {
+	case map[string]any:
+		return nv == nil
+	case []any:
+		return nv == nil
+	}
+	return node == nil
+}
+
+func deletePath(node any, steps []pathStep, i int) (any, error) {
+	step := steps[i]
+	last := i == len(steps)-1
+	if step.indexed {
+		if sv, ok := node.([]any); ok {
+			idx := step.index
+			if idx < 0 {
+				idx = len(sv) + idx
+			}
+			if idx >= 0 && idx < len(sv) {
+				if last {
+					return append(sv[:idx], sv[idx+1:]...), nil
+				}
+				nc, err := deletePath(sv[idx], steps, i+1)
+				if err == nil {
+					sv[idx] = nc
+				}
+				return sv, err
+			}
+		}
+	} else if mv, ok := node.(map[string]any); ok {
+		if child, ok := mv[step.key]; ok {
+			if last {
+				delete(mv, step.key)
+				return mv, nil
+			}
+			nc, err := deletePath(child, steps, i+1)
+			if err == nil {
+				mv[step.key] = nc
+			}
+			return mv, err
+		}
+	}
+	return node, pathError(PathNotFound, steps, i, "not found")
+}
+
+func pathError(err error, steps []pathStep, i int, msg string) error {
+	return fmt.Errorf("%w: %s: %s", err, formatPath(steps[:i+1]), msg)
+}
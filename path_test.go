@@ -0,0 +1,590 @@
+package gopt
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	testCases := []struct {
+		path   string
+		expect []pathStep
+	}{
+		{"foo", []pathStep{{key: "foo"}}},
+		{"foo.bar", []pathStep{{key: "foo"}, {key: "bar"}}},
+		{"foo[0]", []pathStep{{key: "foo"}, {index: 0, indexed: true}}},
+		{"foo[-1]", []pathStep{{key: "foo"}, {index: -1, indexed: true}}},
+		{"matrix[0][1]", []pathStep{{key: "matrix"}, {index: 0, indexed: true}, {index: 1, indexed: true}}},
+		{"[0]", []pathStep{{index: 0, indexed: true}}},
+		{"[0].foo", []pathStep{{index: 0, indexed: true}, {key: "foo"}}},
+		{"[0][\"a.b\"]", []pathStep{{index: 0, indexed: true}, {key: "a.b"}}},
+		{`labels["app.kubernetes.io/name"]`, []pathStep{{key: "labels"}, {key: "app.kubernetes.io/name"}}},
+		{`labels['app.kubernetes.io/name']`, []pathStep{{key: "labels"}, {key: "app.kubernetes.io/name"}}},
+		{`labels["a\"b"]`, []pathStep{{key: "labels"}, {key: `a"b`}}},
+		{`labels['a\'b']`, []pathStep{{key: "labels"}, {key: `a'b`}}},
+		{`labels[""]`, []pathStep{{key: "labels"}, {key: ""}}},
+		{`labels.app\.kubernetes\.io/name`, []pathStep{{key: "labels"}, {key: "app.kubernetes.io/name"}}},
+		{`foo\[0\]`, []pathStep{{key: "foo[0]"}}},
+		{`foo\\`, []pathStep{{key: `foo\`}}},
+		{"foo]", []pathStep{{key: "foo]"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			steps, err := parsePath(tc.path)
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, steps)
+		})
+	}
+}
+
+func TestParsePath_Errors(t *testing.T) {
+	testCases := []struct {
+		path     string
+		position int
+	}{
+		{"", 0},
+		{".", 0},
+		{"foo.", 4},
+		{"foo..bar", 4},
+		{"foo[", 4},
+		{"foo[]", 4},
+		{"foo[x]", 4},
+		{"foo[-]", 4},
+		{"foo[0", 5},
+		{"foo[0]bar", 6},
+		{`foo["bar]`, 9},
+		{`foo["bar"`, 9},
+		{`foo\`, 4},
+		{`foo["bar\`, 9},
+		{"foo[99999999999999999999]", 4},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			_, err := parsePath(tc.path)
+			require.Error(t, err)
+			var pErr *PathSyntaxError
+			require.True(t, errors.As(err, &pErr))
+			require.Equal(t, tc.path, pErr.Path)
+			require.Equal(t, tc.position, pErr.Position)
+		})
+	}
+}
+
+func TestExtractJsonPath_RicherGrammar(t *testing.T) {
+	m := map[string]any{
+		"matrix": []any{
+			[]any{1, 2},
+			[]any{3, 4},
+		},
+		"labels": map[string]any{
+			"app.kubernetes.io/name": "my-app",
+		},
+		"a.b": 1,
+	}
+	i, p := ExtractJsonPath[int](m, "matrix[1][0]")
+	require.True(t, i.IsPresent())
+	require.Equal(t, 3, i.OrElse(-1))
+	require.Equal(t, []bool{true, true, true}, p)
+
+	i, p = ExtractJsonPath[int](m, "matrix[-1][-1]")
+	require.True(t, i.IsPresent())
+	require.Equal(t, 4, i.OrElse(-1))
+	require.Equal(t, []bool{true, true, true}, p)
+
+	i, p = ExtractJsonPath[int](m, "matrix[0][2]")
+	require.False(t, i.IsPresent())
+	require.Equal(t, []bool{true, true, false}, p)
+
+	i, p = ExtractJsonPath[int](m, "matrix[2][0]")
+	require.False(t, i.IsPresent())
+	require.Equal(t, []bool{true, false}, p)
+
+	s, p := ExtractJsonPath[string](m, `labels["app.kubernetes.io/name"]`)
+	require.True(t, s.IsPresent())
+	require.Equal(t, "my-app", s.OrElse(""))
+	require.Equal(t, []bool{true}, p)
+
+	s, _ = ExtractJsonPath[string](m, `labels.app\.kubernetes\.io/name`)
+	require.True(t, s.IsPresent())
+	require.Equal(t, "my-app", s.OrElse(""))
+
+	// single property paths have nil path presence (as before)...
+	i, p = ExtractJsonPath[int](m, `a\.b`)
+	require.True(t, i.IsPresent())
+	require.Nil(t, p)
+	i, p = ExtractJsonPath[int](m, `["a.b"]`)
+	require.True(t, i.IsPresent())
+	require.Nil(t, p)
+	i, p = ExtractJsonPath[int](m, "missing")
+	require.False(t, i.IsPresent())
+	require.Nil(t, p)
+	_, p, _ = ExtractJsonPathFrom[int](m, "missing")
+	require.NotNil(t, p)
+	require.Equal(t, 0, len(p))
+
+	// paths not valid in the extended grammar are interpreted as before...
+	i, p = ExtractJsonPath[int](m, "matrix[x]")
+	require.False(t, i.IsPresent())
+	require.Equal(t, []bool{}, p)
+	m["foo[bar]"] = 1
+	i, p = ExtractJsonPath[int](m, "foo[bar]")
+	require.Equal(t, 1, i.OrElse(0))
+	require.Equal(t, []bool{}, p)
+	i, _ = ExtractJsonPath[int](m, `["foo[bar]"]`)
+	require.Equal(t, 1, i.OrElse(0))
+	m[""] = 2
+	i, p = ExtractJsonPath[int](m, "")
+	require.Equal(t, 2, i.OrElse(0))
+	require.Nil(t, p)
+	m["x"] = map[string]any{"foo[bar]": 3}
+	i, p = ExtractJsonPath[int](m, "x.foo[bar]")
+	require.Equal(t, 3, i.OrElse(0))
+	require.Equal(t, []bool{true}, p)
+	// but are errors for ExtractJsonPathFrom...
+	_, _, err := ExtractJsonPathFrom[int](m, "foo[bar]")
+	var pErr *PathSyntaxError
+	require.True(t, errors.As(err, &pErr))
+	_, _, err = ExtractJsonPathFrom[int](m, "")
+	require.True(t, errors.As(err, &pErr))
+
+	// converters are called for nil values (but not by ExtractJsonPathFrom)...
+	m["n"] = map[string]any{"v": nil}
+	nilToZero := func(value any) (int, bool) {
+		return 0, value == nil
+	}
+	i, _ = ExtractJsonPath[int](m, "n.v", nilToZero)
+	require.True(t, i.IsPresent())
+	i, _, _ = ExtractJsonPathFrom[int](m, "n.v", nilToZero)
+	require.False(t, i.IsPresent())
+}
+
+func TestExtractJsonPathFrom(t *testing.T) {
+	root := []any{
+		map[string]any{"foo": "bar"},
+		[]any{"a", "b"},
+	}
+	s, p, err := ExtractJsonPathFrom[string](root, "[0].foo")
+	require.NoError(t, err)
+	require.True(t, s.IsPresent())
+	require.Equal(t, "bar", s.OrElse(""))
+	require.Equal(t, []bool{true}, p)
+
+	s, p, err = ExtractJsonPathFrom[string](root, "[-1][1]")
+	require.NoError(t, err)
+	require.True(t, s.IsPresent())
+	require.Equal(t, "b", s.OrElse(""))
+	require.Equal(t, []bool{true, true}, p)
+
+	s, p, err = ExtractJsonPathFrom[string](root, "[1].foo")
+	require.NoError(t, err)
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{false}, p)
+
+	s, p, err = ExtractJsonPathFrom[string](root, "[0")
+	require.Error(t, err)
+	require.False(t, s.IsPresent())
+	require.Nil(t, p)
+	var pErr *PathSyntaxError
+	require.True(t, errors.As(err, &pErr))
+	require.Equal(t, `invalid path "[0" at position 2: expected ']'`, err.Error())
+}
+
+func TestCompilePath(t *testing.T) {
+	m := map[string]any{
+		"foo": map[string]any{
+			"bar": []any{
+				map[string]any{"baz": "X"},
+				map[string]any{"baz": "Y"},
+			},
+		},
+	}
+	p, err := CompilePath("foo.bar[-1].baz")
+	require.NoError(t, err)
+	require.Equal(t, "foo.bar[-1].baz", p.String())
+	s, pp := ExtractPath[string](p, m)
+	require.True(t, s.IsPresent())
+	require.Equal(t, "Y", s.OrElse(""))
+	require.Equal(t, []bool{true, true, true}, pp)
+
+	s, pp = ExtractPath[string](p, map[string]any{})
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{false}, pp)
+
+	p, err = CompilePath("[0]")
+	require.NoError(t, err)
+	s, _ = ExtractPath[string](p, []any{"first"})
+	require.True(t, s.IsPresent())
+	require.Equal(t, "first", s.OrElse(""))
+
+	_, err = CompilePath("foo[x]")
+	require.Error(t, err)
+	var pErr *PathSyntaxError
+	require.True(t, errors.As(err, &pErr))
+}
+
+func TestMustCompilePath(t *testing.T) {
+	require.NotPanics(t, func() {
+		_ = MustCompilePath("foo.bar")
+	})
+	require.Panics(t, func() {
+		_ = MustCompilePath("foo..bar")
+	})
+}
+
+func TestExtractPath_Concurrent(t *testing.T) {
+	p := MustCompilePath("foo.bar[0]")
+	m := map[string]any{
+		"foo": map[string]any{
+			"bar": []any{1},
+		},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				o, _ := ExtractPath[int](p, m)
+				if !o.IsPresent() {
+					t.Error("expected present")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+var benchPathMap = map[string]any{
+	"foo": map[string]any{
+		"bar": []any{
+			map[string]any{"baz": "X"},
+			map[string]any{"baz": "Y"},
+		},
+	},
+}
+
+func BenchmarkExtractJsonPath(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = ExtractJsonPath[string](benchPathMap, "foo.bar[-1].baz")
+	}
+}
+
+func BenchmarkExtractPath(b *testing.B) {
+	p := MustCompilePath("foo.bar[-1].baz")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ExtractPath[string](p, benchPathMap)
+	}
+}
+
+func TestExtractJsonPathDetailed(t *testing.T) {
+	m := map[string]any{
+		"foo": map[string]any{
+			"bar": []any{
+				map[string]any{"baz": "X"},
+				nil,
+				1.5,
+			},
+			"str":   "s",
+			"a.b":   true,
+			"null":  nil,
+			"nilsl": []any(nil),
+		},
+	}
+	testCases := []struct {
+		path        string
+		reason      PathReason
+		segment     int
+		segmentText string
+		segmentPath string
+		kind        string
+		message     string
+	}{
+		{"foo.bar[0].baz", PathFound, -1, "", "", "", ""},
+		{"xxx", PathKeyMissing, 0, "xxx", "xxx", "object", "xxx: not found"},
+		{"foo.xxx.baz", PathKeyMissing, 1, "xxx", "foo.xxx", "object", "foo.xxx: not found"},
+		{"foo.str.baz", PathNotObject, 2, "baz", "foo.str.baz", "string", "foo.str.baz: expected object but found string"},
+		{"foo.bar.baz", PathNotObject, 2, "baz", "foo.bar.baz", "array", "foo.bar.baz: expected object but found array"},
+		{"foo[0]", PathNotArray, 1, "[0]", "foo[0]", "object", "foo[0]: expected array but found object"},
+		{"foo.bar[3].baz", PathIndexOutOfRange, 2, "[3]", "foo.bar[3]", "array", "foo.bar[3]: index out of range"},
+		{"foo.bar[1]", PathNullValue, 2, "[1]", "foo.bar[1]", "null", "foo.bar[1]: must not be null"},
+		{"foo.bar[1].baz", PathNotObject, 3, "baz", "foo.bar[1].baz", "null", "foo.bar[1].baz: expected object but found null"},
+		{"foo.null", PathNullValue, 1, "null", "foo.null", "null", "foo.null: must not be null"},
+		{"foo.nilsl", PathNullValue, 1, "nilsl", "foo.nilsl", "null", "foo.nilsl: must not be null"},
+		{"foo.bar[2]", PathTypeMismatch, 2, "[2]", "foo.bar[2]", "number", "foo.bar[2]: unexpected number value"},
+		{`foo["a.b"]`, PathTypeMismatch, 1, "a.b", `foo["a.b"]`, "boolean", `foo["a.b"]: unexpected boolean value`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			o, r := ExtractJsonPathDetailed[string](m, tc.path)
+			require.Equal(t, tc.reason == PathFound, o.IsPresent())
+			require.Equal(t, tc.reason == PathFound, r.Found())
+			require.Equal(t, tc.path, r.Path)
+			require.Equal(t, tc.reason, r.Reason)
+			require.Equal(t, tc.segment, r.Segment)
+			require.Equal(t, tc.segmentText, r.SegmentText)
+			require.Equal(t, tc.segmentPath, r.SegmentPath)
+			require.Equal(t, tc.kind, r.Kind)
+			if tc.message == "" {
+				require.NoError(t, r.Err())
+			} else {
+				require.EqualError(t, r.Err(), tc.message)
+			}
+		})
+	}
+}
+
+func TestExtractJsonPathDetailed_SyntaxError(t *testing.T) {
+	o, r := ExtractJsonPathDetailed[string](map[string]any{}, "foo[x]")
+	require.False(t, o.IsPresent())
+	require.Equal(t, PathSyntaxInvalid, r.Reason)
+	require.Equal(t, -1, r.Segment)
+	require.NotNil(t, r.SyntaxError)
+	require.Error(t, r.Err())
+	var pErr *PathSyntaxError
+	require.True(t, errors.As(r.Err(), &pErr))
+}
+
+func TestExtractPathDetailed(t *testing.T) {
+	p := MustCompilePath("[0].foo")
+	o, r := ExtractPathDetailed[int](p, []any{map[string]any{"foo": 1}})
+	require.True(t, o.IsPresent())
+	require.True(t, r.Found())
+	o, r = ExtractPathDetailed[int](p, []any{map[string]any{"foo": "1"}})
+	require.False(t, o.IsPresent())
+	require.Equal(t, PathTypeMismatch, r.Reason)
+	o, r = ExtractPathDetailed[int](p, []any{map[string]any{"foo": "1"}}, func(v any) (int, bool) {
+		return 1, true
+	})
+	require.True(t, o.IsPresent())
+	require.True(t, r.Found())
+}
+
+func TestPathReason_String(t *testing.T) {
+	require.Equal(t, "found", PathFound.String())
+	require.Equal(t, "type mismatch", PathTypeMismatch.String())
+	require.Equal(t, "reason(99)", PathReason(99).String())
+}
+
+func TestSetJsonPath(t *testing.T) {
+	m := map[string]any{
+		"arr": []any{"a", "b"},
+		"str": "s",
+	}
+	err := SetJsonPath(m, "foo", 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, m["foo"])
+
+	err = SetJsonPath(m, "a.b.c", 2)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"b": map[string]any{"c": 2}}, m["a"])
+
+	err = SetJsonPath(m, `labels["app.kubernetes.io/name"]`, "my-app")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"app.kubernetes.io/name": "my-app"}, m["labels"])
+
+	err = SetJsonPath(m, "arr[0]", "A")
+	require.NoError(t, err)
+	require.Equal(t, []any{"A", "b"}, m["arr"])
+	err = SetJsonPath(m, "arr[-1]", "B")
+	require.NoError(t, err)
+	require.Equal(t, []any{"A", "B"}, m["arr"])
+
+	err = SetJsonPath(m, "arr[3]", "D")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	require.Equal(t, "JSON path location not found: arr[3]: index out of range", err.Error())
+	require.Equal(t, []any{"A", "B"}, m["arr"])
+	err = SetJsonPath(m, "arr[-3]", "D", GrowArrays())
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	err = SetJsonPath(m, "arr[3]", "D", GrowArrays())
+	require.NoError(t, err)
+	require.Equal(t, []any{"A", "B", nil, "D"}, m["arr"])
+
+	err = SetJsonPath(m, "new[1].foo", "bar")
+	require.NoError(t, err)
+	require.Equal(t, []any{nil, map[string]any{"foo": "bar"}}, m["new"])
+	err = SetJsonPath(m, "matrix[1][0]", 1)
+	require.NoError(t, err)
+	require.Equal(t, []any{nil, []any{1}}, m["matrix"])
+	err = SetJsonPath(m, "matrix[2][0]", 1)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	err = SetJsonPath(m, "matrix[0][0]", 0)
+	require.NoError(t, err)
+	require.Equal(t, []any{[]any{0}, []any{1}}, m["matrix"])
+
+	err = SetJsonPath(m, "str.foo", 1)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathConflict))
+	require.Equal(t, "JSON path conflicts with existing value: str.foo: expected object but found string", err.Error())
+	err = SetJsonPath(m, "str[0]", 1)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathConflict))
+	err = SetJsonPath(m, "[0]", 1)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathConflict))
+
+	err = SetJsonPath(m, "x.y.z", 1, NoCreateIntermediate())
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	_, ok := m["x"]
+	require.False(t, ok)
+	err = SetJsonPath(m, "a.b.d", 3, NoCreateIntermediate())
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"b": map[string]any{"c": 2, "d": 3}}, m["a"])
+
+	err = SetJsonPath(m, "foo[", 1)
+	require.Error(t, err)
+	var pErr *PathSyntaxError
+	require.True(t, errors.As(err, &pErr))
+}
+
+func TestSetJsonPath_ExistingArrayElements(t *testing.T) {
+	m := map[string]any{
+		"items":  []any{map[string]any{"a": 1}},
+		"matrix": []any{[]any{1, 2}},
+		"deep":   []any{[]any{map[string]any{"x": 1}}},
+	}
+	err := SetJsonPath(m, "items[0].b", 2)
+	require.NoError(t, err)
+	require.Equal(t, []any{map[string]any{"a": 1, "b": 2}}, m["items"])
+	err = SetJsonPath(m, "items[-1].c", 3, NoCreateIntermediate())
+	require.NoError(t, err)
+	require.Equal(t, []any{map[string]any{"a": 1, "b": 2, "c": 3}}, m["items"])
+
+	err = SetJsonPath(m, "matrix[0][1]", 9)
+	require.NoError(t, err)
+	require.Equal(t, []any{[]any{1, 9}}, m["matrix"])
+	err = SetJsonPath(m, "matrix[0][2]", 3, GrowArrays())
+	require.NoError(t, err)
+	require.Equal(t, []any{[]any{1, 9, 3}}, m["matrix"])
+
+	err = SetJsonPath(m, "deep[0][0].y", 2, NoCreateIntermediate())
+	require.NoError(t, err)
+	require.Equal(t, []any{[]any{map[string]any{"x": 1, "y": 2}}}, m["deep"])
+}
+
+func TestSetJsonPath_TypedNilNodes(t *testing.T) {
+	m := map[string]any{
+		"obj":   map[string]any(nil),
+		"arr":   []any(nil),
+		"items": []any{map[string]any(nil)},
+	}
+	err := SetJsonPath(m, "obj.a", 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": 1}, m["obj"])
+	err = SetJsonPath(m, "arr[1]", 2)
+	require.NoError(t, err)
+	require.Equal(t, []any{nil, 2}, m["arr"])
+	err = SetJsonPath(m, "items[0].b", 3)
+	require.NoError(t, err)
+	require.Equal(t, []any{map[string]any{"b": 3}}, m["items"])
+
+	// typed nil nodes are not created with NoCreateIntermediate...
+	m["obj"] = map[string]any(nil)
+	err = SetJsonPath(m, "obj.a", 1, NoCreateIntermediate())
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+
+	// a nil root map cannot be set...
+	err = SetJsonPath(nil, "a", 1)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+}
+
+func TestSetJsonPath_NoPartialCreate(t *testing.T) {
+	m := map[string]any{}
+	err := SetJsonPath(m, "a.b[-1].c", 1)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	require.Equal(t, 0, len(m))
+}
+
+func TestDeleteJsonPath(t *testing.T) {
+	m := map[string]any{
+		"foo": map[string]any{
+			"arr": []any{"a", "b", "c"},
+			"a.b": 1,
+		},
+		"bar": 1,
+	}
+	err := DeleteJsonPath(m, "bar")
+	require.NoError(t, err)
+	_, ok := m["bar"]
+	require.False(t, ok)
+
+	err = DeleteJsonPath(m, `foo["a.b"]`)
+	require.NoError(t, err)
+	_, ok = m["foo"].(map[string]any)["a.b"]
+	require.False(t, ok)
+
+	err = DeleteJsonPath(m, "foo.arr[1]")
+	require.NoError(t, err)
+	require.Equal(t, []any{"a", "c"}, m["foo"].(map[string]any)["arr"])
+	err = DeleteJsonPath(m, "foo.arr[-1]")
+	require.NoError(t, err)
+	require.Equal(t, []any{"a"}, m["foo"].(map[string]any)["arr"])
+
+	err = DeleteJsonPath(m, "foo.arr[1]")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	require.Equal(t, "JSON path location not found: foo.arr[1]: not found", err.Error())
+	err = DeleteJsonPath(m, "xxx.yyy")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	err = DeleteJsonPath(m, "foo.arr.x")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathNotFound))
+	err = DeleteJsonPath(m, "foo..x")
+	require.Error(t, err)
+	var pErr *PathSyntaxError
+	require.True(t, errors.As(err, &pErr))
+}
+
+func TestUpdateJsonPath(t *testing.T) {
+	m := map[string]any{
+		"counters": map[string]any{
+			"hits": 1,
+		},
+	}
+	inc := func(current *Optional[int]) *Optional[int] {
+		return Of(current.OrElse(0) + 1)
+	}
+	err := UpdateJsonPath[int](m, "counters.hits", inc)
+	require.NoError(t, err)
+	require.Equal(t, 2, m["counters"].(map[string]any)["hits"])
+	err = UpdateJsonPath[int](m, "counters.misses", inc)
+	require.NoError(t, err)
+	require.Equal(t, 1, m["counters"].(map[string]any)["misses"])
+	err = UpdateJsonPath[int](m, "other.arr[2]", inc)
+	require.NoError(t, err)
+	require.Equal(t, []any{nil, nil, 1}, m["other"].(map[string]any)["arr"])
+
+	remove := func(current *Optional[int]) *Optional[int] {
+		return nil
+	}
+	err = UpdateJsonPath[int](m, "counters.hits", remove)
+	require.NoError(t, err)
+	_, ok := m["counters"].(map[string]any)["hits"]
+	require.False(t, ok)
+	err = UpdateJsonPath[int](m, "counters.xxx", remove)
+	require.NoError(t, err)
+	_, ok = m["counters"].(map[string]any)["xxx"]
+	require.False(t, ok)
+
+	err = UpdateJsonPath[int](m, "counters.misses.foo", inc)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathConflict))
+	err = UpdateJsonPath[int](m, "counters.misses.foo", inc, NoCreateIntermediate())
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PathConflict))
+	err = UpdateJsonPath[int](m, "counters[", inc)
+	require.Error(t, err)
+	err = UpdateJsonPath[int](m, "counters.hits", nil)
+	require.Equal(t, InvalidUpdateFunc, err)
+}
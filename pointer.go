@@ -0,0 +1,213 @@
+package gopt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// InvalidPointer is the error returned (wrapped) by SetPointer and RemovePointer when the supplied JSON Pointer is invalid
+	InvalidPointer = errors.New("invalid JSON pointer")
+	// PointerNotFound is the error returned (wrapped) by SetPointer and RemovePointer when the location referenced by the JSON Pointer cannot be found
+	PointerNotFound = errors.New("JSON pointer location not found")
+)
+
+// ExtractPointer extracts an optional value, of the specified type, from a map[string]any using the supplied JSON Pointer (RFC 6901)
+//
+// If the referenced location is present (and the value is non-nil and of the specified type) then an optional with the value is returned, otherwise an empty optional is returned
+//
+// The JSON Pointer is a string of reference tokens each prefixed by "/" - where "~1" and "~0" are unescaped to "/" and "~"
+// respectively, and array elements are referenced by index.  For example, given a map of:
+//   m := map[string]any{
+//     "foo": map[string]any{
+//       "a/b": []any{"X", "Y"},
+//     },
+//   }
+// then using:
+//  o, _ := ExtractPointer[string](m, "/foo/a~1b/1")
+// would yield a present Optional with value "Y"
+//
+// The array index token "-" references the (non-existent) element after the last array element - so is never found
+//
+// The second result arg of ExtractPointer is a slice of bools indicating whether each reference token was found (it stops
+// at the first token not found) - if the returned Optional is not present and all the tokens were found, then the value
+// referenced was nil (or not of the specified type).  If the supplied pointer is invalid, the second result arg is nil
+func ExtractPointer[T any](m map[string]any, pointer string, converters ...Converter[T]) (*Optional[T], []bool) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return Empty[T](), nil
+	}
+	pathPresent := make([]bool, 0, len(tokens))
+	var curr any = m
+	for _, token := range tokens {
+		next, ok := pointerChild(curr, token)
+		pathPresent = append(pathPresent, ok)
+		if !ok {
+			return Empty[T](), pathPresent
+		}
+		curr = next
+	}
+	if v, ok := convertValue[T](curr, converters...); ok {
+		return Of[T](v), pathPresent
+	}
+	return Empty[T](), pathPresent
+}
+
+// SetPointer sets the value in a map[string]any at the location referenced by the supplied JSON Pointer (RFC 6901)
+//
+// The parent of the referenced location must already exist.  If the parent is a map, the member is set (added or replaced).
+// If the parent is a slice ([]any), the element at the index is replaced - or, if the index token is "-" (or equal to the
+// length of the slice), the value is appended
+//
+// Returns an error (wrapping InvalidPointer or PointerNotFound) if the value could not be set
+func SetPointer(m map[string]any, pointer string, v any) error {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	} else if len(tokens) == 0 {
+		return fmt.Errorf("%w: cannot set root", InvalidPointer)
+	}
+	_, err = setPointer(m, tokens, v, pointer)
+	return err
+}
+
+// RemovePointer removes the value from a map[string]any at the location referenced by the supplied JSON Pointer (RFC 6901)
+//
+// If the parent of the referenced location is a slice ([]any), the element is removed and subsequent elements are shifted down
+//
+// Returns an error (wrapping InvalidPointer or PointerNotFound) if the value could not be removed
+func RemovePointer(m map[string]any, pointer string) error {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	} else if len(tokens) == 0 {
+		return fmt.Errorf("%w: cannot remove root", InvalidPointer)
+	}
+	_, err = removePointer(m, tokens, pointer)
+	return err
+}
+
+// EscapePointerToken escapes a reference token for use in a JSON Pointer ("~" is escaped as "~0" and "/" as "~1")
+func EscapePointerToken(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	} else if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: %q must start with '/'", InvalidPointer, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j == len(token)-1 || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: %q has invalid escape in token %q", InvalidPointer, pointer, token)
+			}
+		}
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+// pointerIndex parses an array index reference token - returns -1 if the token is not a valid index
+func pointerIndex(token string) int {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return -1
+	}
+	idx := 0
+	for _, c := range token {
+		if c < '0' || c > '9' || idx > (maxQueryInt-9)/10 {
+			return -1
+		}
+		idx = idx*10 + int(c-'0')
+	}
+	return idx
+}
+
+func pointerChild(node any, token string) (any, bool) {
+	switch nt := node.(type) {
+	case map[string]any:
+		v, ok := nt[token]
+		return v, ok
+	case []any:
+		if idx := pointerIndex(token); idx >= 0 && idx < len(nt) {
+			return nt[idx], true
+		}
+	}
+	return nil, false
+}
+
+func setPointer(node any, tokens []string, v any, pointer string) (any, error) {
+	token := tokens[0]
+	last := len(tokens) == 1
+	switch nt := node.(type) {
+	case map[string]any:
+		if last {
+			nt[token] = v
+			return nt, nil
+		} else if child, ok := nt[token]; ok {
+			nc, err := setPointer(child, tokens[1:], v, pointer)
+			if err == nil {
+				nt[token] = nc
+			}
+			return nt, err
+		}
+	case []any:
+		idx := len(nt)
+		if token != "-" {
+			idx = pointerIndex(token)
+		}
+		if last && idx == len(nt) {
+			return append(nt, v), nil
+		} else if idx >= 0 && idx < len(nt) {
+			if last {
+				nt[idx] = v
+				return nt, nil
+			}
+			nc, err := setPointer(nt[idx], tokens[1:], v, pointer)
+			if err == nil {
+				nt[idx] = nc
+			}
+			return nt, err
+		}
+	}
+	return node, fmt.Errorf("%w: %q (at token %q)", PointerNotFound, pointer, token)
+}
+
+func removePointer(node any, tokens []string, pointer string) (any, error) {
+	token := tokens[0]
+	last := len(tokens) == 1
+	switch nt := node.(type) {
+	case map[string]any:
+		if child, ok := nt[token]; ok {
+			if last {
+				delete(nt, token)
+				return nt, nil
+			}
+			nc, err := removePointer(child, tokens[1:], pointer)
+			if err == nil {
+				nt[token] = nc
+			}
+			return nt, err
+		}
+	case []any:
+		if idx := pointerIndex(token); idx >= 0 && idx < len(nt) {
+			if last {
+				return append(nt[:idx], nt[idx+1:]...), nil
+			}
+			nc, err := removePointer(nt[idx], tokens[1:], pointer)
+			if err == nil {
+				nt[idx] = nc
+			}
+			return nt, err
+		}
+	}
+	return node, fmt.Errorf("%w: %q (at token %q)", PointerNotFound, pointer, token)
+}
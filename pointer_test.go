@@ -0,0 +1,224 @@
+package gopt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExtractPointer(t *testing.T) {
+	m := map[string]any{
+		"foo":  []any{"bar", "baz"},
+		"":     0,
+		"a/b":  1,
+		"c%d":  2,
+		"e^f":  3,
+		"g|h":  4,
+		"i\\j": 5,
+		"k\"l": 6,
+		" ":    7,
+		"m~n":  8,
+		"nested": map[string]any{
+			"arr": []any{
+				map[string]any{"x": "X"},
+				nil,
+			},
+		},
+	}
+	// RFC 6901 section 5 examples...
+	o, p := ExtractPointer[map[string]any](m, "")
+	require.True(t, o.IsPresent())
+	require.Equal(t, 0, len(p))
+	sl, p := ExtractPointer[[]any](m, "/foo")
+	require.True(t, sl.IsPresent())
+	require.Equal(t, []bool{true}, p)
+	s, p := ExtractPointer[string](m, "/foo/0")
+	require.True(t, s.IsPresent())
+	require.Equal(t, "bar", s.OrElse(""))
+	require.Equal(t, []bool{true, true}, p)
+	for ptr, expected := range map[string]int{
+		"/":     0,
+		"/a~1b": 1,
+		"/c%d":  2,
+		"/e^f":  3,
+		"/g|h":  4,
+		"/i\\j": 5,
+		"/k\"l": 6,
+		"/ ":    7,
+		"/m~0n": 8,
+	} {
+		i, p := ExtractPointer[int](m, ptr)
+		require.True(t, i.IsPresent(), ptr)
+		require.Equal(t, expected, i.OrElse(-1), ptr)
+		require.Equal(t, []bool{true}, p, ptr)
+	}
+
+	s, p = ExtractPointer[string](m, "/nested/arr/0/x")
+	require.True(t, s.IsPresent())
+	require.Equal(t, "X", s.OrElse(""))
+	require.Equal(t, []bool{true, true, true, true}, p)
+
+	s, p = ExtractPointer[string](m, "/nested/arr/1")
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{true, true, true}, p)
+
+	s, p = ExtractPointer[string](m, "/nested/arr/2/x")
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{true, true, false}, p)
+
+	s, p = ExtractPointer[string](m, "/nested/arr/-")
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{true, true, false}, p)
+
+	s, p = ExtractPointer[string](m, "/nested/arr/01")
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{true, true, false}, p)
+
+	s, p = ExtractPointer[string](m, "/nested/xxx/0")
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{true, false}, p)
+
+	s, p = ExtractPointer[string](m, "/foo/0/x")
+	require.False(t, s.IsPresent())
+	require.Equal(t, []bool{true, true, false}, p)
+
+	i, p := ExtractPointer[int](m, "/foo/0")
+	require.False(t, i.IsPresent())
+	require.Equal(t, []bool{true, true}, p)
+
+	s, p = ExtractPointer[string](m, "foo")
+	require.False(t, s.IsPresent())
+	require.Nil(t, p)
+	s, p = ExtractPointer[string](m, "/m~2n")
+	require.False(t, s.IsPresent())
+	require.Nil(t, p)
+	s, p = ExtractPointer[string](m, "/m~")
+	require.False(t, s.IsPresent())
+	require.Nil(t, p)
+}
+
+func TestExtractPointer_WithConverters(t *testing.T) {
+	m := map[string]any{
+		"foo": []any{1, 2},
+	}
+	intToStr := func(v any) (string, bool) {
+		if i, ok := v.(int); ok {
+			return fmt.Sprintf("%d", i), true
+		}
+		return "", false
+	}
+	s, _ := ExtractPointer[string](m, "/foo/1")
+	require.False(t, s.IsPresent())
+	s, _ = ExtractPointer[string](m, "/foo/1", intToStr)
+	require.True(t, s.IsPresent())
+	require.Equal(t, "2", s.OrElse(""))
+}
+
+func TestSetPointer(t *testing.T) {
+	m := map[string]any{
+		"foo": map[string]any{
+			"arr": []any{"a", "b"},
+		},
+	}
+	err := SetPointer(m, "/bar", 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, m["bar"])
+
+	err = SetPointer(m, "/foo/a~1b", 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, m["foo"].(map[string]any)["a/b"])
+
+	err = SetPointer(m, "/foo/arr/0", "A")
+	require.NoError(t, err)
+	require.Equal(t, []any{"A", "b"}, m["foo"].(map[string]any)["arr"])
+
+	err = SetPointer(m, "/foo/arr/-", "c")
+	require.NoError(t, err)
+	require.Equal(t, []any{"A", "b", "c"}, m["foo"].(map[string]any)["arr"])
+
+	err = SetPointer(m, "/foo/arr/3", "d")
+	require.NoError(t, err)
+	require.Equal(t, []any{"A", "b", "c", "d"}, m["foo"].(map[string]any)["arr"])
+
+	err = SetPointer(m, "/foo/arr/-/x", "d")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PointerNotFound))
+
+	err = SetPointer(m, "/foo/arr/5", "d")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PointerNotFound))
+
+	err = SetPointer(m, "/xxx/yyy", "d")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PointerNotFound))
+
+	err = SetPointer(m, "/bar/yyy", "d")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PointerNotFound))
+
+	err = SetPointer(m, "", "d")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, InvalidPointer))
+
+	err = SetPointer(m, "xxx", "d")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, InvalidPointer))
+
+	m2 := map[string]any{
+		"outer": []any{[]any{}},
+	}
+	err = SetPointer(m2, "/outer/0/-", 1)
+	require.NoError(t, err)
+	require.Equal(t, []any{[]any{1}}, m2["outer"])
+}
+
+func TestRemovePointer(t *testing.T) {
+	m := map[string]any{
+		"foo": map[string]any{
+			"arr": []any{"a", "b", "c"},
+			"m~n": 1,
+		},
+		"bar": 1,
+	}
+	err := RemovePointer(m, "/bar")
+	require.NoError(t, err)
+	_, ok := m["bar"]
+	require.False(t, ok)
+
+	err = RemovePointer(m, "/foo/m~0n")
+	require.NoError(t, err)
+	_, ok = m["foo"].(map[string]any)["m~n"]
+	require.False(t, ok)
+
+	err = RemovePointer(m, "/foo/arr/1")
+	require.NoError(t, err)
+	require.Equal(t, []any{"a", "c"}, m["foo"].(map[string]any)["arr"])
+
+	err = RemovePointer(m, "/foo/arr/-")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PointerNotFound))
+
+	err = RemovePointer(m, "/foo/arr/2")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PointerNotFound))
+
+	err = RemovePointer(m, "/xxx")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, PointerNotFound))
+
+	err = RemovePointer(m, "")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, InvalidPointer))
+
+	err = RemovePointer(m, "/~x")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, InvalidPointer))
+}
+
+func TestEscapePointerToken(t *testing.T) {
+	require.Equal(t, "a~1b~0c", EscapePointerToken("a/b~c"))
+	m := map[string]any{"a/b~c": 1}
+	i, _ := ExtractPointer[int](m, "/"+EscapePointerToken("a/b~c"))
+	require.True(t, i.IsPresent())
+}
@@ -0,0 +1,176 @@
+package gopt
+
+import (
+	"math"
+	"reflect"
+	"strings"
+)
+
+// PresencePolicy determines whether a value is to be considered present
+//
+// The default policy used throughout is NilOnly - a policy can be selected using OfWith, Optional.SetPolicy, ExtractWith,
+// GetWith and OptMap.GetWith
+type PresencePolicy interface {
+	// IsPresent returns true if the supplied value is to be considered present
+	IsPresent(v any) bool
+}
+
+// PresencePolicyFunc is a function adapter for PresencePolicy - allowing user-defined policies
+//
+// Note: func values are not comparable - so optionals using a PresencePolicyFunc policy cannot be compared using ==
+// (implement PresencePolicy on a comparable type if that is required)
+type PresencePolicyFunc func(v any) bool
+
+// IsPresent implements PresencePolicy.IsPresent
+func (f PresencePolicyFunc) IsPresent(v any) bool {
+	return f(v)
+}
+
+var (
+	// NilOnly is the default presence policy - nil values (nil pointers, maps, slices and interfaces) are not present
+	//
+	// Note: nil funcs and chans are present (use NilFuncOrChanIsEmpty to treat them as not present)
+	NilOnly PresencePolicy = nilOnly{}
+	// ZeroIsEmpty is a presence policy where nil values and zero values (e.g. 0, "", false or empty struct - including
+	// pointers to zero values) are not present
+	ZeroIsEmpty PresencePolicy = zeroIsEmpty{}
+	// BlankStringIsEmpty is a presence policy where nil values and blank strings (empty or only whitespace) are not present
+	BlankStringIsEmpty PresencePolicy = blankStringIsEmpty{}
+	// NaNIsEmpty is a presence policy where nil values and NaN floats are not present
+	NaNIsEmpty PresencePolicy = nanIsEmpty{}
+	// EmptyCollectionIsEmpty is a presence policy where nil values and empty maps, slices and arrays are not present
+	EmptyCollectionIsEmpty PresencePolicy = emptyCollectionIsEmpty{}
+	// NilFuncOrChanIsEmpty is a presence policy where nil values, including nil funcs and chans, are not present
+	NilFuncOrChanIsEmpty PresencePolicy = nilFuncOrChanIsEmpty{}
+)
+
+type nilOnly struct{}
+
+func (nilOnly) IsPresent(v any) bool {
+	return isPresent(v)
+}
+
+type zeroIsEmpty struct{}
+
+func (zeroIsEmpty) IsPresent(v any) bool {
+	return isPresent(v) && !indirectValue(v).IsZero()
+}
+
+type blankStringIsEmpty struct{}
+
+func (blankStringIsEmpty) IsPresent(v any) bool {
+	if !isPresent(v) {
+		return false
+	}
+	vo := indirectValue(v)
+	return vo.Kind() != reflect.String || strings.TrimSpace(vo.String()) != ""
+}
+
+type nanIsEmpty struct{}
+
+func (nanIsEmpty) IsPresent(v any) bool {
+	if !isPresent(v) {
+		return false
+	}
+	vo := indirectValue(v)
+	switch vo.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return !math.IsNaN(vo.Float())
+	}
+	return true
+}
+
+type emptyCollectionIsEmpty struct{}
+
+func (emptyCollectionIsEmpty) IsPresent(v any) bool {
+	if !isPresent(v) {
+		return false
+	}
+	vo := indirectValue(v)
+	switch vo.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return vo.Len() > 0
+	}
+	return true
+}
+
+type nilFuncOrChanIsEmpty struct{}
+
+func (nilFuncOrChanIsEmpty) IsPresent(v any) bool {
+	vo := reflect.ValueOf(v)
+	switch vo.Kind() {
+	case reflect.Func, reflect.Chan:
+		return !vo.IsNil()
+	}
+	return isPresent(v)
+}
+
+// CombinePolicies creates a presence policy from the supplied policies - where a value is only considered present
+// if all the supplied policies consider it present
+func CombinePolicies(policies ...PresencePolicy) PresencePolicy {
+	return &combinedPolicies{
+		policies: policies,
+	}
+}
+
+type combinedPolicies struct {
+	policies []PresencePolicy
+}
+
+func (c *combinedPolicies) IsPresent(v any) bool {
+	for _, policy := range c.policies {
+		if policy != nil && !policy.IsPresent(v) {
+			return false
+		}
+	}
+	return isPresent(v)
+}
+
+// OfWith creates a new optional with the supplied value, where presence is determined by the supplied presence policy
+//
+// The policy is retained by the optional and used for subsequent setting operations (UnmarshalJSON, Scan, OrElseSet etc.)
+//
+// If the supplied policy is nil, the NilOnly policy is used
+func OfWith[T any](policy PresencePolicy, value T) *Optional[T] {
+	policy = presencePolicy(policy)
+	if policy.IsPresent(value) {
+		return &Optional[T]{
+			present: true,
+			value:   value,
+			policy:  policy,
+		}
+	}
+	return &Optional[T]{
+		present: false,
+		policy:  policy,
+	}
+}
+
+func presencePolicy(policy PresencePolicy) PresencePolicy {
+	if policy == nil {
+		return NilOnly
+	}
+	return policy
+}
+
+func isPresent(v any) bool {
+	vo := reflect.ValueOf(v)
+	switch vk := vo.Kind(); vk {
+	case reflect.Ptr:
+		vk = vo.Elem().Kind()
+		if vk == reflect.Invalid {
+			return false
+		}
+	case reflect.Map, reflect.Slice, reflect.Interface:
+		return !vo.IsNil()
+	}
+	return v != nil
+}
+
+func indirectValue(v any) reflect.Value {
+	vo := reflect.ValueOf(v)
+	for vo.Kind() == reflect.Ptr && !vo.IsNil() {
+		vo = vo.Elem()
+	}
+	return vo
+}
@@ -0,0 +1,187 @@
+package gopt
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestNilOnly(t *testing.T) {
+	var nilFunc func()
+	var nilChan chan int
+	var nilPtr *myStruct
+	var nilMap map[string]any
+	var nilSlice []string
+	require.False(t, NilOnly.IsPresent(nil))
+	require.True(t, NilOnly.IsPresent(nilFunc))
+	require.True(t, NilOnly.IsPresent(nilChan))
+	require.False(t, NilOnly.IsPresent(nilPtr))
+	require.False(t, NilOnly.IsPresent(nilMap))
+	require.False(t, NilOnly.IsPresent(nilSlice))
+	require.True(t, NilOnly.IsPresent(func() {}))
+	require.True(t, NilOnly.IsPresent(make(chan int)))
+	require.True(t, NilOnly.IsPresent(""))
+	require.True(t, NilOnly.IsPresent(0))
+	require.True(t, NilOnly.IsPresent(math.NaN()))
+	require.True(t, NilOnly.IsPresent([]string{}))
+}
+
+func TestNilFuncOrChanIsEmpty(t *testing.T) {
+	var nilFunc func()
+	var nilChan chan int
+	var nilPtr *myStruct
+	require.False(t, NilFuncOrChanIsEmpty.IsPresent(nil))
+	require.False(t, NilFuncOrChanIsEmpty.IsPresent(nilFunc))
+	require.False(t, NilFuncOrChanIsEmpty.IsPresent(nilChan))
+	require.False(t, NilFuncOrChanIsEmpty.IsPresent(nilPtr))
+	require.True(t, NilFuncOrChanIsEmpty.IsPresent(func() {}))
+	require.True(t, NilFuncOrChanIsEmpty.IsPresent(make(chan int)))
+	require.True(t, NilFuncOrChanIsEmpty.IsPresent(0))
+	require.False(t, OfWith(NilFuncOrChanIsEmpty, nilFunc).IsPresent())
+	require.True(t, Of(nilFunc).IsPresent())
+}
+
+func TestZeroIsEmpty(t *testing.T) {
+	require.False(t, ZeroIsEmpty.IsPresent(nil))
+	require.False(t, ZeroIsEmpty.IsPresent(""))
+	require.False(t, ZeroIsEmpty.IsPresent(0))
+	require.False(t, ZeroIsEmpty.IsPresent(0.0))
+	require.False(t, ZeroIsEmpty.IsPresent(false))
+	require.False(t, ZeroIsEmpty.IsPresent(myStruct{}))
+	require.True(t, ZeroIsEmpty.IsPresent("a"))
+	require.True(t, ZeroIsEmpty.IsPresent(1))
+	require.True(t, ZeroIsEmpty.IsPresent(true))
+	require.True(t, ZeroIsEmpty.IsPresent(myStruct{Foo: "a"}))
+	// pointers are dereferenced (as with the other built-in policies)...
+	zero, one := 0, 1
+	require.False(t, ZeroIsEmpty.IsPresent(&zero))
+	require.False(t, ZeroIsEmpty.IsPresent(&myStruct{}))
+	require.True(t, ZeroIsEmpty.IsPresent(&one))
+	require.True(t, ZeroIsEmpty.IsPresent(&myStruct{Foo: "a"}))
+	var np *int
+	require.False(t, ZeroIsEmpty.IsPresent(np))
+}
+
+func TestBlankStringIsEmpty(t *testing.T) {
+	str := " \t"
+	type myString string
+	require.False(t, BlankStringIsEmpty.IsPresent(nil))
+	require.False(t, BlankStringIsEmpty.IsPresent(""))
+	require.False(t, BlankStringIsEmpty.IsPresent("   "))
+	require.False(t, BlankStringIsEmpty.IsPresent(&str))
+	require.False(t, BlankStringIsEmpty.IsPresent(myString(" ")))
+	require.True(t, BlankStringIsEmpty.IsPresent(" a "))
+	require.True(t, BlankStringIsEmpty.IsPresent(0))
+}
+
+func TestNaNIsEmpty(t *testing.T) {
+	nan := math.NaN()
+	require.False(t, NaNIsEmpty.IsPresent(nil))
+	require.False(t, NaNIsEmpty.IsPresent(nan))
+	require.False(t, NaNIsEmpty.IsPresent(float32(nan)))
+	require.False(t, NaNIsEmpty.IsPresent(&nan))
+	require.True(t, NaNIsEmpty.IsPresent(0.0))
+	require.True(t, NaNIsEmpty.IsPresent(math.Inf(1)))
+	require.True(t, NaNIsEmpty.IsPresent("NaN"))
+}
+
+func TestEmptyCollectionIsEmpty(t *testing.T) {
+	require.False(t, EmptyCollectionIsEmpty.IsPresent(nil))
+	require.False(t, EmptyCollectionIsEmpty.IsPresent([]string{}))
+	require.False(t, EmptyCollectionIsEmpty.IsPresent(map[string]any{}))
+	require.False(t, EmptyCollectionIsEmpty.IsPresent([0]int{}))
+	require.False(t, EmptyCollectionIsEmpty.IsPresent(&[]int{}))
+	require.True(t, EmptyCollectionIsEmpty.IsPresent([]string{""}))
+	require.True(t, EmptyCollectionIsEmpty.IsPresent(map[string]any{"foo": nil}))
+	require.True(t, EmptyCollectionIsEmpty.IsPresent(""))
+}
+
+func TestCombinePolicies(t *testing.T) {
+	p := CombinePolicies(BlankStringIsEmpty, EmptyCollectionIsEmpty, nil)
+	require.False(t, p.IsPresent(nil))
+	require.False(t, p.IsPresent(" "))
+	require.False(t, p.IsPresent([]int{}))
+	require.True(t, p.IsPresent("a"))
+	require.True(t, p.IsPresent([]int{1}))
+	require.True(t, p.IsPresent(0))
+
+	p = CombinePolicies()
+	require.False(t, p.IsPresent(nil))
+	require.True(t, p.IsPresent(""))
+}
+
+func TestPresencePolicyFunc(t *testing.T) {
+	positive := PresencePolicyFunc(func(v any) bool {
+		i, ok := v.(int)
+		return ok && i > 0
+	})
+	require.True(t, OfWith[int](positive, 1).IsPresent())
+	require.False(t, OfWith[int](positive, 0).IsPresent())
+	require.False(t, OfWith[int](positive, -1).IsPresent())
+}
+
+func TestOfWith(t *testing.T) {
+	o := OfWith(BlankStringIsEmpty, " ")
+	require.False(t, o.IsPresent())
+	require.Equal(t, "", o.OrElse(""))
+	require.Equal(t, BlankStringIsEmpty, o.Policy())
+	o.OrElseSet("")
+	require.False(t, o.IsPresent())
+	require.True(t, o.WasSet())
+	o.OrElseSet("a")
+	require.True(t, o.IsPresent())
+	require.Equal(t, "a", o.OrElse(""))
+
+	o = OfWith(nil, "")
+	require.True(t, o.IsPresent())
+	require.Equal(t, NilOnly, o.Policy())
+
+	oe := OfWith(ZeroIsEmpty, 1).AsEmpty()
+	require.False(t, oe.IsPresent())
+	require.Equal(t, ZeroIsEmpty, oe.Policy())
+}
+
+func TestPresencePolicy_Decoding(t *testing.T) {
+	type request struct {
+		Name  Optional[string]   `json:"name"`
+		Score Optional[float64]  `json:"score"`
+		Tags  Optional[[]string] `json:"tags"`
+	}
+	req := &request{}
+	req.Name.SetPolicy(BlankStringIsEmpty)
+	req.Tags.SetPolicy(EmptyCollectionIsEmpty)
+	err := json.Unmarshal([]byte(`{"name":"  ","score":0,"tags":[]}`), req)
+	require.NoError(t, err)
+	require.False(t, req.Name.IsPresent())
+	require.True(t, req.Name.WasSet())
+	require.True(t, req.Score.IsPresent())
+	require.False(t, req.Tags.IsPresent())
+	require.True(t, req.Tags.WasSet())
+
+	o := Empty[string]().SetPolicy(BlankStringIsEmpty)
+	err = o.Scan("")
+	require.NoError(t, err)
+	require.False(t, o.IsPresent())
+	require.True(t, o.WasSet())
+	err = o.Scan([]byte(" "))
+	require.NoError(t, err)
+	require.False(t, o.IsPresent())
+	err = o.Scan([]byte("abc"))
+	require.NoError(t, err)
+	require.True(t, o.IsPresent())
+
+	of := Empty[float64]().SetPolicy(NaNIsEmpty)
+	err = of.Scan(math.NaN())
+	require.NoError(t, err)
+	require.False(t, of.IsPresent())
+	require.True(t, of.WasSet())
+
+	om := Empty[map[string]any]().SetPolicy(EmptyCollectionIsEmpty)
+	err = om.Scan([]byte(`{}`))
+	require.NoError(t, err)
+	require.False(t, om.IsPresent())
+	err = om.Scan([]byte(`{"foo":1}`))
+	require.NoError(t, err)
+	require.True(t, om.IsPresent())
+}
@@ -0,0 +1,1302 @@
+package gopt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Query evaluates the supplied JSONPath query expression (RFC 9535) against a map[string]any and returns
+// the values, of the specified type, of the resulting nodes
+//
+// The map is typically the result of unmarshalling JSON - i.e. a tree of map[string]any and []any.  For example, given a map of:
+//   m := map[string]any{
+//     "orders": []any{
+//       map[string]any{"id": "A", "status": "open"},
+//       map[string]any{"id": "B", "status": "closed"},
+//     },
+//   }
+// then using:
+//   ids, _ := Query[string](m, "$.orders[?@.status=='open'].id")
+// would yield []string{"A"}
+//
+// Nodes whose value is nil (null), or is not of the specified type (and cannot be converted by any of the supplied converters),
+// are omitted from the result
+//
+// Object members are visited in key order (RFC 9535 does not specify an order for object members)
+//
+// If the supplied query expression is invalid, a *QuerySyntaxError is returned
+func Query[T any](m map[string]any, expr string, converters ...Converter[T]) ([]T, error) {
+	q, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	nodes := q.evaluate(m)
+	result := make([]T, 0, len(nodes))
+	for _, node := range nodes {
+		if v, ok := convertValue[T](node, converters...); ok {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// QueryFirst evaluates the supplied JSONPath query expression (RFC 9535) against a map[string]any and returns an optional
+// of the first resulting node value of the specified type (see Query)
+//
+// If no resulting node value is of the specified type (or can be converted by any of the supplied converters), an empty optional is returned
+//
+// If the supplied query expression is invalid, a *QuerySyntaxError is returned (along with an empty optional)
+func QueryFirst[T any](m map[string]any, expr string, converters ...Converter[T]) (*Optional[T], error) {
+	q, err := parseQuery(expr)
+	if err != nil {
+		return Empty[T](), err
+	}
+	for _, node := range q.evaluate(m) {
+		if v, ok := convertValue[T](node, converters...); ok {
+			return Of[T](v), nil
+		}
+	}
+	return Empty[T](), nil
+}
+
+// QuerySyntaxError is the error returned by Query and QueryFirst when the query expression is invalid
+type QuerySyntaxError struct {
+	// Query is the query expression
+	Query string
+	// Position is the (byte) position in the query expression at which the error was detected
+	Position int
+	// Message describes the error
+	Message string
+}
+
+// Error implements error
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("invalid JSONPath query %q at position %d: %s", e.Query, e.Position, e.Message)
+}
+
+func convertValue[T any](value any, converters ...Converter[T]) (T, bool) {
+	if isPresent(value) {
+		if v, ok := value.(T); ok {
+			return v, true
+		}
+		return runConverters[T](value, converters...)
+	}
+	var zero T
+	return zero, false
+}
+
+// jsonPath is a parsed JSONPath query
+type jsonPath struct {
+	relative bool
+	segments []jsonPathSegment
+}
+
+type jsonPathSegment struct {
+	descendant bool
+	selectors  []jsonPathSelector
+}
+
+type jsonPathSelector interface {
+	selectNodes(v any, root any, nodes []any) []any
+}
+
+func (q *jsonPath) evaluate(root any) []any {
+	return q.evaluateFrom(root, root)
+}
+
+func (q *jsonPath) evaluateFrom(curr any, root any) []any {
+	if !q.relative {
+		curr = root
+	}
+	nodes := []any{curr}
+	for _, seg := range q.segments {
+		next := make([]any, 0, len(nodes))
+		for _, node := range nodes {
+			if seg.descendant {
+				next = seg.selectDescendants(node, root, next)
+			} else {
+				for _, sel := range seg.selectors {
+					next = sel.selectNodes(node, root, next)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (q *jsonPath) isSingular() bool {
+	for _, seg := range q.segments {
+		if seg.descendant || len(seg.selectors) != 1 {
+			return false
+		}
+		switch seg.selectors[0].(type) {
+		case *jsonPathName, *jsonPathIndex:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (seg jsonPathSegment) selectDescendants(v any, root any, nodes []any) []any {
+	for _, sel := range seg.selectors {
+		nodes = sel.selectNodes(v, root, nodes)
+	}
+	for _, child := range childNodes(v) {
+		nodes = seg.selectDescendants(child, root, nodes)
+	}
+	return nodes
+}
+
+func childNodes(v any) []any {
+	switch vt := v.(type) {
+	case map[string]any:
+		result := make([]any, 0, len(vt))
+		for _, k := range sortedKeys(vt) {
+			result = append(result, vt[k])
+		}
+		return result
+	case []any:
+		return vt
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type jsonPathName struct {
+	name string
+}
+
+func (s *jsonPathName) selectNodes(v any, root any, nodes []any) []any {
+	if mv, ok := v.(map[string]any); ok {
+		if cv, ok := mv[s.name]; ok {
+			nodes = append(nodes, cv)
+		}
+	}
+	return nodes
+}
+
+type jsonPathWildcard struct{}
+
+func (s *jsonPathWildcard) selectNodes(v any, root any, nodes []any) []any {
+	return append(nodes, childNodes(v)...)
+}
+
+type jsonPathIndex struct {
+	index int
+}
+
+func (s *jsonPathIndex) selectNodes(v any, root any, nodes []any) []any {
+	if sv, ok := v.([]any); ok {
+		idx := s.index
+		if idx < 0 {
+			idx = len(sv) + idx
+		}
+		if idx >= 0 && idx < len(sv) {
+			nodes = append(nodes, sv[idx])
+		}
+	}
+	return nodes
+}
+
+type jsonPathSlice struct {
+	start *int
+	end   *int
+	step  int
+}
+
+func (s *jsonPathSlice) selectNodes(v any, root any, nodes []any) []any {
+	sv, ok := v.([]any)
+	if !ok || s.step == 0 {
+		return nodes
+	}
+	l := len(sv)
+	normalize := func(i int) int {
+		if i >= 0 {
+			return i
+		}
+		return l + i
+	}
+	bound := func(i, lower, upper int) int {
+		if i < lower {
+			return lower
+		} else if i > upper {
+			return upper
+		}
+		return i
+	}
+	if s.step > 0 {
+		start, end := 0, l
+		if s.start != nil {
+			start = bound(normalize(*s.start), 0, l)
+		}
+		if s.end != nil {
+			end = bound(normalize(*s.end), 0, l)
+		}
+		for i := start; i < end; i += s.step {
+			nodes = append(nodes, sv[i])
+		}
+	} else {
+		start, end := l-1, -1
+		if s.start != nil {
+			start = bound(normalize(*s.start), -1, l-1)
+		}
+		if s.end != nil {
+			end = bound(normalize(*s.end), -1, l-1)
+		}
+		for i := start; i > end; i += s.step {
+			nodes = append(nodes, sv[i])
+		}
+	}
+	return nodes
+}
+
+type jsonPathFilter struct {
+	expr jsonPathLogical
+}
+
+func (s *jsonPathFilter) selectNodes(v any, root any, nodes []any) []any {
+	for _, child := range childNodes(v) {
+		if s.expr.test(child, root) {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// jsonPathLogical is a filter expression that yields a logical result (LogicalType)
+type jsonPathLogical interface {
+	test(curr any, root any) bool
+}
+
+// jsonPathComparable is a filter expression that yields a value (ValueType) - ok is false when the value is Nothing
+type jsonPathComparable interface {
+	value(curr any, root any) (v any, ok bool)
+}
+
+type jsonPathOr []jsonPathLogical
+
+func (e jsonPathOr) test(curr any, root any) bool {
+	for _, sub := range e {
+		if sub.test(curr, root) {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonPathAnd []jsonPathLogical
+
+func (e jsonPathAnd) test(curr any, root any) bool {
+	for _, sub := range e {
+		if !sub.test(curr, root) {
+			return false
+		}
+	}
+	return true
+}
+
+type jsonPathNot struct {
+	expr jsonPathLogical
+}
+
+func (e *jsonPathNot) test(curr any, root any) bool {
+	return !e.expr.test(curr, root)
+}
+
+type jsonPathExists struct {
+	query *jsonPath
+}
+
+func (e *jsonPathExists) test(curr any, root any) bool {
+	return len(e.query.evaluateFrom(curr, root)) > 0
+}
+
+type jsonPathComparison struct {
+	op    string
+	left  jsonPathComparable
+	right jsonPathComparable
+}
+
+func (e *jsonPathComparison) test(curr any, root any) bool {
+	lv, lok := e.left.value(curr, root)
+	rv, rok := e.right.value(curr, root)
+	switch e.op {
+	case "==":
+		return jsonPathEqual(lv, lok, rv, rok)
+	case "!=":
+		return !jsonPathEqual(lv, lok, rv, rok)
+	case "<":
+		return jsonPathLess(lv, lok, rv, rok)
+	case "<=":
+		return jsonPathLess(lv, lok, rv, rok) || jsonPathEqual(lv, lok, rv, rok)
+	case ">":
+		return jsonPathLess(rv, rok, lv, lok)
+	default: // ">="
+		return jsonPathLess(rv, rok, lv, lok) || jsonPathEqual(lv, lok, rv, rok)
+	}
+}
+
+func jsonPathEqual(a any, aok bool, b any, bok bool) bool {
+	if !aok || !bok {
+		return aok == bok
+	}
+	return jsonValuesEqual(a, b)
+}
+
+func jsonValuesEqual(a any, b any) bool {
+	if an, ok := jsonNumber(a); ok {
+		bn, ok := jsonNumber(b)
+		return ok && an == bn
+	}
+	switch at := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		bs, ok := b.(string)
+		return ok && at == bs
+	case bool:
+		bb, ok := b.(bool)
+		return ok && at == bb
+	case []any:
+		bs, ok := b.([]any)
+		if !ok || len(at) != len(bs) {
+			return false
+		}
+		for i := range at {
+			if !jsonValuesEqual(at[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bm, ok := b.(map[string]any)
+		if !ok || len(at) != len(bm) {
+			return false
+		}
+		for k, av := range at {
+			if bv, ok := bm[k]; !ok || !jsonValuesEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func jsonPathLess(a any, aok bool, b any, bok bool) bool {
+	if !aok || !bok {
+		return false
+	}
+	if an, ok := jsonNumber(a); ok {
+		bn, ok := jsonNumber(b)
+		return ok && an < bn
+	} else if as, ok := a.(string); ok {
+		bs, ok := b.(string)
+		return ok && as < bs
+	}
+	return false
+}
+
+func jsonNumber(v any) (float64, bool) {
+	switch vt := v.(type) {
+	case float64:
+		return vt, true
+	case float32:
+		return float64(vt), true
+	case int:
+		return float64(vt), true
+	case int8:
+		return float64(vt), true
+	case int16:
+		return float64(vt), true
+	case int32:
+		return float64(vt), true
+	case int64:
+		return float64(vt), true
+	case uint:
+		return float64(vt), true
+	case uint8:
+		return float64(vt), true
+	case uint16:
+		return float64(vt), true
+	case uint32:
+		return float64(vt), true
+	case uint64:
+		return float64(vt), true
+	case json.Number:
+		if f, err := vt.Float64(); err == nil {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+type jsonPathLiteral struct {
+	v any
+}
+
+func (e *jsonPathLiteral) value(curr any, root any) (any, bool) {
+	return e.v, true
+}
+
+type jsonPathSingular struct {
+	query *jsonPath
+}
+
+func (e *jsonPathSingular) value(curr any, root any) (any, bool) {
+	if nodes := e.query.evaluateFrom(curr, root); len(nodes) == 1 {
+		return nodes[0], true
+	}
+	return nil, false
+}
+
+// jsonPathType is the type of a function extension parameter or result
+type jsonPathType int
+
+const (
+	jsonPathValueType jsonPathType = iota
+	jsonPathLogicalType
+	jsonPathNodesType
+)
+
+type jsonPathFunctionDef struct {
+	params []jsonPathType
+	result jsonPathType
+	// eval is called with evaluated args - ValueType args are jsonPathValue, LogicalType args are bool
+	// and NodesType args are []any.  The result must be of the same form
+	eval func(args []any) any
+}
+
+type jsonPathValue struct {
+	v  any
+	ok bool
+}
+
+var jsonPathFunctions = map[string]*jsonPathFunctionDef{
+	"length": {
+		params: []jsonPathType{jsonPathValueType},
+		result: jsonPathValueType,
+		eval: func(args []any) any {
+			if arg := args[0].(jsonPathValue); arg.ok {
+				switch vt := arg.v.(type) {
+				case string:
+					return jsonPathValue{v: float64(utf8.RuneCountInString(vt)), ok: true}
+				case []any:
+					return jsonPathValue{v: float64(len(vt)), ok: true}
+				case map[string]any:
+					return jsonPathValue{v: float64(len(vt)), ok: true}
+				}
+			}
+			return jsonPathValue{}
+		},
+	},
+	"count": {
+		params: []jsonPathType{jsonPathNodesType},
+		result: jsonPathValueType,
+		eval: func(args []any) any {
+			return jsonPathValue{v: float64(len(args[0].([]any))), ok: true}
+		},
+	},
+	"match": {
+		params: []jsonPathType{jsonPathValueType, jsonPathValueType},
+		result: jsonPathLogicalType,
+		eval: func(args []any) any {
+			return jsonPathRegexp(args, true)
+		},
+	},
+	"search": {
+		params: []jsonPathType{jsonPathValueType, jsonPathValueType},
+		result: jsonPathLogicalType,
+		eval: func(args []any) any {
+			return jsonPathRegexp(args, false)
+		},
+	},
+	"value": {
+		params: []jsonPathType{jsonPathNodesType},
+		result: jsonPathValueType,
+		eval: func(args []any) any {
+			if nodes := args[0].([]any); len(nodes) == 1 {
+				return jsonPathValue{v: nodes[0], ok: true}
+			}
+			return jsonPathValue{}
+		},
+	},
+}
+
+func jsonPathRegexp(args []any, full bool) bool {
+	s, sok := args[0].(jsonPathValue).v.(string)
+	p, pok := args[1].(jsonPathValue).v.(string)
+	if !sok || !pok {
+		return false
+	}
+	re, err := compileIRegexp(p, full)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(s)
+}
+
+// compileIRegexp compiles an I-Regexp (RFC 9485) - where "." matches any character except line terminators
+func compileIRegexp(pattern string, full bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	if full {
+		sb.WriteString(`\A(?:`)
+	}
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			sb.WriteByte(c)
+			i++
+			sb.WriteByte(pattern[i])
+		case c == '[':
+			inClass = true
+			sb.WriteByte(c)
+		case c == ']':
+			inClass = false
+			sb.WriteByte(c)
+		case c == '.' && !inClass:
+			sb.WriteString(`[^\n\r]`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if full {
+		sb.WriteString(`)\z`)
+	}
+	return regexp.Compile(sb.String())
+}
+
+type jsonPathFunction struct {
+	def  *jsonPathFunctionDef
+	args []jsonPathArg
+	// eval, if set, is used instead of def.eval - e.g. for match() and search() with a literal pattern, which is
+	// compiled once at parse time
+	eval func(args []any) any
+}
+
+// jsonPathArg evaluates a function argument according to the declared parameter type
+type jsonPathArg func(curr any, root any) any
+
+func (e *jsonPathFunction) call(curr any, root any) any {
+	args := make([]any, len(e.args))
+	for i, arg := range e.args {
+		args[i] = arg(curr, root)
+	}
+	if e.eval != nil {
+		return e.eval(args)
+	}
+	return e.def.eval(args)
+}
+
+// literalRegexp sets the function's eval to use a pre-compiled regexp when the function is match() or search() and
+// the pattern argument is a string literal (a pattern that is not a valid I-Regexp never matches)
+func (e *jsonPathFunction) literalRegexp(name string, operands []any) {
+	if name != "match" && name != "search" {
+		return
+	}
+	lit, ok := operands[1].(*jsonPathLiteral)
+	if !ok {
+		return
+	}
+	pattern, ok := lit.v.(string)
+	if !ok {
+		return
+	}
+	re, err := compileIRegexp(pattern, name == "match")
+	e.eval = func(args []any) any {
+		s, ok := args[0].(jsonPathValue).v.(string)
+		return ok && err == nil && re.MatchString(s)
+	}
+}
+
+func (e *jsonPathFunction) value(curr any, root any) (any, bool) {
+	r := e.call(curr, root).(jsonPathValue)
+	return r.v, r.ok
+}
+
+func (e *jsonPathFunction) test(curr any, root any) bool {
+	switch r := e.call(curr, root).(type) {
+	case bool:
+		return r
+	case []any:
+		return len(r) > 0
+	}
+	return false
+}
+
+const maxQueryInt = 1<<53 - 1
+
+// jsonPathParser is a recursive descent parser for JSONPath query expressions (see RFC 9535 ABNF)
+type jsonPathParser struct {
+	expr string
+	pos  int
+}
+
+func parseQuery(expr string) (*jsonPath, error) {
+	p := &jsonPathParser{expr: expr}
+	if p.peek() != '$' {
+		return nil, p.error("expected '$'")
+	}
+	q, err := p.parseQuery()
+	if err == nil && p.pos < len(p.expr) {
+		err = p.error("unexpected character")
+	}
+	return q, err
+}
+
+func (p *jsonPathParser) error(msg string) error {
+	return &QuerySyntaxError{
+		Query:    p.expr,
+		Position: p.pos,
+		Message:  msg,
+	}
+}
+
+func (p *jsonPathParser) eof() bool {
+	return p.pos >= len(p.expr)
+}
+
+func (p *jsonPathParser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+func (p *jsonPathParser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.expr[p.pos:], s)
+}
+
+func (p *jsonPathParser) skipSpace() {
+	for p.pos < len(p.expr) {
+		switch p.expr[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsonPathParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.error(fmt.Sprintf("expected '%c'", c))
+	}
+	p.pos++
+	return nil
+}
+
+func (p *jsonPathParser) parseQuery() (*jsonPath, error) {
+	q := &jsonPath{}
+	switch p.peek() {
+	case '$':
+	case '@':
+		q.relative = true
+	default:
+		return nil, p.error("expected '$'")
+	}
+	p.pos++
+	for {
+		save := p.pos
+		p.skipSpace()
+		if p.hasPrefix("..") {
+			p.pos += 2
+			seg, err := p.parseDescendantSegment()
+			if err != nil {
+				return nil, err
+			}
+			q.segments = append(q.segments, seg)
+		} else if p.peek() == '.' {
+			p.pos++
+			seg, err := p.parseDotSegment()
+			if err != nil {
+				return nil, err
+			}
+			q.segments = append(q.segments, seg)
+		} else if p.peek() == '[' {
+			seg, err := p.parseBracketedSelection()
+			if err != nil {
+				return nil, err
+			}
+			q.segments = append(q.segments, seg)
+		} else {
+			p.pos = save
+			return q, nil
+		}
+	}
+}
+
+func (p *jsonPathParser) parseDotSegment() (jsonPathSegment, error) {
+	if p.peek() == '*' {
+		p.pos++
+		return jsonPathSegment{selectors: []jsonPathSelector{&jsonPathWildcard{}}}, nil
+	}
+	name, err := p.parseMemberName()
+	return jsonPathSegment{selectors: []jsonPathSelector{&jsonPathName{name: name}}}, err
+}
+
+func (p *jsonPathParser) parseDescendantSegment() (jsonPathSegment, error) {
+	var seg jsonPathSegment
+	var err error
+	if p.peek() == '[' {
+		seg, err = p.parseBracketedSelection()
+	} else {
+		seg, err = p.parseDotSegment()
+	}
+	seg.descendant = true
+	return seg, err
+}
+
+func (p *jsonPathParser) parseMemberName() (string, error) {
+	start := p.pos
+	for p.pos < len(p.expr) {
+		r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+		if isNameFirst(r) || (p.pos > start && r >= '0' && r <= '9') {
+			p.pos += size
+		} else {
+			break
+		}
+	}
+	if p.pos == start {
+		return "", p.error("expected member name")
+	}
+	return p.expr[start:p.pos], nil
+}
+
+func isNameFirst(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_' ||
+		(r >= 0x80 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0x10FFFF && r != utf8.RuneError)
+}
+
+func (p *jsonPathParser) parseBracketedSelection() (jsonPathSegment, error) {
+	seg := jsonPathSegment{}
+	if err := p.expect('['); err != nil {
+		return seg, err
+	}
+	for {
+		p.skipSpace()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return seg, err
+		}
+		seg.selectors = append(seg.selectors, sel)
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.pos++
+		} else if p.peek() == ']' {
+			p.pos++
+			return seg, nil
+		} else {
+			return seg, p.error("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *jsonPathParser) parseSelector() (jsonPathSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		s, err := p.parseStringLiteral()
+		return &jsonPathName{name: s}, err
+	case c == '*':
+		p.pos++
+		return &jsonPathWildcard{}, nil
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		expr, err := p.parseLogicalOr()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathFilter{expr: expr}, nil
+	case c == ':' || c == '-' || (c >= '0' && c <= '9'):
+		return p.parseIndexOrSlice()
+	}
+	return nil, p.error("expected selector")
+}
+
+func (p *jsonPathParser) parseIndexOrSlice() (jsonPathSelector, error) {
+	var start, end *int
+	if p.peek() != ':' {
+		i, err := p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+		save := p.pos
+		p.skipSpace()
+		if p.peek() != ':' {
+			p.pos = save
+			return &jsonPathIndex{index: i}, nil
+		}
+		start = &i
+	}
+	p.pos++ // ':'
+	p.skipSpace()
+	if c := p.peek(); c == '-' || (c >= '0' && c <= '9') {
+		i, err := p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+		end = &i
+		p.skipSpace()
+	}
+	step := 1
+	if p.peek() == ':' {
+		p.pos++
+		p.skipSpace()
+		if c := p.peek(); c == '-' || (c >= '0' && c <= '9') {
+			i, err := p.parseInt()
+			if err != nil {
+				return nil, err
+			}
+			step = i
+		}
+	}
+	return &jsonPathSlice{start: start, end: end, step: step}, nil
+}
+
+func (p *jsonPathParser) parseInt() (int, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	digitsStart := p.pos
+	for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+		p.pos++
+	}
+	digits := p.expr[digitsStart:p.pos]
+	if digits == "" {
+		return 0, p.error("expected integer")
+	} else if len(digits) > 1 && digits[0] == '0' {
+		return 0, p.error("leading zeros not permitted in integer")
+	} else if digits == "0" && digitsStart > start {
+		return 0, p.error("negative zero not permitted")
+	}
+	i, err := strconv.ParseInt(p.expr[start:p.pos], 10, 64)
+	if err != nil || i > maxQueryInt || i < -maxQueryInt {
+		return 0, p.error("integer out of range")
+	}
+	return int(i), nil
+}
+
+func (p *jsonPathParser) parseStringLiteral() (string, error) {
+	quote := p.peek()
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.error("unterminated string literal")
+		}
+		c := p.expr[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '\\':
+			p.pos++
+			if err := p.parseEscape(quote, &sb); err != nil {
+				return "", err
+			}
+		case c < 0x20:
+			return "", p.error("invalid character in string literal")
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *jsonPathParser) parseEscape(quote byte, sb *strings.Builder) error {
+	if p.eof() {
+		return p.error("unterminated string literal")
+	}
+	c := p.expr[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'r':
+		sb.WriteByte('\r')
+	case 't':
+		sb.WriteByte('\t')
+	case '/', '\\':
+		sb.WriteByte(c)
+	case 'u':
+		r, err := p.parseHexChar()
+		if err != nil {
+			return err
+		}
+		if utf16.IsSurrogate(r) {
+			if r >= 0xDC00 || !p.hasPrefix(`\u`) {
+				return p.error("invalid surrogate in string literal")
+			}
+			p.pos += 2
+			r2, err := p.parseHexChar()
+			if err != nil {
+				return err
+			}
+			if r = utf16.DecodeRune(r, r2); r == utf8.RuneError {
+				return p.error("invalid surrogate pair in string literal")
+			}
+		}
+		sb.WriteRune(r)
+	default:
+		if c != quote {
+			return p.error("invalid escape in string literal")
+		}
+		sb.WriteByte(c)
+	}
+	return nil
+}
+
+func (p *jsonPathParser) parseHexChar() (rune, error) {
+	if p.pos+4 > len(p.expr) {
+		return 0, p.error("invalid unicode escape in string literal")
+	}
+	v, err := strconv.ParseUint(p.expr[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, p.error("invalid unicode escape in string literal")
+	}
+	p.pos += 4
+	return rune(v), nil
+}
+
+func (p *jsonPathParser) parseLogicalOr() (jsonPathLogical, error) {
+	first, err := p.parseLogicalAnd()
+	if err != nil {
+		return nil, err
+	}
+	result := jsonPathOr{first}
+	for {
+		save := p.pos
+		p.skipSpace()
+		if !p.hasPrefix("||") {
+			p.pos = save
+			break
+		}
+		p.pos += 2
+		p.skipSpace()
+		next, err := p.parseLogicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, next)
+	}
+	if len(result) == 1 {
+		return first, nil
+	}
+	return result, nil
+}
+
+func (p *jsonPathParser) parseLogicalAnd() (jsonPathLogical, error) {
+	first, err := p.parseBasicExpr()
+	if err != nil {
+		return nil, err
+	}
+	result := jsonPathAnd{first}
+	for {
+		save := p.pos
+		p.skipSpace()
+		if !p.hasPrefix("&&") {
+			p.pos = save
+			break
+		}
+		p.pos += 2
+		p.skipSpace()
+		next, err := p.parseBasicExpr()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, next)
+	}
+	if len(result) == 1 {
+		return first, nil
+	}
+	return result, nil
+}
+
+func (p *jsonPathParser) parseBasicExpr() (jsonPathLogical, error) {
+	if p.peek() == '!' {
+		p.pos++
+		p.skipSpace()
+		operand, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		expr, err := p.asLogical(operand)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathNot{expr: expr}, nil
+	}
+	start := p.pos
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	save := p.pos
+	p.skipSpace()
+	if op := p.comparisonOp(); op != "" {
+		lc, err := p.asComparable(left, start)
+		if err != nil {
+			return nil, err
+		}
+		p.pos += len(op)
+		p.skipSpace()
+		rstart := p.pos
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		rc, err := p.asComparable(right, rstart)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathComparison{op: op, left: lc, right: rc}, nil
+	}
+	p.pos = save
+	return p.asLogicalAt(left, start)
+}
+
+func (p *jsonPathParser) comparisonOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.hasPrefix(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// parsePrimary parses a parenthesized logical expression, a literal, a filter query or a function expression
+//
+// the result is one of jsonPathLogical (parenthesized), *jsonPathLiteral, *jsonPath or *jsonPathFunction
+func (p *jsonPathParser) parsePrimary() (any, error) {
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+		p.skipSpace()
+		expr, err := p.parseLogicalOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if err = p.expect(')'); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case c == '$' || c == '@':
+		return p.parseQuery()
+	case c == '\'' || c == '"':
+		s, err := p.parseStringLiteral()
+		return &jsonPathLiteral{v: s}, err
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumberLiteral()
+	case c >= 'a' && c <= 'z':
+		return p.parseNameOrFunction()
+	}
+	return nil, p.error("expected expression")
+}
+
+func (p *jsonPathParser) parseNumberLiteral() (any, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	intStart := p.pos
+	for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+		p.pos++
+	}
+	if p.pos == intStart {
+		return nil, p.error("expected number")
+	} else if p.pos-intStart > 1 && p.expr[intStart] == '0' {
+		return nil, p.error("leading zeros not permitted in number")
+	}
+	if p.peek() == '.' {
+		p.pos++
+		fracStart := p.pos
+		for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+			p.pos++
+		}
+		if p.pos == fracStart {
+			return nil, p.error("expected fraction digits")
+		}
+	}
+	if c := p.peek(); c == 'e' || c == 'E' {
+		p.pos++
+		if c = p.peek(); c == '+' || c == '-' {
+			p.pos++
+		}
+		expStart := p.pos
+		for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+			p.pos++
+		}
+		if p.pos == expStart {
+			return nil, p.error("expected exponent digits")
+		}
+	}
+	f, err := strconv.ParseFloat(p.expr[start:p.pos], 64)
+	if err != nil || math.IsInf(f, 0) {
+		return nil, p.error("invalid number")
+	}
+	return &jsonPathLiteral{v: f}, nil
+}
+
+func (p *jsonPathParser) parseNameOrFunction() (any, error) {
+	start := p.pos
+	for c := p.peek(); (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_'; c = p.peek() {
+		p.pos++
+	}
+	name := p.expr[start:p.pos]
+	if p.peek() != '(' {
+		switch name {
+		case "true":
+			return &jsonPathLiteral{v: true}, nil
+		case "false":
+			return &jsonPathLiteral{v: false}, nil
+		case "null":
+			return &jsonPathLiteral{v: nil}, nil
+		}
+		p.pos = start
+		return nil, p.error("expected expression")
+	}
+	def, ok := jsonPathFunctions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.error(fmt.Sprintf("unknown function %q", name))
+	}
+	p.pos++ // '('
+	fn := &jsonPathFunction{def: def}
+	operands := make([]any, 0, len(def.params))
+	p.skipSpace()
+	for i := 0; p.peek() != ')'; i++ {
+		if i > 0 {
+			if err := p.expect(','); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+		}
+		if i >= len(def.params) {
+			return nil, p.error(fmt.Sprintf("too many arguments to function %q", name))
+		}
+		arg, operand, err := p.parseFunctionArg(def.params[i])
+		if err != nil {
+			return nil, err
+		}
+		fn.args = append(fn.args, arg)
+		operands = append(operands, operand)
+		p.skipSpace()
+	}
+	if len(fn.args) != len(def.params) {
+		return nil, p.error(fmt.Sprintf("too few arguments to function %q", name))
+	}
+	p.pos++ // ')'
+	fn.literalRegexp(name, operands)
+	return fn, nil
+}
+
+// parseFunctionArg parses a function argument - returning the argument and the parsed operand
+func (p *jsonPathParser) parseFunctionArg(paramType jsonPathType) (jsonPathArg, any, error) {
+	start := p.pos
+	var operand any
+	var err error
+	if paramType == jsonPathLogicalType {
+		operand, err = p.parseLogicalOr()
+	} else {
+		operand, err = p.parsePrimary()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	switch paramType {
+	case jsonPathValueType:
+		c, err := p.asComparable(operand, start)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(curr any, root any) any {
+			v, ok := c.value(curr, root)
+			return jsonPathValue{v: v, ok: ok}
+		}, operand, nil
+	case jsonPathNodesType:
+		if q, ok := operand.(*jsonPath); ok {
+			return func(curr any, root any) any {
+				return q.evaluateFrom(curr, root)
+			}, operand, nil
+		}
+		if fn, ok := operand.(*jsonPathFunction); ok && fn.def.result == jsonPathNodesType {
+			return func(curr any, root any) any {
+				return fn.call(curr, root)
+			}, operand, nil
+		}
+		p.pos = start
+		return nil, nil, p.error("function argument must be a query")
+	default:
+		l := operand.(jsonPathLogical)
+		return func(curr any, root any) any {
+			return l.test(curr, root)
+		}, operand, nil
+	}
+}
+
+func (p *jsonPathParser) asLogical(operand any) (jsonPathLogical, error) {
+	return p.asLogicalAt(operand, p.pos)
+}
+
+func (p *jsonPathParser) asLogicalAt(operand any, pos int) (jsonPathLogical, error) {
+	switch ot := operand.(type) {
+	case *jsonPath:
+		return &jsonPathExists{query: ot}, nil
+	case *jsonPathFunction:
+		if ot.def.result != jsonPathValueType {
+			return ot, nil
+		}
+		p.pos = pos
+		return nil, p.error("function result must be compared")
+	case *jsonPathLiteral:
+		p.pos = pos
+		return nil, p.error("literal must be compared")
+	}
+	return operand.(jsonPathLogical), nil
+}
+
+func (p *jsonPathParser) asComparable(operand any, pos int) (jsonPathComparable, error) {
+	switch ot := operand.(type) {
+	case *jsonPathLiteral:
+		return ot, nil
+	case *jsonPath:
+		if ot.isSingular() {
+			return &jsonPathSingular{query: ot}, nil
+		}
+		p.pos = pos
+		return nil, p.error("query in comparison must be a singular query")
+	case *jsonPathFunction:
+		if ot.def.result == jsonPathValueType {
+			return ot, nil
+		}
+		p.pos = pos
+		return nil, p.error("function result cannot be compared")
+	}
+	p.pos = pos
+	return nil, p.error("logical expression cannot be compared")
+}
@@ -0,0 +1,473 @@
+package gopt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	m := map[string]any{
+		"orders": []any{
+			map[string]any{"id": "A", "status": "open"},
+			map[string]any{"id": "B", "status": "closed"},
+			map[string]any{"id": "C", "status": "open"},
+			map[string]any{"id": nil, "status": "open"},
+		},
+	}
+	ids, err := Query[string](m, "$.orders[?@.status=='open'].id")
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "C"}, ids)
+
+	ids, err = Query[string](m, "$.orders[?@.status=='xxx'].id")
+	require.NoError(t, err)
+	require.Equal(t, 0, len(ids))
+
+	ints, err := Query[int](m, "$.orders[*].id")
+	require.NoError(t, err)
+	require.Equal(t, 0, len(ints))
+
+	_, err = Query[string](m, "$.orders[")
+	require.Error(t, err)
+	_, ok := err.(*QuerySyntaxError)
+	require.True(t, ok)
+}
+
+func TestQuery_WithConverters(t *testing.T) {
+	m := map[string]any{
+		"items": []any{
+			map[string]any{"qty": float64(1)},
+			map[string]any{"qty": float64(2)},
+			map[string]any{"qty": "3"},
+		},
+	}
+	fltToInt := func(v any) (int, bool) {
+		if f, ok := v.(float64); ok {
+			return int(f), true
+		}
+		return 0, false
+	}
+	qtys, err := Query[int](m, "$.items[*].qty")
+	require.NoError(t, err)
+	require.Equal(t, 0, len(qtys))
+	qtys, err = Query[int](m, "$.items[*].qty", fltToInt)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, qtys)
+}
+
+func TestQueryFirst(t *testing.T) {
+	m := map[string]any{
+		"orders": []any{
+			map[string]any{"id": 1, "status": "closed"},
+			map[string]any{"id": "B", "status": "open"},
+			map[string]any{"id": "C", "status": "open"},
+		},
+	}
+	o, err := QueryFirst[string](m, "$.orders[?@.status=='open'].id")
+	require.NoError(t, err)
+	require.True(t, o.IsPresent())
+	require.Equal(t, "B", o.OrElse(""))
+
+	o, err = QueryFirst[string](m, "$.orders[*].id")
+	require.NoError(t, err)
+	require.Equal(t, "B", o.OrElse(""))
+
+	o, err = QueryFirst[string](m, "$.orders[?@.status=='xxx'].id")
+	require.NoError(t, err)
+	require.False(t, o.IsPresent())
+
+	intToStr := func(v any) (string, bool) {
+		if i, ok := v.(int); ok {
+			return fmt.Sprintf("%d", i), true
+		}
+		return "", false
+	}
+	o, err = QueryFirst[string](m, "$.orders[*].id", intToStr)
+	require.NoError(t, err)
+	require.Equal(t, "1", o.OrElse(""))
+
+	o, err = QueryFirst[string](m, "orders")
+	require.Error(t, err)
+	require.NotNil(t, o)
+	require.False(t, o.IsPresent())
+}
+
+func TestQuerySyntaxError(t *testing.T) {
+	_, err := Query[any](map[string]any{}, "$.foo[01]")
+	require.Error(t, err)
+	qse, ok := err.(*QuerySyntaxError)
+	require.True(t, ok)
+	require.Equal(t, "$.foo[01]", qse.Query)
+	require.Equal(t, 8, qse.Position)
+	require.Equal(t, `invalid JSONPath query "$.foo[01]" at position 8: leading zeros not permitted in integer`, err.Error())
+}
+
+const rfcBookstore = `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+
+type rfcQueryTest struct {
+	query    string
+	expected string
+	ordered  bool
+}
+
+func runRfcQueryTests(t *testing.T, doc string, tests []rfcQueryTest) {
+	var root any
+	require.NoError(t, json.Unmarshal([]byte(doc), &root))
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("[%d]%s", i+1, tc.query), func(t *testing.T) {
+			q, err := parseQuery(tc.query)
+			require.NoError(t, err)
+			var expected []any
+			require.NoError(t, json.Unmarshal([]byte(tc.expected), &expected))
+			actual := q.evaluate(root)
+			if tc.ordered {
+				require.Equal(t, expected, actual)
+			} else {
+				require.ElementsMatch(t, expected, actual)
+			}
+		})
+	}
+}
+
+func TestQuery_RfcBookstore(t *testing.T) {
+	runRfcQueryTests(t, rfcBookstore, []rfcQueryTest{
+		{query: `$.store.book[*].author`, expected: `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`, ordered: true},
+		{query: `$..author`, expected: `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+		{query: `$.store.*`, expected: `[{"color":"red","price":399},[
+			{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},
+			{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},
+			{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},
+			{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]]`},
+		{query: `$.store..price`, expected: `[399,8.95,12.99,8.99,22.99]`},
+		{query: `$..book[2]`, expected: `[{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99}]`},
+		{query: `$..book[2].author`, expected: `["Herman Melville"]`},
+		{query: `$..book[2].publisher`, expected: `[]`},
+		{query: `$..book[-1]`, expected: `[{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]`},
+		{query: `$..book[0,1]`, expected: `[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99}]`, ordered: true},
+		{query: `$..book[:2]`, expected: `[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99}]`, ordered: true},
+		{query: `$..book[?@.isbn]`, expected: `[{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}]`, ordered: true},
+		{query: `$..book[?@.price<10]`, expected: `[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99}]`, ordered: true},
+	})
+
+	var root any
+	require.NoError(t, json.Unmarshal([]byte(rfcBookstore), &root))
+	q, err := parseQuery(`$..*`)
+	require.NoError(t, err)
+	require.Equal(t, 27, len(q.evaluate(root)))
+}
+
+func TestQuery_RfcNameSelector(t *testing.T) {
+	runRfcQueryTests(t, `{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, []rfcQueryTest{
+		{query: `$.o['j j']`, expected: `[{"k.k":3}]`},
+		{query: `$.o['j j']['k.k']`, expected: `[3]`},
+		{query: `$.o["j j"]["k.k"]`, expected: `[3]`},
+		{query: `$["'"]["@"]`, expected: `[2]`},
+		{query: `$['\'']['@']`, expected: `[2]`},
+		{query: `$["'"]`, expected: `[{"@":2}]`},
+	})
+}
+
+func TestQuery_RfcWildcardSelector(t *testing.T) {
+	runRfcQueryTests(t, `{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, []rfcQueryTest{
+		{query: `$[*]`, expected: `[{"j":1,"k":2},[5,3]]`},
+		{query: `$.o[*]`, expected: `[1,2]`},
+		{query: `$.o[*, *]`, expected: `[1,2,1,2]`},
+		{query: `$.a[*]`, expected: `[5,3]`, ordered: true},
+	})
+}
+
+func TestQuery_RfcIndexSelector(t *testing.T) {
+	runRfcQueryTests(t, `["a","b"]`, []rfcQueryTest{
+		{query: `$[1]`, expected: `["b"]`},
+		{query: `$[-2]`, expected: `["a"]`},
+		{query: `$[2]`, expected: `[]`},
+		{query: `$[-3]`, expected: `[]`},
+	})
+}
+
+func TestQuery_RfcSliceSelector(t *testing.T) {
+	runRfcQueryTests(t, `["a", "b", "c", "d", "e", "f", "g"]`, []rfcQueryTest{
+		{query: `$[1:3]`, expected: `["b","c"]`, ordered: true},
+		{query: `$[5:]`, expected: `["f","g"]`, ordered: true},
+		{query: `$[1:5:2]`, expected: `["b","d"]`, ordered: true},
+		{query: `$[5:1:-2]`, expected: `["f","d"]`, ordered: true},
+		{query: `$[::-1]`, expected: `["g","f","e","d","c","b","a"]`, ordered: true},
+		{query: `$[::0]`, expected: `[]`},
+		{query: `$[-2:]`, expected: `["f","g"]`, ordered: true},
+		{query: `$[-100:2]`, expected: `["a","b"]`, ordered: true},
+		{query: `$[ 1 : 3 : 1 ]`, expected: `["b","c"]`, ordered: true},
+	})
+}
+
+const rfcFilterDoc = `{
+  "a": [3, 5, 1, 2, 4, 6,
+        {"b": "j"},
+        {"b": "k"},
+        {"b": {}},
+        {"b": "kilo"}
+       ],
+  "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}},
+  "e": "f"
+}`
+
+func TestQuery_RfcFilterSelector(t *testing.T) {
+	runRfcQueryTests(t, rfcFilterDoc, []rfcQueryTest{
+		{query: `$.a[?@.b == 'kilo']`, expected: `[{"b":"kilo"}]`},
+		{query: `$.a[?(@.b == 'kilo')]`, expected: `[{"b":"kilo"}]`},
+		{query: `$.a[?@>3.5]`, expected: `[5,4,6]`, ordered: true},
+		{query: `$.a[?@.b]`, expected: `[{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]`, ordered: true},
+		{query: `$[?@.*]`, expected: `[[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}],{"p":1,"q":2,"r":3,"s":5,"t":{"u":6}}]`},
+		{query: `$[?@[?@.b]]`, expected: `[[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]]`},
+		{query: `$.o[?@<3, ?@<3]`, expected: `[1,2,1,2]`},
+		{query: `$.a[?@<2 || @.b == "k"]`, expected: `[1,{"b":"k"}]`, ordered: true},
+		{query: `$.a[?match(@.b, "[jk]")]`, expected: `[{"b":"j"},{"b":"k"}]`, ordered: true},
+		{query: `$.a[?search(@.b, "[jk]")]`, expected: `[{"b":"j"},{"b":"k"},{"b":"kilo"}]`, ordered: true},
+		{query: `$.o[?@>1 && @<4]`, expected: `[2,3]`},
+		{query: `$.o[?@.u || @.x]`, expected: `[{"u":6}]`},
+		{query: `$.a[?@.b == $.x]`, expected: `[3,5,1,2,4,6]`, ordered: true},
+		{query: `$.a[?@ == @]`, expected: `[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]`, ordered: true},
+		{query: `$.a[?!@.b]`, expected: `[3,5,1,2,4,6]`, ordered: true},
+		{query: `$.a[?!(@ > 2)]`, expected: `[1,2,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]`, ordered: true},
+	})
+}
+
+func TestQuery_RfcComparisons(t *testing.T) {
+	doc := `{"obj": {"x": "y"}, "arr": [2, 3]}`
+	tests := map[string]bool{
+		`$.absent1 == $.absent2`: true,
+		`$.absent1 <= $.absent2`: true,
+		`$.absent == 'g'`:        false,
+		`$.absent1 != $.absent2`: false,
+		`$.absent != 'g'`:        true,
+		`1 <= 2`:                 true,
+		`1> 2`:                   false,
+		`13 == '13'`:             false,
+		`'a' <= 'b'`:             true,
+		`'a' > 'b'`:              false,
+		`$.obj == $.arr`:         false,
+		`$.obj != $.arr`:         true,
+		`$.obj == $.obj`:         true,
+		`$.obj != $.obj`:         false,
+		`$.arr == $.arr`:         true,
+		`$.arr != $.arr`:         false,
+		`$.obj == 17`:            false,
+		`$.obj != 17`:            true,
+		`$.obj <= $.arr`:         false,
+		`$.obj < $.arr`:          false,
+		`$.obj <= $.obj`:         true,
+		`$.arr <= $.arr`:         true,
+		`1 <= $.arr`:             false,
+		`1 >= $.arr`:             false,
+		`1 > $.arr`:              false,
+		`1 < $.arr`:              false,
+		`true <= true`:           true,
+		`true > true`:            false,
+		`1 == 1.0`:               true,
+		`1 == 1e0`:               true,
+		`null == null`:           true,
+		`$.arr[0] == 2`:          true,
+		`$.arr[-1] >= 3`:         true,
+	}
+	var root any
+	require.NoError(t, json.Unmarshal([]byte(doc), &root))
+	for cmp, expected := range tests {
+		t.Run(cmp, func(t *testing.T) {
+			q, err := parseQuery(`$[?` + cmp + `]`)
+			require.NoError(t, err)
+			if expected {
+				require.Equal(t, 2, len(q.evaluate(root)))
+			} else {
+				require.Equal(t, 0, len(q.evaluate(root)))
+			}
+		})
+	}
+}
+
+func TestQuery_RfcFunctions(t *testing.T) {
+	runRfcQueryTests(t, `{"a": ["ab", "abc", [1,2,3], {"x": 1}, 7], "c": [{"d": [1]}, {"d": [1,2]}, {"t": "Europe/Paris"}, {"t": "Asia/Tokyo"}]}`, []rfcQueryTest{
+		{query: `$.a[?length(@) == 3]`, expected: `["abc",[1,2,3]]`, ordered: true},
+		{query: `$.a[?length(@) < 3]`, expected: `["ab",{"x":1}]`, ordered: true},
+		{query: `$.c[?count(@.*) == 1]`, expected: `[{"d":[1]},{"d":[1,2]},{"t":"Europe/Paris"},{"t":"Asia/Tokyo"}]`, ordered: true},
+		{query: `$.c[?count(@.d.*) == 1]`, expected: `[{"d":[1]}]`},
+		{query: `$.c[?count(@.d[*]) == 2]`, expected: `[{"d":[1,2]}]`},
+		{query: `$.c[?match(@.t, 'Europe/.*')]`, expected: `[{"t":"Europe/Paris"}]`},
+		{query: `$.c[?match(@.t, 'Europe')]`, expected: `[]`},
+		{query: `$.c[?search(@.t, 'Europe')]`, expected: `[{"t":"Europe/Paris"}]`},
+		{query: `$.c[?match(@.t, '[')]`, expected: `[]`},
+		{query: `$.c[?value(@..t) == "Asia/Tokyo"]`, expected: `[{"t":"Asia/Tokyo"}]`},
+		{query: `$.c[?length(value(@.d)) == 2]`, expected: `[{"d":[1,2]}]`},
+	})
+	runRfcQueryTests(t, `["a\nb", "a\rb", "axb"]`, []rfcQueryTest{
+		{query: `$[?match(@, 'a.b')]`, expected: `["axb"]`},
+	})
+}
+
+func TestQuery_LiteralRegexpCompiledOnce(t *testing.T) {
+	function := func(query string) *jsonPathFunction {
+		q, err := parseQuery(query)
+		require.NoError(t, err)
+		return q.segments[0].selectors[0].(*jsonPathFilter).expr.(*jsonPathFunction)
+	}
+	require.NotNil(t, function(`$[?match(@.t, 'Europe/.*')]`).eval)
+	require.NotNil(t, function(`$[?search(@.t, '[')]`).eval)
+	require.Nil(t, function(`$[?match(@.t, @.p)]`).eval)
+	require.Nil(t, function(`$[?search(@.t, 1)]`).eval)
+
+	// non-literal patterns are still compiled at evaluation...
+	runRfcQueryTests(t, `[{"t": "abc", "p": "a.c"}, {"t": "abc", "p": "b"}, {"t": "abc", "p": "["}, {"t": "abc"}]`, []rfcQueryTest{
+		{query: `$[?match(@.t, @.p)]`, expected: `[{"t":"abc","p":"a.c"}]`},
+		{query: `$[?search(@.t, @.p)]`, expected: `[{"t":"abc","p":"a.c"},{"t":"abc","p":"b"}]`, ordered: true},
+		{query: `$[?search(@.t, 'b')]`, expected: `[{"t":"abc","p":"a.c"},{"t":"abc","p":"b"},{"t":"abc","p":"["},{"t":"abc"}]`, ordered: true},
+		{query: `$[?match(@.p, '[')]`, expected: `[]`},
+	})
+}
+
+func TestQuery_RfcDescendantSegment(t *testing.T) {
+	runRfcQueryTests(t, `{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`, []rfcQueryTest{
+		{query: `$..j`, expected: `[1,4]`},
+		{query: `$..[0]`, expected: `[5,{"j":4}]`},
+		{query: `$..[*]`, expected: `[{"j":1,"k":2},[5,3,[{"j":4},{"k":6}]],1,2,5,3,[{"j":4},{"k":6}],{"j":4},{"k":6},4,6]`},
+		{query: `$..*`, expected: `[{"j":1,"k":2},[5,3,[{"j":4},{"k":6}]],1,2,5,3,[{"j":4},{"k":6}],{"j":4},{"k":6},4,6]`},
+		{query: `$..o`, expected: `[{"j":1,"k":2}]`},
+		{query: `$.o..[*, *]`, expected: `[1,2,1,2]`},
+		{query: `$.a..[0, 1]`, expected: `[5,3,{"j":4},{"k":6}]`, ordered: true},
+	})
+}
+
+func TestQuery_RfcNullSemantics(t *testing.T) {
+	runRfcQueryTests(t, `{"a": null, "b": [null], "c": [{}], "null": 1}`, []rfcQueryTest{
+		{query: `$.a`, expected: `[null]`},
+		{query: `$.a[0]`, expected: `[]`},
+		{query: `$.a.d`, expected: `[]`},
+		{query: `$.b[0]`, expected: `[null]`},
+		{query: `$.b[*]`, expected: `[null]`},
+		{query: `$.b[?@]`, expected: `[null]`},
+		{query: `$.b[?@==null]`, expected: `[null]`},
+		{query: `$.c[?@.d==null]`, expected: `[]`},
+		{query: `$.null`, expected: `[1]`},
+	})
+}
+
+func TestQuery_RfcWellTypedness(t *testing.T) {
+	valid := []string{
+		`$[?length(@) < 3]`,
+		`$[?count(@.*) == 1]`,
+		`$[?match(@.timezone, 'Europe/.*')]`,
+		`$[?value(@..color) == "red"]`,
+		`$[?!match(@.timezone, 'Europe/.*')]`,
+		`$[?count(@[?@.a]) > 0]`,
+	}
+	for _, q := range valid {
+		_, err := parseQuery(q)
+		require.NoError(t, err, q)
+	}
+	invalid := []string{
+		`$[?length(@.*) < 3]`,
+		`$[?count(1) == 1]`,
+		`$[?match(@.timezone, 'Europe/.*') == true]`,
+		`$[?value(@..color)]`,
+		`$[?length(@)]`,
+		`$[?bar(@.a)]`,
+		`$[?length(@, 1) == 1]`,
+		`$[?length() == 1]`,
+	}
+	for _, q := range invalid {
+		_, err := parseQuery(q)
+		require.Error(t, err, q)
+	}
+}
+
+func TestQuery_SyntaxErrors(t *testing.T) {
+	invalid := []string{
+		``,
+		`foo`,
+		` $`,
+		`$ `,
+		`@`,
+		`$.`,
+		`$..`,
+		`$.1a`,
+		`$. a`,
+		`$[`,
+		`$[]`,
+		`$[01]`,
+		`$[-0]`,
+		`$[1.0]`,
+		`$[9007199254740992]`,
+		`$['a'`,
+		`$['a\x']`,
+		`$["a` + "\n" + `"]`,
+		`$[1 2]`,
+		`$[?]`,
+		`$[?1]`,
+		`$[?'a']`,
+		`$[?@.a == ]`,
+		`$[?@.a == 1 == 1]`,
+		`$[?!@.a == 1]`,
+		`$[?(@.a]`,
+		`$[?@.* == 1]`,
+		`$[?@..a == 1]`,
+		`$[?@.a == 01]`,
+		`$[?@.a == 1.]`,
+		`$[?@.a == 1e]`,
+		`$[?@.a == tru]`,
+		`$[?@.b == {}]`,
+		`$['\uD800']`,
+		`$['\uDC00\uD800']`,
+	}
+	for _, q := range invalid {
+		_, err := parseQuery(q)
+		require.Error(t, err, q)
+	}
+	valid := []string{
+		`$`,
+		`$.a`,
+		`$ .a`,
+		`$.a .b`,
+		`$[ 'a' , "b" ]`,
+		`$._a1`,
+		`$.ünïcödé`,
+		`$[-9007199254740991]`,
+		`$['😀']`,
+		`$[?@.a == -0]`,
+		`$[?@.a == -1.5e-3]`,
+		`$[? @.a==1 ]`,
+		`$[?@.a && (@.b || !@.c)]`,
+		`$[?(@.a == true) && (@.b != null)]`,
+	}
+	for _, q := range valid {
+		_, err := parseQuery(q)
+		require.NoError(t, err, q)
+	}
+}
@@ -0,0 +1,343 @@
+package gopt
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Rule names (as used in `gopt` tags and reported in RuleError.Rule and FieldValidationError.Rule)
+const (
+	RuleRequired      = "required"
+	RuleNotNull       = "notnull"
+	RuleRequiredIf    = "required_if"
+	RuleForbiddenWith = "forbidden_with"
+	RuleMin           = "min"
+	RuleMax           = "max"
+	RuleBetween       = "between"
+	RuleLen           = "len"
+	RuleMatches       = "matches"
+	RuleOneOf         = "oneof"
+	RuleNotBlank      = "notblank"
+)
+
+// RuleError is the error returned by Optional.Validate when a rule fails
+//
+// The rule name and params can be used to produce localised messages
+type RuleError struct {
+	// Rule is the name of the rule that failed (e.g. "min")
+	Rule string
+	// Params is the parameters of the rule (e.g. for Min(1) - 1)
+	Params []any
+	// Message describes the error
+	Message string
+}
+
+// Error implements error
+func (e *RuleError) Error() string {
+	return e.Message
+}
+
+// Rule is a validation rule for a value (see Optional.Validate) - returns nil if the value is valid
+//
+// Custom rules can be written as a function, e.g.
+//   even := func(v int) *gopt.RuleError {
+//     if v%2 != 0 {
+//       return &gopt.RuleError{Rule: "even", Message: "must be even"}
+//     }
+//     return nil
+//   }
+type Rule[T any] func(v T) *RuleError
+
+// Validate validates the value against the supplied rules - returning the *RuleError of the first rule that fails
+//
+// Rules only apply when the value is present (i.e. if the value is not present, nil is returned)
+func (o *Optional[T]) Validate(rules ...Rule[T]) error {
+	if o.IsPresent() {
+		for _, r := range rules {
+			if r == nil {
+				continue
+			}
+			if re := r(o.value); re != nil {
+				return re
+			}
+		}
+	}
+	return nil
+}
+
+// Min is a Rule that the value must be at least min
+func Min[T Ordered](min T) Rule[T] {
+	return func(v T) *RuleError {
+		if v < min {
+			return minError(min)
+		}
+		return nil
+	}
+}
+
+// Max is a Rule that the value must be at most max
+func Max[T Ordered](max T) Rule[T] {
+	return func(v T) *RuleError {
+		if v > max {
+			return maxError(max)
+		}
+		return nil
+	}
+}
+
+// Between is a Rule that the value must be between min and max (inclusive)
+func Between[T Ordered](min T, max T) Rule[T] {
+	return func(v T) *RuleError {
+		if v < min || v > max {
+			return betweenError(min, max)
+		}
+		return nil
+	}
+}
+
+// Len is a Rule that the length of the value must be between min and max (inclusive)
+//
+// The value must be a string (length is the number of runes), slice, array or map - a max of less than zero means no maximum
+func Len[T any](min int, max int) Rule[T] {
+	return func(v T) *RuleError {
+		return lenRule(reflect.ValueOf(v), min, max)
+	}
+}
+
+// Matches is a Rule that the value must match the supplied regular expression
+func Matches(re *regexp.Regexp) Rule[string] {
+	return func(v string) *RuleError {
+		if !re.MatchString(v) {
+			return matchesError(re.String())
+		}
+		return nil
+	}
+}
+
+// OneOf is a Rule that the value must be one of the supplied values
+func OneOf[T comparable](values ...T) Rule[T] {
+	return func(v T) *RuleError {
+		for _, av := range values {
+			if v == av {
+				return nil
+			}
+		}
+		params := make([]any, len(values))
+		for i, av := range values {
+			params[i] = av
+		}
+		return oneOfError(params)
+	}
+}
+
+// NotBlank is a Rule that the value must not be blank (empty or only whitespace)
+func NotBlank() Rule[string] {
+	return func(v string) *RuleError {
+		if strings.TrimSpace(v) == "" {
+			return notBlankError()
+		}
+		return nil
+	}
+}
+
+func minError(min any) *RuleError {
+	return &RuleError{Rule: RuleMin, Params: []any{min}, Message: fmt.Sprintf("must be at least %v", min)}
+}
+
+func maxError(max any) *RuleError {
+	return &RuleError{Rule: RuleMax, Params: []any{max}, Message: fmt.Sprintf("must be at most %v", max)}
+}
+
+func betweenError(min any, max any) *RuleError {
+	return &RuleError{Rule: RuleBetween, Params: []any{min, max}, Message: fmt.Sprintf("must be between %v and %v", min, max)}
+}
+
+func matchesError(pattern string) *RuleError {
+	return &RuleError{Rule: RuleMatches, Params: []any{pattern}, Message: fmt.Sprintf("must match %q", pattern)}
+}
+
+func oneOfError(values []any) *RuleError {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = fmt.Sprint(v)
+	}
+	return &RuleError{Rule: RuleOneOf, Params: values, Message: fmt.Sprintf("must be one of %s", strings.Join(strs, ", "))}
+}
+
+func notBlankError() *RuleError {
+	return &RuleError{Rule: RuleNotBlank, Message: "must not be blank"}
+}
+
+func lenRule(rv reflect.Value, min int, max int) *RuleError {
+	var l int
+	switch rv.Kind() {
+	case reflect.String:
+		l = utf8.RuneCountInString(rv.String())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		l = rv.Len()
+	default:
+		return &RuleError{Rule: RuleLen, Params: []any{min, max}, Message: fmt.Sprintf("length not applicable to %s", rv.Kind())}
+	}
+	if l < min || (max >= 0 && l > max) {
+		var msg string
+		if min == max {
+			msg = fmt.Sprintf("length must be %d", min)
+		} else if max < 0 {
+			msg = fmt.Sprintf("length must be at least %d", min)
+		} else {
+			msg = fmt.Sprintf("length must be between %d and %d", min, max)
+		}
+		return &RuleError{Rule: RuleLen, Params: []any{min, max}, Message: msg}
+	}
+	return nil
+}
+
+// tagValueRule applies a value rule from a `gopt` tag (see Validate) to a present value
+func tagValueRule(r tagRule, rv reflect.Value) *RuleError {
+	switch r.name {
+	case RuleMin, RuleMax:
+		p, err := ruleParam(rv, r.param)
+		if err != nil {
+			return paramError(r, err)
+		} else if c := compareValues(rv, p); r.name == RuleMin && c < 0 {
+			return minError(p.Interface())
+		} else if r.name == RuleMax && c > 0 {
+			return maxError(p.Interface())
+		}
+	case RuleBetween:
+		lo, hi, _ := strings.Cut(r.param, ":")
+		min, err := ruleParam(rv, lo)
+		if err != nil {
+			return paramError(r, err)
+		}
+		max, err := ruleParam(rv, hi)
+		if err != nil {
+			return paramError(r, err)
+		} else if compareValues(rv, min) < 0 || compareValues(rv, max) > 0 {
+			return betweenError(min.Interface(), max.Interface())
+		}
+	case RuleLen:
+		lo, hi, ranged := strings.Cut(r.param, ":")
+		min, err := strconv.Atoi(lo)
+		if err != nil {
+			return paramError(r, err)
+		}
+		max := min
+		if ranged {
+			if max, err = strconv.Atoi(hi); err != nil {
+				return paramError(r, err)
+			}
+		}
+		return lenRule(rv, min, max)
+	case RuleMatches:
+		re, err := tagRegexp(r.param)
+		if err != nil {
+			return paramError(r, err)
+		} else if rv.Kind() != reflect.String {
+			return paramError(r, fmt.Errorf("not applicable to %s", rv.Kind()))
+		} else if !re.MatchString(rv.String()) {
+			return matchesError(r.param)
+		}
+	case RuleOneOf:
+		options := strings.Split(r.param, "|")
+		params := make([]any, len(options))
+		found := false
+		for i, option := range options {
+			p, err := ruleParam(rv, option)
+			if err != nil {
+				return paramError(r, err)
+			}
+			params[i] = p.Interface()
+			found = found || compareValues(rv, p) == 0
+		}
+		if !found {
+			return oneOfError(params)
+		}
+	case RuleNotBlank:
+		if rv.Kind() != reflect.String {
+			return paramError(r, fmt.Errorf("not applicable to %s", rv.Kind()))
+		} else if strings.TrimSpace(rv.String()) == "" {
+			return notBlankError()
+		}
+	}
+	return nil
+}
+
+type compiledRegexp struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// tagRegexps caches the compiled `matches` rule regexps (and compile errors), by pattern
+var tagRegexps sync.Map
+
+// tagRegexp returns the compiled regexp for a `matches` rule pattern - compiling each pattern only once
+func tagRegexp(pattern string) (*regexp.Regexp, error) {
+	if c, ok := tagRegexps.Load(pattern); ok {
+		return c.(*compiledRegexp).re, c.(*compiledRegexp).err
+	}
+	re, err := regexp.Compile(pattern)
+	c, _ := tagRegexps.LoadOrStore(pattern, &compiledRegexp{re: re, err: err})
+	return c.(*compiledRegexp).re, c.(*compiledRegexp).err
+}
+
+func paramError(r tagRule, err error) *RuleError {
+	return &RuleError{Rule: r.name, Params: []any{r.param}, Message: fmt.Sprintf("invalid rule parameter %q (%s)", r.param, err)}
+}
+
+// ruleParam parses a tag rule parameter as the type of the value
+func ruleParam(rv reflect.Value, s string) (reflect.Value, error) {
+	pv := reflect.New(rv.Type()).Elem()
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return pv, err
+		}
+		pv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return pv, err
+		}
+		pv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return pv, err
+		}
+		pv.SetFloat(f)
+	case reflect.String:
+		pv.SetString(s)
+	default:
+		return pv, fmt.Errorf("not applicable to %s", rv.Kind())
+	}
+	return pv, nil
+}
+
+// compareValues compares two values of the same (ordered) kind - as parsed by ruleParam
+func compareValues(a reflect.Value, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return compareOrdered(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float(), b.Float())
+	}
+	return compareOrdered(a.String(), b.String())
+}
+
+func compareOrdered[T Ordered](a T, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
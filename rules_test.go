@@ -0,0 +1,199 @@
+package gopt
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestOptional_Validate(t *testing.T) {
+	o := Of(5)
+	require.NoError(t, o.Validate())
+	require.NoError(t, o.Validate(Min(1), Max(10), nil))
+	err := o.Validate(Min(1), Max(3))
+	require.Error(t, err)
+	var re *RuleError
+	require.True(t, errors.As(err, &re))
+	require.Equal(t, RuleMax, re.Rule)
+	require.Equal(t, []any{3}, re.Params)
+	require.Equal(t, "must be at most 3", err.Error())
+
+	// rules do not apply when not present...
+	require.NoError(t, Empty[int]().Validate(Min(10)))
+	var no *Optional[int]
+	require.NoError(t, no.Validate(Min(10)))
+
+	// custom rule...
+	even := func(v int) *RuleError {
+		if v%2 != 0 {
+			return &RuleError{Rule: "even", Message: "must be even"}
+		}
+		return nil
+	}
+	require.Equal(t, "must be even", o.Validate(even).Error())
+	require.NoError(t, Of(4).Validate(even))
+}
+
+func TestRules(t *testing.T) {
+	testCases := []struct {
+		err     error
+		rule    string
+		params  []any
+		message string
+	}{
+		{err: Of(0).Validate(Min(1)), rule: RuleMin, params: []any{1}, message: "must be at least 1"},
+		{err: Of(1).Validate(Min(1))},
+		{err: Of(1.5).Validate(Max(1.0)), rule: RuleMax, params: []any{1.0}, message: "must be at most 1"},
+		{err: Of(11).Validate(Between(1, 10)), rule: RuleBetween, params: []any{1, 10}, message: "must be between 1 and 10"},
+		{err: Of(0).Validate(Between(1, 10)), rule: RuleBetween, params: []any{1, 10}, message: "must be between 1 and 10"},
+		{err: Of(10).Validate(Between(1, 10))},
+		{err: Of("b").Validate(Between("a", "c"))},
+		{err: Of("héllo").Validate(Len[string](5, 5))},
+		{err: Of("hello!").Validate(Len[string](5, 5)), rule: RuleLen, params: []any{5, 5}, message: "length must be 5"},
+		{err: Of([]int{1}).Validate(Len[[]int](2, 3)), rule: RuleLen, params: []any{2, 3}, message: "length must be between 2 and 3"},
+		{err: Of(map[string]int{}).Validate(Len[map[string]int](1, -1)), rule: RuleLen, params: []any{1, -1}, message: "length must be at least 1"},
+		{err: Of(1).Validate(Len[int](1, 1)), rule: RuleLen, params: []any{1, 1}, message: "length not applicable to int"},
+		{err: Of("abc").Validate(Matches(regexp.MustCompile(`^[a-z]+$`)))},
+		{err: Of("ab1").Validate(Matches(regexp.MustCompile(`^[a-z]+$`))), rule: RuleMatches, params: []any{`^[a-z]+$`}, message: `must match "^[a-z]+$"`},
+		{err: Of("b").Validate(OneOf("a", "b"))},
+		{err: Of("c").Validate(OneOf("a", "b")), rule: RuleOneOf, params: []any{"a", "b"}, message: "must be one of a, b"},
+		{err: Of(" x ").Validate(NotBlank())},
+		{err: Of(" \t").Validate(NotBlank()), rule: RuleNotBlank, message: "must not be blank"},
+	}
+	for i, tc := range testCases {
+		if tc.rule == "" {
+			require.NoError(t, tc.err, "test case %d", i)
+			continue
+		}
+		require.Error(t, tc.err, "test case %d", i)
+		re := tc.err.(*RuleError)
+		require.Equal(t, tc.rule, re.Rule, "test case %d", i)
+		require.Equal(t, tc.params, re.Params, "test case %d", i)
+		require.Equal(t, tc.message, re.Message, "test case %d", i)
+	}
+}
+
+func TestValidate_ValueRules(t *testing.T) {
+	type product struct {
+		Name     Optional[string]  `json:"name" gopt:"required,notblank,len=1:10"`
+		Code     Optional[string]  `json:"code" gopt:"matches=^[A-Z]{2,3}$"`
+		Qty      *Optional[int]    `json:"qty" gopt:"min=1,max=10"`
+		Price    Optional[float64] `json:"price" gopt:"between=0.01:99.99"`
+		Status   Optional[string]  `json:"status" gopt:"oneof=draft|live"`
+		Priority uint8             `json:"priority" gopt:"oneof=1|2|3"`
+		Tags     []string          `json:"tags" gopt:"len=0:2"`
+	}
+	var p product
+	err := json.Unmarshal([]byte(`{"name": "Widget", "code": "AB", "qty": 5, "price": 9.99, "status": "live", "priority": 2, "tags": ["a"]}`), &p)
+	require.NoError(t, err)
+	require.NoError(t, Validate(p))
+
+	// rules only apply to optionals when present (but apply to the zero value of non-optional fields)...
+	p = product{}
+	err = json.Unmarshal([]byte(`{"name": "Widget", "code": null, "qty": null}`), &p)
+	require.NoError(t, err)
+	err = Validate(p)
+	require.Error(t, err)
+	verr := err.(*ValidationError)
+	require.Equal(t, 1, len(verr.Errors))
+	require.Equal(t, "priority", verr.Errors[0].Path)
+	require.Equal(t, RuleOneOf, verr.Errors[0].Rule)
+
+	p = product{}
+	err = json.Unmarshal([]byte(`{"name": " ", "code": "ABCD", "qty": 0, "price": 100, "status": "gone", "priority": 4, "tags": ["a", "b", "c"]}`), &p)
+	require.NoError(t, err)
+	err = Validate(p)
+	require.Error(t, err)
+	verr = err.(*ValidationError)
+	require.Equal(t, 7, len(verr.Errors))
+	require.Equal(t, "name", verr.Errors[0].Path)
+	require.Equal(t, RuleNotBlank, verr.Errors[0].Rule)
+	require.Equal(t, "code", verr.Errors[1].Path)
+	require.Equal(t, RuleMatches, verr.Errors[1].Rule)
+	require.Equal(t, "qty", verr.Errors[2].Path)
+	require.Equal(t, RuleMin, verr.Errors[2].Rule)
+	require.Equal(t, []any{1}, verr.Errors[2].Params)
+	require.Equal(t, "price", verr.Errors[3].Path)
+	require.Equal(t, RuleBetween, verr.Errors[3].Rule)
+	require.Equal(t, []any{0.01, 99.99}, verr.Errors[3].Params)
+	require.Equal(t, "status", verr.Errors[4].Path)
+	require.Equal(t, []any{"draft", "live"}, verr.Errors[4].Params)
+	require.Equal(t, "priority", verr.Errors[5].Path)
+	require.Equal(t, []any{uint8(1), uint8(2), uint8(3)}, verr.Errors[5].Params)
+	require.Equal(t, "tags", verr.Errors[6].Path)
+	require.Equal(t, "length must be between 0 and 2", verr.Errors[6].Message)
+	require.Equal(t, `field "qty": must be at least 1`, verr.Errors[2].Error())
+}
+
+func TestValidate_ValueRules_NonOptionalFields(t *testing.T) {
+	type counts struct {
+		Count int     `json:"count" gopt:"min=1"`
+		Ptr   *int    `json:"ptr" gopt:"max=5"`
+		Name  string  `json:"name" gopt:"notblank"`
+		Any   any     `json:"any" gopt:"min=1"`
+		Tags  []int   `json:"tags" gopt:"len=1:2"`
+		Rate  float64 `json:"rate" gopt:"between=0:1"`
+	}
+	err := Validate(counts{})
+	require.Error(t, err)
+	verr := err.(*ValidationError)
+	require.Equal(t, 3, len(verr.Errors))
+	require.Equal(t, "count", verr.Errors[0].Path)
+	require.Equal(t, "must be at least 1", verr.Errors[0].Message)
+	require.Equal(t, "name", verr.Errors[1].Path)
+	require.Equal(t, "tags", verr.Errors[2].Path)
+
+	six, one := 6, 1
+	err = Validate(counts{Count: 1, Ptr: &six, Name: "x", Any: 0, Tags: []int{1}})
+	require.Error(t, err)
+	verr = err.(*ValidationError)
+	require.Equal(t, 2, len(verr.Errors))
+	require.Equal(t, "ptr", verr.Errors[0].Path)
+	require.Equal(t, "any", verr.Errors[1].Path)
+	require.NoError(t, Validate(counts{Count: 1, Ptr: &one, Name: "x", Any: 1, Tags: []int{1}}))
+}
+
+func TestValidate_ValueRuleTagErrors(t *testing.T) {
+	type badTags struct {
+		A Optional[int]    `gopt:"min=x"`
+		B Optional[int]    `gopt:"between=1"`
+		C Optional[string] `gopt:"len=a"`
+		D Optional[string] `gopt:"matches=[a-"`
+		E Optional[int]    `gopt:"matches=^1$"`
+		F Optional[bool]   `gopt:"max=1"`
+		G Optional[int]    `gopt:"notblank"`
+		H Optional[int8]   `gopt:"oneof=1|1000"`
+	}
+	v := badTags{}
+	v.A.Set(1)
+	v.B.Set(1)
+	v.C.Set("c")
+	v.D.Set("d")
+	v.E.Set(1)
+	v.F.Set(true)
+	v.G.Set(1)
+	v.H.Set(1)
+	err := Validate(v)
+	require.Error(t, err)
+	verr := err.(*ValidationError)
+	require.Equal(t, 8, len(verr.Errors))
+	for _, fe := range verr.Errors {
+		require.Contains(t, fe.Message, "invalid rule parameter")
+	}
+	require.Equal(t, `invalid rule parameter "1" (not applicable to bool)`, verr.Errors[5].Message)
+}
+
+func TestTagRegexp(t *testing.T) {
+	re, err := tagRegexp(`^[a-z]+$`)
+	require.NoError(t, err)
+	require.True(t, re.MatchString("abc"))
+	again, err := tagRegexp(`^[a-z]+$`)
+	require.NoError(t, err)
+	require.Same(t, re, again)
+	_, err = tagRegexp(`[a-`)
+	require.Error(t, err)
+	_, err2 := tagRegexp(`[a-`)
+	require.Equal(t, err, err2)
+}
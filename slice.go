@@ -0,0 +1,181 @@
+package gopt
+
+import "math/rand"
+
+// OptSlice can be used to cast an existing slice for optional/functional methods
+//
+// Methods that return an element return an optional - which is empty if there is no such element (or the element is nil)
+type OptSlice[T any] []T
+
+// At returns an optional of the element at the specified index
+//
+// The index may be negative (indicating relative index to the end - e.g. -1 is the last element).  If the index is
+// out of range, an empty optional is returned
+func (s OptSlice[T]) At(i int) *Optional[T] {
+	if i < 0 {
+		i = len(s) + i
+	}
+	if i >= 0 && i < len(s) {
+		return Of(s[i])
+	}
+	return Empty[T]()
+}
+
+// First returns an optional of the first element (empty if the slice is empty)
+func (s OptSlice[T]) First() *Optional[T] {
+	return s.At(0)
+}
+
+// Last returns an optional of the last element (empty if the slice is empty)
+func (s OptSlice[T]) Last() *Optional[T] {
+	return s.At(-1)
+}
+
+// Find returns an optional of the first element for which the supplied predicate returns true
+func (s OptSlice[T]) Find(pred func(v T) bool) *Optional[T] {
+	if i := s.findIndex(pred); i != -1 {
+		return Of(s[i])
+	}
+	return Empty[T]()
+}
+
+// FindIndex returns an optional of the index of the first element for which the supplied predicate returns true
+func (s OptSlice[T]) FindIndex(pred func(v T) bool) *Optional[int] {
+	if i := s.findIndex(pred); i != -1 {
+		return Of(i)
+	}
+	return Empty[int]()
+}
+
+// FindLast returns an optional of the last element for which the supplied predicate returns true
+func (s OptSlice[T]) FindLast(pred func(v T) bool) *Optional[T] {
+	if pred != nil {
+		for i := len(s) - 1; i >= 0; i-- {
+			if pred(s[i]) {
+				return Of(s[i])
+			}
+		}
+	}
+	return Empty[T]()
+}
+
+// Single returns an optional of the only element for which the supplied predicate returns true
+//
+// If no elements, or more than one element, match the predicate, an empty optional is returned.  If the supplied
+// predicate is nil, all elements match (i.e. the optional is present only if the slice has exactly one element)
+func (s OptSlice[T]) Single(pred func(v T) bool) *Optional[T] {
+	found := -1
+	for i, v := range s {
+		if pred == nil || pred(v) {
+			if found != -1 {
+				return Empty[T]()
+			}
+			found = i
+		}
+	}
+	if found == -1 {
+		return Empty[T]()
+	}
+	return Of(s[found])
+}
+
+// MinBy returns an optional of the minimum element, where less reports whether a is less than b (if more than one
+// element is the minimum, the first is returned)
+func (s OptSlice[T]) MinBy(less func(a, b T) bool) *Optional[T] {
+	if less == nil {
+		return Empty[T]()
+	}
+	return s.Reduce(func(acc, v T) T {
+		if less(v, acc) {
+			return v
+		}
+		return acc
+	})
+}
+
+// MaxBy returns an optional of the maximum element, where less reports whether a is less than b (if more than one
+// element is the maximum, the first is returned)
+func (s OptSlice[T]) MaxBy(less func(a, b T) bool) *Optional[T] {
+	if less == nil {
+		return Empty[T]()
+	}
+	return s.Reduce(func(acc, v T) T {
+		if less(acc, v) {
+			return v
+		}
+		return acc
+	})
+}
+
+// Reduce reduces the elements to a single value, by successively calling the supplied function with the accumulated
+// value (starting with the first element) and the next element
+//
+// If the slice is empty, an empty optional is returned
+func (s OptSlice[T]) Reduce(f func(acc T, v T) T) *Optional[T] {
+	if len(s) == 0 || f == nil {
+		return Empty[T]()
+	}
+	acc := s[0]
+	for _, v := range s[1:] {
+		acc = f(acc, v)
+	}
+	return Of(acc)
+}
+
+// Sample returns an optional of a randomly selected element (empty if the slice is empty)
+func (s OptSlice[T]) Sample() *Optional[T] {
+	if len(s) == 0 {
+		return Empty[T]()
+	}
+	return Of(s[rand.Intn(len(s))])
+}
+
+// Pop removes the last element and returns an optional of it (empty if the slice is empty)
+func (s *OptSlice[T]) Pop() *Optional[T] {
+	if s == nil || len(*s) == 0 {
+		return Empty[T]()
+	}
+	last := len(*s) - 1
+	v := (*s)[last]
+	var zero T
+	(*s)[last] = zero
+	*s = (*s)[:last]
+	return Of(v)
+}
+
+// Shift removes the first element and returns an optional of it (empty if the slice is empty)
+func (s *OptSlice[T]) Shift() *Optional[T] {
+	if s == nil || len(*s) == 0 {
+		return Empty[T]()
+	}
+	v := (*s)[0]
+	var zero T
+	(*s)[0] = zero
+	*s = (*s)[1:]
+	return Of(v)
+}
+
+func (s OptSlice[T]) findIndex(pred func(v T) bool) int {
+	if pred != nil {
+		for i, v := range s {
+			if pred(v) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// MinOf returns an optional of the minimum element of the supplied slice (empty if the slice is empty)
+func MinOf[T Ordered](s []T) *Optional[T] {
+	return OptSlice[T](s).MinBy(func(a, b T) bool {
+		return a < b
+	})
+}
+
+// MaxOf returns an optional of the maximum element of the supplied slice (empty if the slice is empty)
+func MaxOf[T Ordered](s []T) *Optional[T] {
+	return OptSlice[T](s).MaxBy(func(a, b T) bool {
+		return a < b
+	})
+}
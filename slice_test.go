@@ -0,0 +1,145 @@
+package gopt
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestOptSlice_At(t *testing.T) {
+	s := OptSlice[string]{"a", "b", "c"}
+	require.Equal(t, "a", s.At(0).OrElse(""))
+	require.Equal(t, "c", s.At(2).OrElse(""))
+	require.Equal(t, "c", s.At(-1).OrElse(""))
+	require.Equal(t, "a", s.At(-3).OrElse(""))
+	require.False(t, s.At(3).IsPresent())
+	require.False(t, s.At(-4).IsPresent())
+	require.Equal(t, "a", s.First().OrElse(""))
+	require.Equal(t, "c", s.Last().OrElse(""))
+
+	var empty OptSlice[string]
+	require.False(t, empty.At(0).IsPresent())
+	require.False(t, empty.First().IsPresent())
+	require.False(t, empty.Last().IsPresent())
+
+	// nil elements are not present...
+	ps := OptSlice[*int]{nil}
+	require.False(t, ps.At(0).IsPresent())
+}
+
+func TestOptSlice_Find(t *testing.T) {
+	s := OptSlice[string]{"apple", "banana", "avocado", "cherry"}
+	startsA := func(v string) bool {
+		return strings.HasPrefix(v, "a")
+	}
+	startsZ := func(v string) bool {
+		return strings.HasPrefix(v, "z")
+	}
+	require.Equal(t, "apple", s.Find(startsA).OrElse(""))
+	require.False(t, s.Find(startsZ).IsPresent())
+	require.False(t, s.Find(nil).IsPresent())
+	require.Equal(t, "avocado", s.FindLast(startsA).OrElse(""))
+	require.False(t, s.FindLast(startsZ).IsPresent())
+	require.False(t, s.FindLast(nil).IsPresent())
+
+	idx := s.FindIndex(func(v string) bool {
+		return v == "avocado"
+	})
+	require.True(t, idx.IsPresent())
+	require.Equal(t, 2, idx.OrElse(-1))
+	idx = s.FindIndex(startsZ)
+	require.False(t, idx.IsPresent())
+	// index zero is present...
+	require.True(t, s.FindIndex(startsA).IsPresent())
+	require.Equal(t, 0, s.FindIndex(startsA).OrElse(-1))
+}
+
+func TestOptSlice_Single(t *testing.T) {
+	s := OptSlice[int]{1, 2, 3, 4}
+	require.Equal(t, 3, s.Single(func(v int) bool {
+		return v == 3
+	}).OrElse(0))
+	require.False(t, s.Single(func(v int) bool {
+		return v%2 == 0
+	}).IsPresent())
+	require.False(t, s.Single(func(v int) bool {
+		return v > 10
+	}).IsPresent())
+	require.False(t, s.Single(nil).IsPresent())
+	require.Equal(t, 7, OptSlice[int]{7}.Single(nil).OrElse(0))
+	require.False(t, OptSlice[int]{}.Single(nil).IsPresent())
+}
+
+func TestOptSlice_MinMax(t *testing.T) {
+	require.Equal(t, 1, MinOf([]int{3, 1, 2}).OrElse(0))
+	require.Equal(t, 3, MaxOf([]int{3, 1, 2}).OrElse(0))
+	require.Equal(t, "a", MinOf([]string{"b", "a", "c"}).OrElse(""))
+	require.Equal(t, 2.5, MaxOf(OptSlice[float64]{1, 2.5, -1}).OrElse(0))
+	require.False(t, MinOf([]int{}).IsPresent())
+	require.False(t, MaxOf[int](nil).IsPresent())
+
+	type item struct {
+		name  string
+		price int
+	}
+	items := OptSlice[item]{{"a", 3}, {"b", 1}, {"c", 3}, {"d", 1}}
+	byPrice := func(a, b item) bool {
+		return a.price < b.price
+	}
+	require.Equal(t, "b", items.MinBy(byPrice).OrElse(item{}).name)
+	require.Equal(t, "a", items.MaxBy(byPrice).OrElse(item{}).name)
+	require.False(t, OptSlice[item]{}.MinBy(byPrice).IsPresent())
+	require.False(t, items.MinBy(nil).IsPresent())
+	require.False(t, items.MaxBy(nil).IsPresent())
+}
+
+func TestOptSlice_Reduce(t *testing.T) {
+	s := OptSlice[int]{1, 2, 3, 4}
+	sum := s.Reduce(func(acc int, v int) int {
+		return acc + v
+	})
+	require.Equal(t, 10, sum.OrElse(0))
+	require.Equal(t, 5, OptSlice[int]{5}.Reduce(func(acc int, v int) int {
+		return acc * v
+	}).OrElse(0))
+	require.False(t, OptSlice[int]{}.Reduce(func(acc int, v int) int {
+		return acc + v
+	}).IsPresent())
+	require.False(t, s.Reduce(nil).IsPresent())
+}
+
+func TestOptSlice_Sample(t *testing.T) {
+	s := OptSlice[string]{"a", "b", "c"}
+	for i := 0; i < 20; i++ {
+		o := s.Sample()
+		require.True(t, o.IsPresent())
+		require.Contains(t, s, o.OrElse(""))
+	}
+	require.False(t, OptSlice[string]{}.Sample().IsPresent())
+}
+
+func TestOptSlice_PopShift(t *testing.T) {
+	s := OptSlice[int]{1, 2, 3, 4}
+	require.Equal(t, 4, s.Pop().OrElse(0))
+	require.Equal(t, OptSlice[int]{1, 2, 3}, s)
+	require.Equal(t, 1, s.Shift().OrElse(0))
+	require.Equal(t, OptSlice[int]{2, 3}, s)
+	require.Equal(t, 3, s.Pop().OrElse(0))
+	require.Equal(t, 2, s.Shift().OrElse(0))
+	require.Equal(t, 0, len(s))
+	require.False(t, s.Pop().IsPresent())
+	require.False(t, s.Shift().IsPresent())
+
+	var ns *OptSlice[int]
+	require.False(t, ns.Pop().IsPresent())
+	require.False(t, ns.Shift().IsPresent())
+
+	// popped/shifted elements are not retained by the underlying array...
+	a, b := 1, 2
+	ps := OptSlice[*int]{&a, &b}
+	backing := ps[:2]
+	require.Equal(t, &b, ps.Pop().OrElse(nil))
+	require.Equal(t, &a, ps.Shift().OrElse(nil))
+	require.Nil(t, backing[0])
+	require.Nil(t, backing[1])
+}
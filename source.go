@@ -0,0 +1,148 @@
+package gopt
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// Source indicates which setting operation set an optional's value (see Optional.Source)
+type Source int
+
+const (
+	// SourceNone indicates that the value was not set
+	SourceNone Source = iota
+	// SourceJSON indicates that the value was set by UnmarshalJSON
+	SourceJSON
+	// SourceSQL indicates that the value was set by Scan
+	SourceSQL
+	// SourceDefault indicates that the value was set by OrElseSet or WasSetElseSet
+	SourceDefault
+	// SourceExplicit indicates that the value was set by Set (or SetFrom with SourceNone)
+	SourceExplicit
+	// SourceBind indicates that the value was set by Bind
+	SourceBind
+	// SourceHTTP indicates that the value was set by ExtractQuery, ExtractHeader or ExtractForm
+	SourceHTTP
+	// SourceCache indicates that the value was obtained from a Cache
+	SourceCache
+)
+
+// sourceCustomStart is the first value used for custom sources created using NewSource
+const sourceCustomStart Source = 100
+
+var (
+	sourceNames = map[Source]string{
+		SourceNone:     "none",
+		SourceJSON:     "json",
+		SourceSQL:      "sql",
+		SourceDefault:  "default",
+		SourceExplicit: "explicit",
+		SourceBind:     "bind",
+		SourceHTTP:     "http",
+		SourceCache:    "cache",
+	}
+	sourceNext  = sourceCustomStart
+	sourceMutex sync.RWMutex
+)
+
+// NewSource creates (registers) a new custom source with the supplied name
+//
+// Custom sources can be recorded using Optional.SetFrom - for example:
+//   var SourceEnv = gopt.NewSource("env")
+//   ...
+//   o.SetFrom(SourceEnv, v)
+func NewSource(name string) Source {
+	sourceMutex.Lock()
+	defer sourceMutex.Unlock()
+	s := sourceNext
+	sourceNext++
+	sourceNames[s] = name
+	return s
+}
+
+// String implements fmt.Stringer
+func (s Source) String() string {
+	sourceMutex.RLock()
+	defer sourceMutex.RUnlock()
+	if name, ok := sourceNames[s]; ok {
+		return name
+	}
+	return "source(" + strconv.Itoa(int(s)) + ")"
+}
+
+// FieldSource is a single field entry in the result of SourceReport
+type FieldSource struct {
+	// Field is the name of the field (nested struct fields are dot separated, e.g. "Outer.Inner")
+	Field string
+	// Source is the source of the field value
+	Source Source
+	// Set is whether the field value was set
+	Set bool
+	// Present is whether the field value is present
+	Present bool
+}
+
+type sourced interface {
+	Source() Source
+	WasSet() bool
+	IsPresent() bool
+}
+
+var sourcedType = reflect.TypeOf((*sourced)(nil)).Elem()
+
+// SourceReport lists every optional field (of type Optional[T] or *Optional[T]) in the supplied struct (or pointer to struct)
+// with the source of its value
+//
+// Nested (and embedded) structs are also reported - if the supplied value is not a struct (or pointer to struct) an empty report is returned
+func SourceReport(v any) []FieldSource {
+	result := make([]FieldSource, 0)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		if !rv.CanAddr() {
+			cv := reflect.New(rv.Type()).Elem()
+			cv.Set(rv)
+			rv = cv
+		}
+		result = sourceReport(rv, "", result)
+	}
+	return result
+}
+
+func sourceReport(rv reflect.Value, prefix string, result []FieldSource) []FieldSource {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		name := prefix + sf.Name
+		if reflect.PtrTo(sf.Type).Implements(sourcedType) {
+			result = append(result, fieldSource(name, fv.Addr().Interface().(sourced)))
+		} else if sf.Type.Kind() == reflect.Ptr && sf.Type.Implements(sourcedType) {
+			if fv.IsNil() {
+				result = append(result, FieldSource{Field: name})
+			} else {
+				result = append(result, fieldSource(name, fv.Interface().(sourced)))
+			}
+		} else if sf.Type.Kind() == reflect.Struct {
+			result = sourceReport(fv, name+".", result)
+		} else if sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct && !fv.IsNil() {
+			result = sourceReport(fv.Elem(), name+".", result)
+		}
+	}
+	return result
+}
+
+func fieldSource(name string, s sourced) FieldSource {
+	return FieldSource{
+		Field:   name,
+		Source:  s.Source(),
+		Set:     s.WasSet(),
+		Present: s.IsPresent(),
+	}
+}
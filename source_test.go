@@ -0,0 +1,67 @@
+package gopt
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSource_String(t *testing.T) {
+	require.Equal(t, "none", SourceNone.String())
+	require.Equal(t, "json", SourceJSON.String())
+	require.Equal(t, "sql", SourceSQL.String())
+	require.Equal(t, "default", SourceDefault.String())
+	require.Equal(t, "explicit", SourceExplicit.String())
+	require.Equal(t, "source(99)", Source(99).String())
+}
+
+func TestNewSource(t *testing.T) {
+	s1 := NewSource("env")
+	s2 := NewSource("flag")
+	require.NotEqual(t, s1, s2)
+	require.GreaterOrEqual(t, int(s1), int(sourceCustomStart))
+	require.Equal(t, "env", s1.String())
+	require.Equal(t, "flag", s2.String())
+
+	o := Empty[string]().SetFrom(s1, "foo")
+	require.Equal(t, s1, o.Source())
+	require.True(t, o.WasSet())
+}
+
+func TestSourceReport(t *testing.T) {
+	type inner struct {
+		Baz Optional[string] `json:"baz"`
+	}
+	type outer struct {
+		Foo      Optional[string]  `json:"foo"`
+		Bar      *Optional[int]    `json:"bar"`
+		Qux      Optional[float64] `json:"qux"`
+		Inner    inner             `json:"inner"`
+		InnerPtr *inner            `json:"innerPtr"`
+		Nil      *Optional[int]    `json:"nil"`
+		Other    string            `json:"other"`
+		private  Optional[string]
+	}
+	v := &outer{}
+	err := json.Unmarshal([]byte(`{"foo":"a","bar":1,"inner":{"baz":"b"},"innerPtr":{}}`), v)
+	require.NoError(t, err)
+	v.Qux.OrElseSet(1.2)
+
+	r := SourceReport(v)
+	require.Equal(t, []FieldSource{
+		{Field: "Foo", Source: SourceJSON, Set: true, Present: true},
+		{Field: "Bar", Source: SourceJSON, Set: true, Present: true},
+		{Field: "Qux", Source: SourceDefault, Set: true, Present: true},
+		{Field: "Inner.Baz", Source: SourceJSON, Set: true, Present: true},
+		{Field: "InnerPtr.Baz", Source: SourceNone, Set: false, Present: false},
+		{Field: "Nil", Source: SourceNone, Set: false, Present: false},
+	}, r)
+
+	r = SourceReport(*v)
+	require.Equal(t, 6, len(r))
+
+	r = SourceReport("not a struct")
+	require.Equal(t, 0, len(r))
+	r = SourceReport(nil)
+	require.Equal(t, 0, len(r))
+}
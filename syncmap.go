@@ -0,0 +1,409 @@
+package gopt
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"sync"
+)
+
+// defaultSyncOptMapShards is the number of shards used by SyncOptMap (unless created by NewSyncOptMapShards)
+const defaultSyncOptMapShards = 32
+
+// SyncOptMap is a concurrency safe map with the same optional/functional methods as OptMap
+//
+// The check-then-act methods (Compute, ComputeIfAbsent, ComputeIfPresent, Merge, PutIfAbsent and ReplaceIfPresent) are atomic - for
+// example, concurrent calls to ComputeIfAbsent for the same key call the compute function only once.  Compute functions
+// are called while holding the lock for the key's shard, so they must not call methods of the same map
+//
+// Keys are spread across sharded locks (for throughput under concurrent use) - the zero value is an empty map ready to use
+type SyncOptMap[K comparable, V any] struct {
+	once   sync.Once
+	seed   maphash.Seed
+	count  int
+	shards []syncOptMapShard[K, V]
+}
+
+type syncOptMapShard[K comparable, V any] struct {
+	sync.RWMutex
+	m map[K]V
+}
+
+// NewSyncOptMap creates a new, empty, SyncOptMap
+func NewSyncOptMap[K comparable, V any]() *SyncOptMap[K, V] {
+	return NewSyncOptMapShards[K, V](defaultSyncOptMapShards)
+}
+
+// NewSyncOptMapShards creates a new, empty, SyncOptMap with the specified number of shards (if less than 1, 1 is used)
+func NewSyncOptMapShards[K comparable, V any](shards int) *SyncOptMap[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+	return &SyncOptMap[K, V]{
+		count: shards,
+	}
+}
+
+// SyncOptMapOf creates a new SyncOptMap containing a copy of the entries in the supplied map
+func SyncOptMapOf[K comparable, V any](m map[K]V) *SyncOptMap[K, V] {
+	result := NewSyncOptMap[K, V]()
+	for k, v := range m {
+		result.shard(k).m[k] = v
+	}
+	return result
+}
+
+// Get returns an optional of the value in the map
+//
+// If the value is not present (or is nil) then an empty optional is returned
+func (m *SyncOptMap[K, V]) Get(key K) *Optional[V] {
+	s := m.shard(key)
+	s.RLock()
+	defer s.RUnlock()
+	return Get(s.m, key)
+}
+
+// GetWith returns an optional of the value in the map - where presence is determined by the supplied presence policy
+//
+// If the value is not present (according to the policy) then an empty optional is returned
+func (m *SyncOptMap[K, V]) GetWith(policy PresencePolicy, key K) *Optional[V] {
+	s := m.shard(key)
+	s.RLock()
+	defer s.RUnlock()
+	return GetWith(policy, s.m, key)
+}
+
+// Default returns the value (if present and non-nil) otherwise returns the default value
+func (m *SyncOptMap[K, V]) Default(key K, def V) V {
+	return m.Get(key).Default(def)
+}
+
+// IfPresent if the key is present (and the value is non-nil) calls the supplied function with the key and value
+//
+// otherwise does nothing (the supplied function is called without holding any lock)
+func (m *SyncOptMap[K, V]) IfPresent(key K, f func(key K, v V)) *SyncOptMap[K, V] {
+	if f != nil {
+		if v, ok := m.load(key); ok && isPresent(v) {
+			f(key, v)
+		}
+	}
+	return m
+}
+
+// IfPresentOtherwise if the key is present (and the value is non-nil) calls the supplied function with the key and value
+//
+// otherwise calls the other function with they key (the supplied functions are called without holding any lock)
+func (m *SyncOptMap[K, V]) IfPresentOtherwise(key K, f func(key K, v V), other func(key K)) *SyncOptMap[K, V] {
+	if v, ok := m.load(key); ok && isPresent(v) {
+		if f != nil {
+			f(key, v)
+		}
+	} else if other != nil {
+		other(key)
+	}
+	return m
+}
+
+// ComputeIfAbsent if the specified key is not present (or the value is nil) atomically sets the value according to the specified function
+//
+// returns either the existing value or the newly set value
+func (m *SyncOptMap[K, V]) ComputeIfAbsent(key K, f func(key K) V) V {
+	if v, ok := m.load(key); ok && isPresent(v) {
+		return v
+	}
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return OptMap[K, V](s.m).ComputeIfAbsent(key, f)
+}
+
+// ComputeIfPresent if the specified key is present (and the value is non-nil) atomically attempts to compute a new mapping using the supplied function
+//
+// If the supplied function is called but returns a nil value, the key is deleted
+func (m *SyncOptMap[K, V]) ComputeIfPresent(key K, f func(key K, v V) V) V {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return OptMap[K, V](s.m).ComputeIfPresent(key, f)
+}
+
+// PutIfAbsent if the specified key if absent (not present or nil-value) it is atomically set to the specified value
+//
+// returns true if the value was set
+func (m *SyncOptMap[K, V]) PutIfAbsent(key K, v V) bool {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return OptMap[K, V](s.m).PutIfAbsent(key, v)
+}
+
+// ReplaceIfPresent is the specified key is present (and the value is non-nil) it is atomically replaced with the specified value
+//
+// returns true if the value was replaced
+func (m *SyncOptMap[K, V]) ReplaceIfPresent(key K, v V) bool {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return OptMap[K, V](s.m).ReplaceIfPresent(key, v)
+}
+
+// Compute atomically computes a new mapping for the specified key using the supplied function (see OptMap.Compute)
+func (m *SyncOptMap[K, V]) Compute(key K, f func(key K, current *Optional[V]) *Optional[V]) *Optional[V] {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return OptMap[K, V](s.m).Compute(key, f)
+}
+
+// Merge atomically merges the specified value into the mapping for the specified key (see OptMap.Merge)
+func (m *SyncOptMap[K, V]) Merge(key K, v V, f func(old V, new V) V) V {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	return OptMap[K, V](s.m).Merge(key, v, f)
+}
+
+// GetOrDefault returns the value (if present and non-nil) otherwise returns the default value (the same as Default)
+func (m *SyncOptMap[K, V]) GetOrDefault(key K, def V) V {
+	return m.Default(key, def)
+}
+
+// RemoveIf removes all entries (with non-nil values) for which the supplied predicate returns true
+//
+// Each shard is locked in turn while its entries are tested (so the predicate must not call methods of the same map)
+//
+// returns the number of entries removed
+func (m *SyncOptMap[K, V]) RemoveIf(pred func(key K, v V) bool) int {
+	m.init()
+	count := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.Lock()
+		count += OptMap[K, V](s.m).RemoveIf(pred)
+		s.Unlock()
+	}
+	return count
+}
+
+// Filter returns a new map containing the entries (with non-nil values), in a consistent snapshot of the map, for
+// which the supplied predicate returns true (see OptMap.Filter)
+func (m *SyncOptMap[K, V]) Filter(pred func(key K, v V) bool) OptMap[K, V] {
+	return m.Snapshot().Filter(pred)
+}
+
+// Keys returns the keys of the entries with non-nil values, in a consistent snapshot of the map (in no particular order)
+func (m *SyncOptMap[K, V]) Keys() []K {
+	return m.Snapshot().Keys()
+}
+
+// Values returns the non-nil values, in a consistent snapshot of the map (in no particular order)
+func (m *SyncOptMap[K, V]) Values() []V {
+	return m.Snapshot().Values()
+}
+
+// Put sets the value for the specified key
+func (m *SyncOptMap[K, V]) Put(key K, v V) {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	s.m[key] = v
+}
+
+// Delete deletes the specified key
+//
+// returns true if the key was in the map
+func (m *SyncOptMap[K, V]) Delete(key K) bool {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.m[key]
+	delete(s.m, key)
+	return ok
+}
+
+// Len returns the number of entries in the map
+func (m *SyncOptMap[K, V]) Len() int {
+	m.init()
+	m.rlockAll()
+	defer m.runlockAll()
+	result := 0
+	for i := range m.shards {
+		result += len(m.shards[i].m)
+	}
+	return result
+}
+
+// Snapshot returns a consistent copy of the map (i.e. all shards are locked while the copy is taken)
+func (m *SyncOptMap[K, V]) Snapshot() OptMap[K, V] {
+	m.init()
+	m.rlockAll()
+	defer m.runlockAll()
+	size := 0
+	for i := range m.shards {
+		size += len(m.shards[i].m)
+	}
+	result := make(OptMap[K, V], size)
+	for i := range m.shards {
+		for k, v := range m.shards[i].m {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Range calls the supplied function for each entry in a consistent snapshot of the map (see Snapshot) - if the
+// function returns false, iteration stops
+//
+// Because the function is called with a snapshot, it may call methods of the same map
+func (m *SyncOptMap[K, V]) Range(f func(key K, v V) bool) {
+	for k, v := range m.Snapshot() {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// Clear removes all entries from the map
+func (m *SyncOptMap[K, V]) Clear() {
+	m.init()
+	m.lockAll()
+	defer m.unlockAll()
+	for i := range m.shards {
+		m.shards[i].m = map[K]V{}
+	}
+}
+
+func (m *SyncOptMap[K, V]) load(key K) (V, bool) {
+	s := m.shard(key)
+	s.RLock()
+	defer s.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+func (m *SyncOptMap[K, V]) init() {
+	m.once.Do(func() {
+		if m.count < 1 {
+			m.count = defaultSyncOptMapShards
+		}
+		m.seed = maphash.MakeSeed()
+		m.shards = make([]syncOptMapShard[K, V], m.count)
+		for i := range m.shards {
+			m.shards[i].m = map[K]V{}
+		}
+	})
+}
+
+func (m *SyncOptMap[K, V]) shard(key K) *syncOptMapShard[K, V] {
+	m.init()
+	if len(m.shards) == 1 {
+		return &m.shards[0]
+	}
+	var h maphash.Hash
+	h.SetSeed(m.seed)
+	hashKey(&h, key)
+	return &m.shards[h.Sum64()%uint64(len(m.shards))]
+}
+
+func (m *SyncOptMap[K, V]) lockAll() {
+	for i := range m.shards {
+		m.shards[i].Lock()
+	}
+}
+
+func (m *SyncOptMap[K, V]) unlockAll() {
+	for i := range m.shards {
+		m.shards[i].Unlock()
+	}
+}
+
+func (m *SyncOptMap[K, V]) rlockAll() {
+	for i := range m.shards {
+		m.shards[i].RLock()
+	}
+}
+
+func (m *SyncOptMap[K, V]) runlockAll() {
+	for i := range m.shards {
+		m.shards[i].RUnlock()
+	}
+}
+
+// hashKey writes a hash of the supplied (comparable) key - such that equal keys always have equal hashes
+func hashKey(h *maphash.Hash, key any) {
+	switch k := key.(type) {
+	case string:
+		_, _ = h.WriteString(k)
+	case int:
+		hashUint64(h, uint64(k))
+	case int64:
+		hashUint64(h, uint64(k))
+	case int32:
+		hashUint64(h, uint64(k))
+	case uint:
+		hashUint64(h, uint64(k))
+	case uint64:
+		hashUint64(h, k)
+	case uint32:
+		hashUint64(h, uint64(k))
+	default:
+		hashValue(h, reflect.ValueOf(key))
+	}
+}
+
+func hashValue(h *maphash.Hash, rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		_ = h.WriteByte(0)
+	case reflect.String:
+		_, _ = h.WriteString(rv.String())
+	case reflect.Bool:
+		if rv.Bool() {
+			_ = h.WriteByte(1)
+		} else {
+			_ = h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		hashUint64(h, uint64(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		hashUint64(h, rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		hashFloat(h, rv.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := rv.Complex()
+		hashFloat(h, real(c))
+		hashFloat(h, imag(c))
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		hashUint64(h, uint64(rv.Pointer()))
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			hashValue(h, rv.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			hashValue(h, rv.Field(i))
+		}
+	case reflect.Interface:
+		if rv.IsNil() {
+			_ = h.WriteByte(0)
+		} else {
+			_, _ = h.WriteString(rv.Elem().Type().String())
+			hashValue(h, rv.Elem())
+		}
+	}
+}
+
+func hashFloat(h *maphash.Hash, f float64) {
+	if f == 0 {
+		// +0 and -0 are equal
+		f = 0
+	}
+	hashUint64(h, math.Float64bits(f))
+}
+
+func hashUint64(h *maphash.Hash, u uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], u)
+	_, _ = h.Write(buf[:])
+}
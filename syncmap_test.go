@@ -0,0 +1,375 @@
+package gopt
+
+import (
+	"github.com/stretchr/testify/require"
+	"hash/maphash"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSyncOptMap(t *testing.T) {
+	m := NewSyncOptMap[string, *string]()
+	str := "x"
+	m.Put("a", &str)
+	m.Put("nil", nil)
+
+	require.True(t, m.Get("a").IsPresent())
+	require.False(t, m.Get("nil").IsPresent())
+	require.False(t, m.Get("missing").IsPresent())
+	require.Equal(t, &str, m.Default("a", nil))
+	require.Nil(t, m.Default("missing", nil))
+	require.Equal(t, 2, m.Len())
+
+	called := false
+	m.IfPresent("a", func(key string, v *string) {
+		called = true
+	}).IfPresent("nil", func(key string, v *string) {
+		t.Fatal("should not be called")
+	})
+	require.True(t, called)
+	otherCalled := false
+	m.IfPresentOtherwise("nil", func(key string, v *string) {
+		t.Fatal("should not be called")
+	}, func(key string) {
+		otherCalled = true
+	})
+	require.True(t, otherCalled)
+
+	require.True(t, m.Delete("a"))
+	require.False(t, m.Delete("a"))
+	require.Equal(t, 1, m.Len())
+	m.Clear()
+	require.Equal(t, 0, m.Len())
+}
+
+func TestSyncOptMap_ZeroValue(t *testing.T) {
+	var m SyncOptMap[int, string]
+	require.False(t, m.Get(1).IsPresent())
+	require.True(t, m.PutIfAbsent(1, "a"))
+	require.False(t, m.PutIfAbsent(1, "b"))
+	require.Equal(t, "a", m.Get(1).OrElse(""))
+	require.Equal(t, 1, m.Len())
+}
+
+func TestSyncOptMap_GetWith(t *testing.T) {
+	m := SyncOptMapOf(map[string]string{"a": "", "b": "x"})
+	require.True(t, m.Get("a").IsPresent())
+	require.False(t, m.GetWith(ZeroIsEmpty, "a").IsPresent())
+	require.True(t, m.GetWith(ZeroIsEmpty, "b").IsPresent())
+}
+
+func TestSyncOptMap_Compute(t *testing.T) {
+	m := NewSyncOptMapShards[string, *int](4)
+	one := 1
+	v := m.ComputeIfAbsent("a", func(key string) *int {
+		return &one
+	})
+	require.Equal(t, &one, v)
+	v = m.ComputeIfAbsent("a", func(key string) *int {
+		t.Fatal("should not be called")
+		return nil
+	})
+	require.Equal(t, &one, v)
+	v = m.ComputeIfAbsent("b", func(key string) *int {
+		return nil
+	})
+	require.Nil(t, v)
+	require.Equal(t, 1, m.Len())
+
+	two := 2
+	v = m.ComputeIfPresent("a", func(key string, v *int) *int {
+		return &two
+	})
+	require.Equal(t, &two, v)
+	v = m.ComputeIfPresent("a", func(key string, v *int) *int {
+		return nil
+	})
+	require.Nil(t, v)
+	require.Equal(t, 0, m.Len())
+
+	require.False(t, m.ReplaceIfPresent("a", &one))
+	require.True(t, m.PutIfAbsent("a", &one))
+	require.True(t, m.ReplaceIfPresent("a", &two))
+	require.Equal(t, &two, m.Get("a").OrElse(nil))
+	require.True(t, m.ReplaceIfPresent("a", nil))
+	require.Equal(t, 0, m.Len())
+}
+
+func TestSyncOptMap_SnapshotAndRange(t *testing.T) {
+	m := NewSyncOptMap[int, int]()
+	for i := 0; i < 100; i++ {
+		m.Put(i, i*i)
+	}
+	snap := m.Snapshot()
+	require.Equal(t, 100, len(snap))
+	require.Equal(t, 81, snap[9])
+	m.Put(9, 0)
+	require.Equal(t, 81, snap[9])
+
+	count := 0
+	m.Range(func(key int, v int) bool {
+		// calling methods of the same map is safe during range...
+		m.Delete(key)
+		count++
+		return count < 10
+	})
+	require.Equal(t, 10, count)
+	require.Equal(t, 90, m.Len())
+}
+
+func TestSyncOptMap_Shards(t *testing.T) {
+	m := NewSyncOptMapShards[int, int](0)
+	m.Put(1, 1)
+	require.Equal(t, 1, len(m.shards))
+	require.Equal(t, 1, m.Get(1).OrElse(0))
+}
+
+type testSyncKey struct {
+	name string
+	f    float64
+	p    *int
+	arr  [2]int8
+}
+
+func TestSyncOptMap_StructKeys(t *testing.T) {
+	m := NewSyncOptMap[testSyncKey, int]()
+	x := 1
+	k1 := testSyncKey{name: "a", f: math.Copysign(0, -1), p: &x, arr: [2]int8{1, 2}}
+	k2 := testSyncKey{name: "a", f: 0, p: &x, arr: [2]int8{1, 2}}
+	require.True(t, k1 == k2)
+	m.Put(k1, 1)
+	require.Equal(t, 1, m.Get(k2).OrElse(0))
+	m.Put(k2, 2)
+	require.Equal(t, 1, m.Len())
+	k3 := k2
+	k3.arr[1] = 3
+	require.False(t, m.Get(k3).IsPresent())
+}
+
+func TestHashKey_EqualKeys(t *testing.T) {
+	seed := maphash.MakeSeed()
+	hash := func(k any) uint64 {
+		var h maphash.Hash
+		h.SetSeed(seed)
+		hashKey(&h, k)
+		return h.Sum64()
+	}
+	type named int
+	type keys struct {
+		b bool
+		c complex128
+		u uint16
+		n named
+		i any
+	}
+	require.Equal(t, hash(keys{b: true, c: complex(1, 2), u: 3, n: 4, i: "x"}), hash(keys{b: true, c: complex(1, 2), u: 3, n: 4, i: "x"}))
+	require.Equal(t, hash(keys{i: nil}), hash(keys{i: nil}))
+	require.Equal(t, hash(named(1)), hash(named(1)))
+	require.Equal(t, hash("abc"), hash("abc"))
+	require.NotEqual(t, hash("abc"), hash("abd"))
+}
+
+func TestSyncOptMap_ComputeIfAbsent_Atomic(t *testing.T) {
+	m := NewSyncOptMap[int, int]()
+	var calls int32
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				m.ComputeIfAbsent(i, func(key int) int {
+					atomic.AddInt32(&calls, 1)
+					return key + 1
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(100), atomic.LoadInt32(&calls))
+	require.Equal(t, 100, m.Len())
+}
+
+func TestSyncOptMap_ComputeIfPresent_Atomic(t *testing.T) {
+	m := NewSyncOptMap[string, int]()
+	m.Put("counter", 1)
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				m.ComputeIfPresent("counter", func(key string, v int) int {
+					return v + 1
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 16001, m.Get("counter").OrElse(0))
+}
+
+func TestSyncOptMap_Concurrent(t *testing.T) {
+	m := NewSyncOptMap[string, int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := strconv.Itoa(i % 50)
+				switch i % 7 {
+				case 0:
+					m.Put(k, i)
+				case 1:
+					m.Get(k)
+				case 2:
+					m.PutIfAbsent(k, g)
+				case 3:
+					m.ReplaceIfPresent(k, i)
+				case 4:
+					m.Delete(k)
+				case 5:
+					m.Snapshot()
+				default:
+					m.Range(func(key string, v int) bool {
+						return true
+					})
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	require.LessOrEqual(t, m.Len(), 50)
+}
+
+const benchSyncKeys = 1024
+
+func benchSyncKeyNames() []string {
+	keys := make([]string, benchSyncKeys)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkSyncOptMap_Get(b *testing.B) {
+	keys := benchSyncKeyNames()
+	m := NewSyncOptMap[string, int]()
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Get(keys[i%benchSyncKeys])
+			i++
+		}
+	})
+}
+
+func BenchmarkSyncMap_Load(b *testing.B) {
+	keys := benchSyncKeyNames()
+	var m sync.Map
+	for i, k := range keys {
+		m.Store(k, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			m.Load(keys[i%benchSyncKeys])
+			i++
+		}
+	})
+}
+
+func BenchmarkSyncOptMap_Mixed(b *testing.B) {
+	keys := benchSyncKeyNames()
+	m := NewSyncOptMap[string, int]()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			k := keys[i%benchSyncKeys]
+			if i%4 == 0 {
+				m.Put(k, i)
+			} else {
+				m.ComputeIfAbsent(k, func(key string) int {
+					return i
+				})
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkSyncMap_Mixed(b *testing.B) {
+	keys := benchSyncKeyNames()
+	var m sync.Map
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			k := keys[i%benchSyncKeys]
+			if i%4 == 0 {
+				m.Store(k, i)
+			} else {
+				m.LoadOrStore(k, i)
+			}
+			i++
+		}
+	})
+}
+
+func TestSyncOptMap_ComputeMerge(t *testing.T) {
+	m := NewSyncOptMap[string, int]()
+	o := m.Compute("a", func(key string, current *Optional[int]) *Optional[int] {
+		return Of(current.OrElse(0) + 1)
+	})
+	require.Equal(t, 1, o.OrElse(0))
+	require.Equal(t, 1, m.GetOrDefault("a", -1))
+	require.Equal(t, 3, m.Merge("a", 2, func(old int, new int) int {
+		return old + new
+	}))
+	require.Equal(t, -1, m.GetOrDefault("b", -1))
+}
+
+func TestSyncOptMap_Functional(t *testing.T) {
+	m := NewSyncOptMap[int, int]()
+	for i := 0; i < 10; i++ {
+		m.Put(i, i)
+	}
+	require.Equal(t, 10, len(m.Keys()))
+	require.Equal(t, 10, len(m.Values()))
+	even := m.Filter(func(key int, v int) bool {
+		return v%2 == 0
+	})
+	require.Equal(t, 5, len(even))
+	require.Equal(t, 5, m.RemoveIf(func(key int, v int) bool {
+		return v%2 == 0
+	}))
+	require.Equal(t, 5, m.Len())
+}
+
+func TestSyncOptMap_Merge_Atomic(t *testing.T) {
+	m := NewSyncOptMap[string, int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				m.Merge("counter", 1, func(old int, new int) int {
+					return old + new
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 16000, m.Get("counter").OrElse(0))
+}
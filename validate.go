@@ -0,0 +1,392 @@
+package gopt
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// InvalidValidateTarget is the error returned by Validate when the value is not a struct (or a non-nil pointer to a struct)
+var InvalidValidateTarget = errors.New("validate target must be a struct or a non-nil pointer to a struct")
+
+// ValidationError is the (aggregated) error returned by Validate when one or more fields failed validation
+type ValidationError struct {
+	// Errors is the errors for each field rule that failed
+	Errors []*FieldValidationError
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("validation failed (%d field errors): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// ByPath returns the field errors for the specified (JSON) path
+func (e *ValidationError) ByPath(path string) []*FieldValidationError {
+	result := make([]*FieldValidationError, 0)
+	for _, fe := range e.Errors {
+		if fe.Path == path {
+			result = append(result, fe)
+		}
+	}
+	return result
+}
+
+// FieldValidationError is the error for a single field rule that failed (see ValidationError)
+type FieldValidationError struct {
+	// Path is the JSON path of the field (e.g. "items[0].name")
+	Path string
+	// Field is the name of the field (nested struct fields are dot separated, e.g. "Items[0].Name")
+	Field string
+	// Rule is the name of the rule that failed (e.g. "required")
+	Rule string
+	// Params is the parameters of the rule (e.g. for `required_if=Type:card` - "Type" and "card")
+	Params []any
+	// Message describes the error
+	Message string
+}
+
+// Error implements error
+func (e *FieldValidationError) Error() string {
+	return fmt.Sprintf("field %q: %s", e.Path, e.Message)
+}
+
+// tagRules is the rule names recognised in `gopt` tags (mapped to whether the rule is a flag - i.e. takes no parameter)
+//
+// flag rules are recognised by name alone (so are reserved words - a Bind path with one of these names must be quoted,
+// e.g. `gopt:"['required']"`), other rules only when followed by "=" (so that, e.g., `gopt:"max"` is still the Bind path
+// "max") - any other item in the tag is the Bind path
+var tagRules = map[string]bool{
+	RuleRequired:      true,
+	RuleNotNull:       true,
+	RuleRequiredIf:    false,
+	RuleForbiddenWith: false,
+	RuleMin:           false,
+	RuleMax:           false,
+	RuleBetween:       false,
+	RuleLen:           false,
+	RuleMatches:       false,
+	RuleOneOf:         false,
+	RuleNotBlank:      true,
+}
+
+type tagRule struct {
+	name  string
+	param string
+}
+
+// parseGoptTag splits a `gopt` tag into its (optional) Bind path and validation rules
+//
+// items are comma separated - commas within brackets or quotes (e.g. a path of `["a,b"]`) do not split items
+func parseGoptTag(tag string) (path string, rules []tagRule, err error) {
+	for _, item := range splitTag(tag) {
+		name, param, hasParam := strings.Cut(item, "=")
+		flag, isRule := tagRules[name]
+		switch {
+		case isRule && flag && hasParam:
+			return "", nil, fmt.Errorf("tag %q rule %q does not take a parameter", tag, name)
+		case isRule && !flag && hasParam && param == "":
+			return "", nil, fmt.Errorf("tag %q rule %q requires a parameter", tag, name)
+		case isRule && flag == !hasParam:
+			rules = append(rules, tagRule{name: name, param: param})
+		case item == "":
+			continue
+		case path != "":
+			return "", nil, fmt.Errorf("tag %q has more than one path (%q and %q)", tag, path, item)
+		default:
+			path = item
+		}
+	}
+	return
+}
+
+func splitTag(tag string) []string {
+	result := make([]string, 0)
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(tag); i++ {
+		ch := tag[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '[' || ch == '(' || ch == '{':
+			depth++
+		case ch == ']' || ch == ')' || ch == '}':
+			depth--
+		case ch == ',' && depth <= 0:
+			result = append(result, strings.TrimSpace(tag[start:i]))
+			start = i + 1
+		}
+	}
+	return append(result, strings.TrimSpace(tag[start:]))
+}
+
+// Validate validates the fields of the supplied struct (or pointer to struct) according to the rules in their `gopt` tags
+//
+// The rules are:
+//   required                  the field must be set (see Optional.WasSet)
+//   notnull                   if the field was set, it must be present (i.e. not null)
+//   required_if=Field:value   the field must be set if the named sibling field is present and has the specified value
+//   forbidden_with=Field      the field must not be set if the named sibling field is set
+// and the value rules (which, like Rule, only apply to optionals when the value is present - for other fields they
+// apply to the field value, including the zero value, unless it is a nil pointer):
+//   min=n                     the value must be at least n
+//   max=n                     the value must be at most n
+//   between=n:m               the value must be between n and m (inclusive)
+//   len=n (or len=n:m)        the length (of a string, slice or map) must be n (or between n and m)
+//   matches=regexp            the (string) value must match the regular expression
+//   oneof=a|b|c               the value must be one of the listed values
+//   notblank                  the (string) value must not be blank
+// For example:
+//   type Payment struct {
+//     Type       gopt.Optional[string] `json:"type" gopt:"required,notnull"`
+//     CardNumber gopt.Optional[string] `json:"cardNumber" gopt:"required_if=Type:card,forbidden_with=IBAN"`
+//     IBAN       gopt.Optional[string] `json:"iban"`
+//   }
+// Sibling fields may be named by their Go field name or their JSON name.  For fields that are not Optional[T] (or
+// *Optional[T]), the field is considered set and present if it is not the zero value.  A nil *Optional[T] is considered not set
+//
+// Rules may be combined, comma separated, with a Bind path in the same tag (e.g. `gopt:"order.id,required"`) - rules
+// that take a parameter are only recognised with "=" (e.g. `gopt:"max"` is the Bind path "max" and `gopt:"max="` is an error).
+// The rules that take no parameter (required, notnull and notblank) are reserved words and are always recognised as rules -
+// to bind a property with one of these names, quote it in brackets (e.g. `gopt:"['required']"`)
+//
+// Nested structs, pointers to structs, present optionals of structs and slices (or arrays) of these are validated
+// recursively - every failing field is listed, by JSON path (e.g. "items[0].name"), in the returned *ValidationError
+func Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return InvalidValidateTarget
+	}
+	vc := &validateContext{}
+	vc.validateStruct(addressable(rv), "", "")
+	if len(vc.errors) > 0 {
+		return &ValidationError{Errors: vc.errors}
+	}
+	return nil
+}
+
+type validatable interface {
+	validateState() (set bool, present bool, value any)
+}
+
+var validatableType = reflect.TypeOf((*validatable)(nil)).Elem()
+
+func (o *Optional[T]) validateState() (bool, bool, any) {
+	v, ok := o.GetOk()
+	return o.WasSet(), ok, v
+}
+
+type validateContext struct {
+	errors []*FieldValidationError
+}
+
+type validateField struct {
+	sf    reflect.StructField
+	fv    reflect.Value
+	name  string
+	path  string
+	field string
+}
+
+func (vc *validateContext) validateStruct(rv reflect.Value, pathPrefix string, fieldPrefix string) {
+	fields := structFields(rv, pathPrefix, fieldPrefix)
+	for _, f := range fields {
+		set, present, _ := fieldState(f.fv)
+		_, rules, err := parseGoptTag(f.sf.Tag.Get("gopt"))
+		if err != nil {
+			vc.fail(f, "tag", nil, err.Error())
+			continue
+		}
+		for _, r := range rules {
+			vc.validateRule(f, r, set, present, fields)
+		}
+		vc.validateValue(f.fv, f.path, f.field)
+	}
+}
+
+func (vc *validateContext) validateRule(f validateField, r tagRule, set bool, present bool, siblings []validateField) {
+	switch r.name {
+	case RuleRequired:
+		if !set {
+			vc.fail(f, r.name, nil, "required")
+		}
+	case RuleNotNull:
+		if set && !present {
+			vc.fail(f, r.name, nil, "must not be null")
+		}
+	case RuleRequiredIf:
+		other, value, ok := strings.Cut(r.param, ":")
+		params := []any{other, value}
+		if !ok {
+			vc.fail(f, r.name, params, fmt.Sprintf("invalid rule parameter %q (expected Field:value)", r.param))
+		} else if sibling, found := findField(siblings, other); !found {
+			vc.fail(f, r.name, params, fmt.Sprintf("unknown field %q", other))
+		} else if _, sp, sv := fieldState(sibling.fv); sp && fmt.Sprint(sv) == value && !set {
+			vc.fail(f, r.name, params, fmt.Sprintf("required when %s is %s", other, value))
+		}
+	case RuleForbiddenWith:
+		params := []any{r.param}
+		if sibling, found := findField(siblings, r.param); !found {
+			vc.fail(f, r.name, params, fmt.Sprintf("unknown field %q", r.param))
+		} else if ss, _, _ := fieldState(sibling.fv); ss && set {
+			vc.fail(f, r.name, params, fmt.Sprintf("forbidden with %s", r.param))
+		}
+	default:
+		if rv, ok := ruleValue(f.fv); ok {
+			if re := tagValueRule(r, rv); re != nil {
+				vc.fail(f, re.Rule, re.Params, re.Message)
+			}
+		}
+	}
+}
+
+// validateValue recursively validates structs (and slices/arrays of structs) within a field value - optionals are
+// only validated if present
+func (vc *validateContext) validateValue(rv reflect.Value, path string, field string) {
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return
+		}
+		if rv.Type().Implements(validatableType) {
+			if _, present, value := rv.Interface().(validatable).validateState(); present {
+				vc.validateValue(reflect.ValueOf(value), path, field)
+			}
+			return
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv = addressable(rv); reflect.PtrTo(rv.Type()).Implements(validatableType) {
+			vc.validateValue(rv.Addr(), path, field)
+		} else {
+			vc.validateStruct(rv, path+".", field+".")
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			idx := "[" + strconv.Itoa(i) + "]"
+			vc.validateValue(rv.Index(i), path+idx, field+idx)
+		}
+	}
+}
+
+func (vc *validateContext) fail(f validateField, rule string, params []any, msg string) {
+	vc.errors = append(vc.errors, &FieldValidationError{
+		Path:    f.path,
+		Field:   f.field,
+		Rule:    rule,
+		Params:  params,
+		Message: msg,
+	})
+}
+
+// structFields lists the exported fields of a struct - with embedded structs flattened (as with encoding/json)
+func structFields(rv reflect.Value, pathPrefix string, fieldPrefix string) []validateField {
+	result := make([]validateField, 0, rv.NumField())
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := sf.Name
+		explicit := false
+		if tag, ok := sf.Tag.Lookup("json"); ok {
+			if jn := strings.Split(tag, ",")[0]; jn != "" && jn != "-" {
+				name = jn
+				explicit = true
+			}
+		}
+		fv := rv.Field(i)
+		if sf.Anonymous && !explicit && bindStructType(sf.Type) {
+			if sf.Type.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			result = append(result, structFields(fv, pathPrefix, fieldPrefix)...)
+			continue
+		}
+		result = append(result, validateField{
+			sf:    sf,
+			fv:    fv,
+			name:  name,
+			path:  pathPrefix + name,
+			field: fieldPrefix + sf.Name,
+		})
+	}
+	return result
+}
+
+func findField(fields []validateField, name string) (validateField, bool) {
+	for _, f := range fields {
+		if f.sf.Name == name || f.name == name {
+			return f, true
+		}
+	}
+	return validateField{}, false
+}
+
+// fieldState determines whether a field value is set and present
+//
+// Optional[T] and *Optional[T] fields use WasSet and IsPresent (a nil *Optional[T] is not set) - other fields are
+// set and present if they are not the zero value
+func fieldState(fv reflect.Value) (set bool, present bool, value any) {
+	if reflect.PtrTo(fv.Type()).Implements(validatableType) {
+		return fv.Addr().Interface().(validatable).validateState()
+	} else if fv.Kind() == reflect.Ptr && fv.Type().Implements(validatableType) {
+		if fv.IsNil() {
+			return false, false, nil
+		}
+		return fv.Interface().(validatable).validateState()
+	}
+	nonZero := !fv.IsZero()
+	return nonZero, nonZero, fv.Interface()
+}
+
+// ruleValue determines the value to which value rules (e.g. min=1) apply - for Optional[T] and *Optional[T] fields,
+// the value only if present - for other fields, the field value (dereferenced if a pointer or interface, but not if nil)
+func ruleValue(fv reflect.Value) (reflect.Value, bool) {
+	if reflect.PtrTo(fv.Type()).Implements(validatableType) || (fv.Kind() == reflect.Ptr && fv.Type().Implements(validatableType)) {
+		if _, present, value := fieldState(fv); present {
+			return reflect.ValueOf(value), true
+		}
+		return reflect.Value{}, false
+	}
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return reflect.Value{}, false
+		}
+		fv = fv.Elem()
+	}
+	return fv, true
+}
+
+func addressable(rv reflect.Value) reflect.Value {
+	if rv.CanAddr() {
+		return rv
+	}
+	av := reflect.New(rv.Type()).Elem()
+	av.Set(rv)
+	return av
+}
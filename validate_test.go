@@ -0,0 +1,281 @@
+package gopt
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type validatePayment struct {
+	Type       Optional[string]  `json:"type" gopt:"required,notnull"`
+	Amount     *Optional[int]    `json:"amount" gopt:"required"`
+	CardNumber Optional[string]  `json:"cardNumber" gopt:"required_if=type:card,forbidden_with=IBAN"`
+	IBAN       Optional[string]  `json:"iban" gopt:"required_if=Type:bank"`
+	Reference  string            `json:"ref"`
+	Note       Optional[string]  `json:"note" gopt:"notnull"`
+	Meta       *Optional[string] `json:"meta"`
+}
+
+func TestValidate(t *testing.T) {
+	var p validatePayment
+	err := json.Unmarshal([]byte(`{"type": "card", "amount": 10, "cardNumber": "4111"}`), &p)
+	require.NoError(t, err)
+	require.NoError(t, Validate(p))
+	require.NoError(t, Validate(&p))
+
+	p = validatePayment{}
+	err = json.Unmarshal([]byte(`{"type": null, "note": null}`), &p)
+	require.NoError(t, err)
+	err = Validate(&p)
+	require.Error(t, err)
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, 3, len(verr.Errors))
+	require.Equal(t, "type", verr.Errors[0].Path)
+	require.Equal(t, "Type", verr.Errors[0].Field)
+	require.Equal(t, "notnull", verr.Errors[0].Rule)
+	require.Equal(t, "amount", verr.Errors[1].Path)
+	require.Equal(t, "required", verr.Errors[1].Rule)
+	require.Equal(t, "note", verr.Errors[2].Path)
+	require.Equal(t, `validation failed (3 field errors): field "type": must not be null; field "amount": required; field "note": must not be null`, err.Error())
+}
+
+func TestValidate_Conditional(t *testing.T) {
+	var p validatePayment
+	err := json.Unmarshal([]byte(`{"type": "card", "amount": 10, "iban": "GB00"}`), &p)
+	require.NoError(t, err)
+	err = Validate(p)
+	require.Error(t, err)
+	verr := err.(*ValidationError)
+	require.Equal(t, 1, len(verr.Errors))
+	require.Equal(t, "cardNumber", verr.Errors[0].Path)
+	require.Equal(t, "required_if", verr.Errors[0].Rule)
+	require.Equal(t, []any{"type", "card"}, verr.Errors[0].Params)
+	require.Equal(t, "required when type is card", verr.Errors[0].Message)
+
+	p = validatePayment{}
+	err = json.Unmarshal([]byte(`{"type": "bank", "amount": 10, "cardNumber": "4111"}`), &p)
+	require.NoError(t, err)
+	verr = Validate(p).(*ValidationError)
+	require.Equal(t, 1, len(verr.Errors))
+	require.Equal(t, "iban", verr.Errors[0].Path)
+	require.Equal(t, "required when Type is bank", verr.Errors[0].Message)
+
+	p = validatePayment{}
+	err = json.Unmarshal([]byte(`{"type": "card", "amount": 10, "cardNumber": "4111", "iban": "GB00"}`), &p)
+	require.NoError(t, err)
+	verr = Validate(p).(*ValidationError)
+	require.Equal(t, 1, len(verr.Errors))
+	require.Equal(t, "cardNumber", verr.Errors[0].Path)
+	require.Equal(t, "forbidden_with", verr.Errors[0].Rule)
+	require.Equal(t, []any{"IBAN"}, verr.Errors[0].Params)
+	require.Equal(t, "forbidden with IBAN", verr.Errors[0].Message)
+}
+
+func TestValidate_NonOptionalFields(t *testing.T) {
+	type thing struct {
+		Name  string         `json:"name" gopt:"required"`
+		Tags  []string       `json:"tags" gopt:"required"`
+		Ptr   *int           `json:"ptr" gopt:"required"`
+		Kind  string         `json:"kind"`
+		Extra map[string]int `json:"extra" gopt:"required_if=kind:special"`
+	}
+	err := Validate(thing{Kind: "special"})
+	require.Error(t, err)
+	verr := err.(*ValidationError)
+	require.Equal(t, 4, len(verr.Errors))
+	require.Equal(t, "name", verr.Errors[0].Path)
+	require.Equal(t, "tags", verr.Errors[1].Path)
+	require.Equal(t, "ptr", verr.Errors[2].Path)
+	require.Equal(t, "extra", verr.Errors[3].Path)
+
+	i := 0
+	require.NoError(t, Validate(thing{Name: "a", Tags: []string{}, Ptr: &i}))
+}
+
+type validateLine struct {
+	SKU Optional[string] `json:"sku" gopt:"required,notnull"`
+	Qty Optional[int]    `json:"qty" gopt:"required"`
+}
+
+type validateAddress struct {
+	Street Optional[string] `json:"street" gopt:"required"`
+}
+
+func TestValidate_Nested(t *testing.T) {
+	type Audit struct {
+		CreatedBy Optional[string] `json:"createdBy" gopt:"required"`
+	}
+	type order struct {
+		Audit
+		ID       Optional[string]          `json:"id" gopt:"required"`
+		Lines    []validateLine            `json:"lines"`
+		Billing  *validateAddress          `json:"billing"`
+		Shipping Optional[validateAddress] `json:"shipping"`
+		Extra    []*validateLine           `json:"extra"`
+		Items    [1]validateLine           `json:"items"`
+		Any      any                       `json:"any"`
+	}
+	var o order
+	err := json.Unmarshal([]byte(`{
+		"createdBy": "me",
+		"id": "o1",
+		"lines": [{"sku": "a", "qty": 1}, {"sku": null}],
+		"billing": {},
+		"shipping": {},
+		"extra": [null, {"qty": 2}],
+		"items": [{"sku": "x", "qty": 1}]
+	}`), &o)
+	require.NoError(t, err)
+	o.Any = &validateLine{}
+	err = Validate(o)
+	require.Error(t, err)
+	verr := err.(*ValidationError)
+	paths := make([]string, len(verr.Errors))
+	fields := make([]string, len(verr.Errors))
+	for i, fe := range verr.Errors {
+		paths[i] = fe.Path
+		fields[i] = fe.Field
+	}
+	require.Equal(t, []string{
+		"lines[1].sku",
+		"lines[1].qty",
+		"billing.street",
+		"shipping.street",
+		"extra[1].sku",
+		"any.sku",
+		"any.qty",
+	}, paths)
+	require.Equal(t, []string{
+		"Lines[1].SKU",
+		"Lines[1].Qty",
+		"Billing.Street",
+		"Shipping.Street",
+		"Extra[1].SKU",
+		"Any.SKU",
+		"Any.Qty",
+	}, fields)
+	require.Equal(t, 1, len(verr.ByPath("lines[1].sku")))
+	require.Equal(t, 0, len(verr.ByPath("id")))
+
+	// embedded struct fields are flattened and nil pointers/empty optionals are not recursed...
+	err = Validate(&order{})
+	require.Error(t, err)
+	verr = err.(*ValidationError)
+	require.Equal(t, 4, len(verr.Errors))
+	require.Equal(t, "createdBy", verr.Errors[0].Path)
+	require.Equal(t, "id", verr.Errors[1].Path)
+	require.Equal(t, "items[0].sku", verr.Errors[2].Path)
+	require.Equal(t, "items[0].qty", verr.Errors[3].Path)
+}
+
+func TestValidate_TagErrors(t *testing.T) {
+	type badTags struct {
+		A Optional[string] `gopt:"required_if=B"`
+		B Optional[string] `gopt:"forbidden_with=Missing"`
+		C Optional[string] `gopt:"required_if=Missing:x"`
+		D Optional[string] `gopt:"a.b,c.d"`
+	}
+	err := Validate(badTags{})
+	require.Error(t, err)
+	verr := err.(*ValidationError)
+	require.Equal(t, 4, len(verr.Errors))
+	require.Equal(t, `invalid rule parameter "B" (expected Field:value)`, verr.Errors[0].Message)
+	require.Equal(t, `unknown field "Missing"`, verr.Errors[1].Message)
+	require.Equal(t, `unknown field "Missing"`, verr.Errors[2].Message)
+	require.Equal(t, "tag", verr.Errors[3].Rule)
+}
+
+func TestValidate_InvalidTarget(t *testing.T) {
+	require.Equal(t, InvalidValidateTarget, Validate(nil))
+	require.Equal(t, InvalidValidateTarget, Validate("not a struct"))
+	var p *validatePayment
+	require.Equal(t, InvalidValidateTarget, Validate(p))
+}
+
+func TestParseGoptTag(t *testing.T) {
+	path, rules, err := parseGoptTag(`order.id,required,notnull`)
+	require.NoError(t, err)
+	require.Equal(t, "order.id", path)
+	require.Equal(t, []tagRule{{name: "required"}, {name: "notnull"}}, rules)
+
+	path, rules, err = parseGoptTag(`required, ['a,b'].c, required_if=Type:card`)
+	require.NoError(t, err)
+	require.Equal(t, "['a,b'].c", path)
+	require.Equal(t, []tagRule{{name: "required"}, {name: "required_if", param: "Type:card"}}, rules)
+
+	path, rules, err = parseGoptTag(`-`)
+	require.NoError(t, err)
+	require.Equal(t, "-", path)
+	require.Nil(t, rules)
+
+	_, _, err = parseGoptTag(`a,b`)
+	require.Error(t, err)
+
+	// rules that take a parameter are only recognised with "="...
+	path, rules, err = parseGoptTag(`max`)
+	require.NoError(t, err)
+	require.Equal(t, "max", path)
+	require.Nil(t, rules)
+	path, rules, err = parseGoptTag(`len,notblank,min=1`)
+	require.NoError(t, err)
+	require.Equal(t, "len", path)
+	require.Equal(t, []tagRule{{name: "notblank"}, {name: "min", param: "1"}}, rules)
+	_, _, err = parseGoptTag(`max=`)
+	require.Error(t, err)
+	require.Equal(t, `tag "max=" rule "max" requires a parameter`, err.Error())
+	_, _, err = parseGoptTag(`required=true`)
+	require.Error(t, err)
+	require.Equal(t, `tag "required=true" rule "required" does not take a parameter`, err.Error())
+}
+
+func TestBind_RuleNamedPaths(t *testing.T) {
+	type dst struct {
+		Limit Optional[float64] `gopt:"max"`
+		Size  Optional[float64] `gopt:"len,min=1"`
+		Bad   Optional[float64] `gopt:"max="`
+	}
+	var d dst
+	err := Bind(map[string]any{"max": 5.0, "len": 2.0}, &d)
+	require.Error(t, err)
+	require.Equal(t, 5.0, d.Limit.OrElse(0))
+	require.Equal(t, 2.0, d.Size.OrElse(0))
+	berr := err.(*BindError)
+	require.Equal(t, 1, len(berr.Errors))
+	require.Equal(t, "Bad", berr.Errors[0].Field)
+}
+
+func TestBind_ReservedWordPaths(t *testing.T) {
+	type dst struct {
+		Required Optional[bool]   `gopt:"required"`
+		Quoted   Optional[bool]   `gopt:"['required'],required"`
+		NotBlank Optional[string] `gopt:"[\"notblank\"]"`
+	}
+	var d dst
+	err := Bind(map[string]any{"required": true, "notblank": "x", "Required": false}, &d)
+	require.NoError(t, err)
+	// an unquoted reserved word is a rule - so the field name is used as the path...
+	require.False(t, d.Required.OrElse(true))
+	require.True(t, d.Quoted.OrElse(false))
+	require.Equal(t, "x", d.NotBlank.OrElse(""))
+	require.NoError(t, Validate(d))
+}
+
+func TestBind_WithValidationTag(t *testing.T) {
+	type dst struct {
+		Name Optional[string] `json:"name" gopt:"required"`
+		ID   string           `gopt:"order.id,required"`
+	}
+	m := map[string]any{
+		"name":  "foo",
+		"order": map[string]any{"id": "o1"},
+	}
+	var d dst
+	err := Bind(m, &d)
+	require.NoError(t, err)
+	require.Equal(t, "foo", d.Name.OrElse(""))
+	require.Equal(t, "o1", d.ID)
+	require.NoError(t, Validate(d))
+	require.Error(t, Validate(dst{}))
+}